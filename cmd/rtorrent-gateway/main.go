@@ -0,0 +1,105 @@
+// Command rtorrent-gateway serves the REST/JSON API of the httpapi package in front of an rTorrent instance.
+//
+//	rtorrent-gateway -rtorrent http://127.0.0.1:8000/RPC2 -tokens tokens.json -listen :8080
+//
+// The tokens file is a JSON array of the tokens clients may use, each with the permissions it is granted:
+//
+//	[
+//		{"name": "dashboard", "secret": "…", "permissions": ["read"]},
+//		{"name": "automation", "secret": "…", "permissions": ["read", "control", "delete"]}
+//	]
+//
+// Bearer tokens are only as private as the connection they're sent over, so give -tls-cert and -tls-key unless the
+// gateway sits behind something that terminates TLS for it.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/aauren/rtorrent/rtorrent/httpapi"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "rtorrent-gateway:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		listen      = flag.String("listen", ":8080", "address to serve the API on")
+		rtorrentURL = flag.String("rtorrent", "", "URL of rTorrent's XML-RPC endpoint (required)")
+		tokensPath  = flag.String("tokens", "", "JSON file of the tokens clients may use (required)")
+		tlsCert     = flag.String("tls-cert", "", "certificate to serve TLS with, along with -tls-key")
+		tlsKey      = flag.String("tls-key", "", "private key of -tls-cert")
+	)
+	flag.Parse()
+	if *rtorrentURL == "" || *tokensPath == "" {
+		return errors.New("-rtorrent and -tokens are required")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return errors.New("-tls-cert and -tls-key must be given together")
+	}
+
+	tokens, err := httpapi.ReadTokens(*tokensPath)
+	if err != nil {
+		return err
+	}
+	// rTorrent's URL can hold a password for the proxy in front of it, which mustn't end up in the logs
+	u, err := url.Parse(*rtorrentURL)
+	if err != nil {
+		return fmt.Errorf("parsing -rtorrent: %w", err)
+	}
+	client, err := rtorrent.New(*rtorrentURL, nil)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           httpapi.New(client, httpapi.WithTokens(tokens...), httpapi.WithLogger(logger)),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := make(chan error, 1)
+	go func() {
+		logger.Info("serving", "listen", *listen, "rtorrent", u.Redacted(), "tokens", len(tokens), "tls", *tlsCert != "")
+		if *tlsCert != "" {
+			served <- srv.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			served <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,286 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CacheForever is a CachePolicy TTL for values that can't change for as long as the download exists.
+	CacheForever time.Duration = -1
+
+	// infoHashHexLen is the length of a hex encoded v1 info-hash, which is how rTorrent identifies downloads
+	infoHashHexLen = 40
+)
+
+// A CachePolicy maps rTorrent commands, without their trailing "=", to how long their results may be served from a
+// Cache. Commands it doesn't mention, or maps to zero, are never cached. A multicall is cached for the shortest TTL
+// among the commands it runs, and the list command it runs them over, so one volatile field keeps the whole call live.
+type CachePolicy map[string]time.Duration
+
+// DefaultCachePolicy caches the fields that are fixed when a torrent is created forever, and nothing else. Rates,
+// totals, state and download lists are never cached.
+var DefaultCachePolicy = CachePolicy{
+	"d.hash":          CacheForever,
+	"d.name":          CacheForever,
+	"d.base_filename": CacheForever,
+	"d.size_bytes":    CacheForever,
+	"d.size_chunks":   CacheForever,
+	"d.size_files":    CacheForever,
+	"d.chunk_size":    CacheForever,
+	"d.creation_date": CacheForever,
+	"d.is_multi_file": CacheForever,
+	"d.is_private":    CacheForever,
+}
+
+// mutatingCommands change a download without following the ".set" naming that setters otherwise share
+var mutatingCommands = map[string]bool{
+	"d.start": true, "d.stop": true, "d.open": true, "d.close": true, "d.pause": true, "d.resume": true,
+	"d.erase": true, "d.check_hash": true, "d.tracker.insert": true, "d.save_full_session": true,
+	"t.enable": true, "t.disable": true,
+}
+
+// CacheStats counts how a Cache has been doing since it was created.
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// HitRatio returns the fraction of cacheable calls that were answered from the cache, or 0 before there have been any.
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// A Cache answers repeat calls for values that rarely or never change without going to rTorrent, following a
+// CachePolicy. Any mutating call, such as a ".set" command, d.stop or d.erase, drops everything cached for the download
+// it targets along with all cached lists; a mutating call that targets no download empties the cache. A Cache is safe
+// for concurrent use, but should only be given to one client since entries aren't keyed by endpoint.
+type Cache struct {
+	policy CachePolicy
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	stats   CacheStats
+
+	// now is swapped out by tests that need to move time along
+	now func() time.Time
+}
+
+// cacheEntry is a decoded reply and when it stops being valid, with a zero expiry meaning never
+type cacheEntry struct {
+	infoHash string
+	value    reflect.Value
+	expires  time.Time
+}
+
+// NewCache creates an empty Cache following policy. The policy is copied, so later changes to it have no effect.
+func NewCache(policy CachePolicy) *Cache {
+	return &Cache{policy: maps.Clone(policy), entries: make(map[string]cacheEntry), now: time.Now}
+}
+
+// WithCache serves calls from cache where its policy allows. It runs at the point in the interceptor chain where it's
+// passed relative to WithInterceptors, and always ahead of any limits, so cache hits never wait on them.
+func WithCache(cache *Cache) Option {
+	return WithInterceptors(cache.Intercept)
+}
+
+// Stats returns a snapshot of the cache's counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Entries = len(c.entries)
+	return s
+}
+
+// Invalidate drops everything cached for the download with the given info-hash, along with all cached lists.
+func (c *Cache) Invalidate(infoHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidateLocked(strings.ToUpper(infoHash))
+}
+
+// Purge empties the cache.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidateLocked("")
+}
+
+// Intercept is the Interceptor WithCache installs.
+func (c *Cache) Intercept(ctx context.Context, method string, args []any, reply any, next Invoker) error {
+	infoHash := infoHashOf(args)
+
+	if isMutating(method) {
+		err := next(ctx, method, args, reply)
+		// Even a failed call may have got partway, so we invalidate regardless
+		c.Invalidate(infoHash)
+		return err
+	}
+
+	ttl := c.ttlFor(method, args)
+	if ttl == 0 {
+		return next(ctx, method, args, reply)
+	}
+
+	out := reflect.ValueOf(reply)
+	if out.Kind() != reflect.Pointer || out.IsNil() {
+		return next(ctx, method, args, reply)
+	}
+	key := cacheKey(method, args)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && (entry.expires.IsZero() || c.now().Before(entry.expires)) && entry.value.Type() == out.Elem().Type() {
+		c.stats.Hits++
+		out.Elem().Set(cloneValue(entry.value))
+		c.mu.Unlock()
+		return nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	if err := next(ctx, method, args, reply); err != nil {
+		return err
+	}
+
+	entry = cacheEntry{infoHash: infoHash, value: cloneValue(out.Elem())}
+	if ttl != CacheForever {
+		entry.expires = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	return nil
+}
+
+// ttlFor works out how long a call may be cached under the policy, treating a multicall as only as cacheable as the
+// least cacheable of its commands
+func (c *Cache) ttlFor(method string, args []any) time.Duration {
+	var ttl time.Duration
+	switch method {
+	case downloadListMultiCall:
+		// The rows come from a download list, so they're only as stable as the list itself
+		ttl = c.policy[downloadList]
+	case trackerListMultiCall:
+		// A download's trackers only change through d.tracker.insert, which invalidates them anyway
+		ttl = CacheForever
+	default:
+		return c.policy[method]
+	}
+
+	for _, arg := range args {
+		cmd, ok := arg.(string)
+		if !ok || !strings.Contains(cmd, "=") {
+			continue
+		}
+		name, _, _ := strings.Cut(cmd, "=")
+		if ttl = shorterTTL(ttl, c.policy[name]); ttl == 0 {
+			return 0
+		}
+	}
+	return ttl
+}
+
+// invalidateLocked drops entries for infoHash and every entry not tied to a download, or everything when infoHash is
+// empty. The caller must hold c.mu.
+func (c *Cache) invalidateLocked(infoHash string) {
+	c.stats.Invalidations++
+	for key, entry := range c.entries {
+		if infoHash == "" || entry.infoHash == "" || entry.infoHash == infoHash {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// shorterTTL returns whichever of a and b expires sooner, where zero is sooner than anything and CacheForever is
+// later than anything
+func shorterTTL(a, b time.Duration) time.Duration {
+	switch {
+	case a == CacheForever:
+		return b
+	case b == CacheForever:
+		return a
+	default:
+		return min(a, b)
+	}
+}
+
+// isMutating reports whether method changes rTorrent's state, and so must invalidate what we've cached
+func isMutating(method string) bool {
+	return strings.HasSuffix(method, ".set") || mutatingCommands[method]
+}
+
+// cacheKey identifies a call by its method and arguments
+func cacheKey(method string, args []any) string {
+	var sb strings.Builder
+	sb.WriteString(method)
+	for _, arg := range args {
+		sb.WriteByte(0)
+		fmt.Fprint(&sb, arg)
+	}
+	return sb.String()
+}
+
+// infoHashOf returns the upper-cased info-hash a call targets, which leads its arguments and may carry a ":" suffix
+// for a tracker, file or peer index, or "" when it targets no single download
+func infoHashOf(args []any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	target, ok := args[0].(string)
+	if !ok {
+		return ""
+	}
+	hash, _, _ := strings.Cut(target, ":")
+	if !isInfoHash(hash) {
+		return ""
+	}
+	return strings.ToUpper(hash)
+}
+
+// cloneValue deep copies the slices, maps and interfaces that XML-RPC replies decode into, so that callers are free to
+// modify what they get back without corrupting the cache. Everything else a reply can hold, such as strings, numbers
+// and times, is a value type already.
+func cloneValue(v reflect.Value) reflect.Value {
+	k := v.Kind()
+	if (k == reflect.Slice || k == reflect.Map || k == reflect.Interface) && v.IsNil() {
+		return v
+	}
+
+	out := v
+	if k == reflect.Slice {
+		out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			out.Index(i).Set(cloneValue(v.Index(i)))
+		}
+	}
+	if k == reflect.Map {
+		out = reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), cloneValue(iter.Value()))
+		}
+	}
+	if k == reflect.Interface {
+		out = reflect.New(v.Type()).Elem()
+		out.Set(cloneValue(v.Elem()))
+	}
+	return out
+}
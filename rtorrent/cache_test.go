@@ -0,0 +1,222 @@
+package rtorrent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRTorrent stands in for rTorrent behind a cache, answering every call with reply and counting how many
+// actually got through
+type countingRTorrent struct {
+	calls atomic.Int32
+	reply any
+}
+
+func (f *countingRTorrent) Intercept(_ context.Context, _ string, _ []any, out any, _ Invoker) error {
+	f.calls.Add(1)
+	switch out := out.(type) {
+	case *int:
+		*out = f.reply.(int)
+	case *string:
+		*out = f.reply.(string)
+	case *[][]any:
+		*out = f.reply.([][]any)
+	}
+	return nil
+}
+
+// testCachedClient returns a client whose calls go through cache and are then answered by a countingRTorrent
+func testCachedClient(t *testing.T, cache *Cache, reply any) (Client, *countingRTorrent) {
+	t.Helper()
+
+	fake := &countingRTorrent{reply: reply}
+	c, err := New("http://127.0.0.1:1/RPC2", nil, WithCache(cache), WithInterceptors(fake.Intercept))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	return c, fake
+}
+
+func TestCacheServesImmutableFieldsForever(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(DefaultCachePolicy)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	c, fake := testCachedClient(t, cache, "foobar")
+	ds := &DownloadService{C: c}
+
+	for range 3 {
+		name, err := ds.BaseFilename(testInfoHash)
+		require.NoError(t, err)
+		assert.Equal(t, "foobar", name)
+	}
+	now = now.Add(365 * 24 * time.Hour)
+	_, err := ds.BaseFilename(testInfoHash)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), fake.calls.Load())
+	stats := cache.Stats()
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 1, Entries: 1}, stats)
+	assert.InDelta(t, 0.75, stats.HitRatio(), 0.0001)
+}
+
+func TestCacheNeverCachesRates(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(DefaultCachePolicy)
+	c, fake := testCachedClient(t, cache, testBytes)
+	ds := &DownloadService{C: c}
+
+	for range 3 {
+		_, err := ds.UploadRate(testInfoHash)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(3), fake.calls.Load())
+	assert.Equal(t, CacheStats{}, cache.Stats(), "uncacheable calls don't count as misses")
+	assert.Zero(t, cache.Stats().HitRatio())
+}
+
+func TestCacheExpiresTTLs(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(CachePolicy{"down.rate": time.Second})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	c, fake := testCachedClient(t, cache, testBytes)
+
+	for range 2 {
+		_, err := c.DownloadRate()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fake.calls.Load())
+
+	now = now.Add(time.Second)
+	_, err := c.DownloadRate()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fake.calls.Load())
+}
+
+func TestCacheMulticallTakesShortestTTL(t *testing.T) {
+	t.Parallel()
+
+	rows := [][]any{{"a name"}}
+
+	t.Run("uncached download list keeps multicalls live", func(t *testing.T) {
+		t.Parallel()
+
+		c, fake := testCachedClient(t, NewCache(DefaultCachePolicy), rows)
+		ds := &DownloadService{C: c}
+
+		for range 2 {
+			_, err := ds.DownloadWithDetails([]string{"d.name="})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), fake.calls.Load())
+	})
+
+	t.Run("volatile command keeps multicalls live", func(t *testing.T) {
+		t.Parallel()
+
+		c, fake := testCachedClient(t, NewCache(CachePolicy{downloadList: time.Minute, "d.name": CacheForever}), rows)
+		ds := &DownloadService{C: c}
+
+		for range 2 {
+			_, err := ds.DownloadWithDetails([]string{"d.name=", "d.up.rate="})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), fake.calls.Load())
+	})
+
+	t.Run("cacheable list and commands", func(t *testing.T) {
+		t.Parallel()
+
+		cache := NewCache(CachePolicy{downloadList: time.Minute, "d.name": CacheForever})
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		c, fake := testCachedClient(t, cache, rows)
+		ds := &DownloadService{C: c}
+
+		for range 2 {
+			got, err := ds.DownloadWithDetails([]string{"d.name="})
+			require.NoError(t, err)
+			assert.Equal(t, rows, got)
+		}
+		assert.Equal(t, int32(1), fake.calls.Load())
+
+		// The list's minute bounds the otherwise forever d.name
+		now = now.Add(time.Minute)
+		_, err := ds.DownloadWithDetails([]string{"d.name="})
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), fake.calls.Load())
+	})
+}
+
+func TestCacheInvalidatesOnMutation(t *testing.T) {
+	t.Parallel()
+
+	otherHash := "B" + testInfoHash[1:]
+	cache := NewCache(DefaultCachePolicy)
+	c, fake := testCachedClient(t, cache, "foobar")
+	ds := &DownloadService{C: c}
+
+	for _, hash := range []string{testInfoHash, otherHash} {
+		_, err := ds.BaseFilename(hash)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, cache.Stats().Entries)
+
+	noop := func(context.Context, string, []any, any) error { return nil }
+
+	var ok int
+	require.NoError(t, cache.Intercept(t.Context(), "d.directory.set", []any{testInfoHash, "/mnt/new"}, &ok, noop))
+	assert.Equal(t, 1, cache.Stats().Entries, "only the targeted download is dropped")
+
+	_, err := ds.BaseFilename(otherHash)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fake.calls.Load(), "the other download is still served from cache")
+
+	require.NoError(t, cache.Intercept(t.Context(), "d.erase", []any{otherHash}, &ok, noop))
+	assert.Zero(t, cache.Stats().Entries)
+	assert.Equal(t, uint64(2), cache.Stats().Invalidations)
+}
+
+func TestCacheRepliesAreCopies(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(CachePolicy{downloadList: CacheForever, "d.name": CacheForever})
+	c, _ := testCachedClient(t, cache, [][]any{{"a name"}})
+	ds := &DownloadService{C: c}
+
+	first, err := ds.DownloadWithDetails([]string{"d.name="})
+	require.NoError(t, err)
+	first[0][0] = "clobbered"
+
+	second, err := ds.DownloadWithDetails([]string{"d.name="})
+	require.NoError(t, err)
+	assert.Equal(t, [][]any{{"a name"}}, second)
+}
+
+func TestCachePurge(t *testing.T) {
+	t.Parallel()
+
+	cache := NewCache(DefaultCachePolicy)
+	c, fake := testCachedClient(t, cache, "foobar")
+	ds := &DownloadService{C: c}
+
+	_, err := ds.BaseFilename(testInfoHash)
+	require.NoError(t, err)
+	cache.Purge()
+	_, err = ds.BaseFilename(testInfoHash)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), fake.calls.Load())
+}
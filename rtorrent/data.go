@@ -75,3 +75,22 @@ func stringFromAny(data any) (string, error) {
 		return "", fmt.Errorf("%w: cannot convert %T to string", ErrBadData, data)
 	}
 }
+
+func floatFromAny(data any) (float64, error) {
+	switch v := data.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrBadData, err)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("%w: cannot convert %T to float64", ErrBadData, data)
+	}
+}
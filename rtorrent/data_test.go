@@ -141,3 +141,37 @@ func TestStringFromAny(t *testing.T) {
 		})
 	}
 }
+
+func TestFloatFromAny(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    any
+		expected float64
+		errs     []error
+	}{
+		{"float64", 1.5, 1.5, nil},
+		{"int", 2, 2, nil},
+		{"int64", int64(3), 3, nil},
+		{stringCase, "0.25", 0.25, nil},
+		{invalidStringCase, "invalid", 0, []error{ErrBadData, strconv.ErrSyntax}},
+		{invalidTypeCase, []int{1}, 0, []error{ErrBadData}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := floatFromAny(tt.input)
+			assert.InDelta(t, tt.expected, result, 0)
+			if tt.errs == nil {
+				require.NoError(t, err)
+				return
+			}
+			for _, want := range tt.errs {
+				assert.ErrorIs(t, err, want)
+			}
+		})
+	}
+}
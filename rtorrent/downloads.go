@@ -1,6 +1,14 @@
 package rtorrent
 
-import "slices"
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
 
 const (
 	// downloadList is used in methods which retrieve a list of downloads.
@@ -11,82 +19,472 @@ const (
 	downloadListMultiCall = "d.multicall2"
 )
 
+// XMLRPC Download Fields
+const (
+	DownloadFieldHash            = DownloadField("hash")
+	DownloadFieldName            = DownloadField("name")
+	DownloadFieldState           = DownloadField("state")
+	DownloadFieldStateChanged    = DownloadField("state_changed")
+	DownloadFieldComplete        = DownloadField("complete")
+	DownloadFieldDirectory       = DownloadField("directory")
+	DownloadFieldDirectoryBase   = DownloadField("directory_base")
+	DownloadFieldTiedToFile      = DownloadField("tied_to_file")
+	DownloadFieldIsMultiFile     = DownloadField("is_multi_file")
+	DownloadFieldIsOpen          = DownloadField("is_open")
+	DownloadFieldBasePath        = DownloadField("base_path")
+	DownloadFieldBaseFilename    = DownloadField("base_filename")
+	DownloadFieldCustom1         = DownloadField("custom1")
+	DownloadFieldCustom2         = DownloadField("custom2")
+	DownloadFieldCustom3         = DownloadField("custom3")
+	DownloadFieldCustom4         = DownloadField("custom4")
+	DownloadFieldCustom5         = DownloadField("custom5")
+	DownloadFieldSizeBytes       = DownloadField("size_bytes")
+	DownloadFieldSizeChunks      = DownloadField("size_chunks")
+	DownloadFieldCompletedChunks = DownloadField("completed_chunks")
+	DownloadFieldUpTotal         = DownloadField("up.total")
+	DownloadFieldDownTotal       = DownloadField("down.total")
+	DownloadFieldMessage         = DownloadField("message")
+	DownloadFieldRatio           = DownloadField("ratio")
+	DownloadFieldFinished        = DownloadField("timestamp.finished")
+)
+
+// ratioScale is how much rTorrent scales d.ratio up by to report it as an integer
+const ratioScale = 1000
+
+// customFields maps the numbered custom slots Custom accepts to their fields
+var customFields = []DownloadField{
+	DownloadFieldCustom1, DownloadFieldCustom2, DownloadFieldCustom3, DownloadFieldCustom4, DownloadFieldCustom5,
+}
+
+// downloadFields does for downloads what trackerFields does for trackers, being the list of valid download fields
+// (see AllDownloadFields)
+var downloadFields = NewFieldSet(
+	NewField(PrefixDownload, DownloadFieldHash, stringFromAny, identity),
+	NewField(PrefixDownload, DownloadFieldName, stringFromAny, identity),
+	NewField(PrefixDownload, DownloadFieldState, boolFromAny, strconv.FormatBool),
+	NewField(PrefixDownload, DownloadFieldStateChanged, timeFromAny, time.Time.String),
+	NewField(PrefixDownload, DownloadFieldComplete, boolFromAny, strconv.FormatBool),
+	NewField(PrefixDownload, DownloadFieldDirectory, stringFromAny, identity).AsSettable(),
+	NewField(PrefixDownload, DownloadFieldDirectoryBase, stringFromAny, identity).AsSettable(),
+	NewField(PrefixDownload, DownloadFieldTiedToFile, stringFromAny, identity),
+	NewField(PrefixDownload, DownloadFieldIsMultiFile, boolFromAny, strconv.FormatBool),
+	NewField(PrefixDownload, DownloadFieldIsOpen, boolFromAny, strconv.FormatBool),
+	NewField(PrefixDownload, DownloadFieldBasePath, stringFromAny, identity),
+	NewField(PrefixDownload, DownloadFieldBaseFilename, stringFromAny, identity),
+	NewField(PrefixDownload, DownloadFieldCustom1, stringFromAny, identity).AsSettable(),
+	NewField(PrefixDownload, DownloadFieldCustom2, stringFromAny, identity).AsSettable(),
+	NewField(PrefixDownload, DownloadFieldCustom3, stringFromAny, identity).AsSettable(),
+	NewField(PrefixDownload, DownloadFieldCustom4, stringFromAny, identity).AsSettable(),
+	NewField(PrefixDownload, DownloadFieldCustom5, stringFromAny, identity).AsSettable(),
+	NewField(PrefixDownload, DownloadFieldSizeBytes, intFromAny, strconv.Itoa),
+	NewField(PrefixDownload, DownloadFieldSizeChunks, intFromAny, strconv.Itoa),
+	NewField(PrefixDownload, DownloadFieldCompletedChunks, intFromAny, strconv.Itoa),
+	NewField(PrefixDownload, DownloadFieldUpTotal, intFromAny, strconv.Itoa),
+	NewField(PrefixDownload, DownloadFieldDownTotal, intFromAny, strconv.Itoa),
+	NewField(PrefixDownload, DownloadFieldMessage, stringFromAny, identity),
+	NewField(PrefixDownload, DownloadFieldRatio, ratioFromAny, formatFloat),
+	NewField(PrefixDownload, DownloadFieldFinished, timeFromAny, time.Time.String),
+)
+
+// AllDownloadFields returns every retrievable download field, sorted, in a fresh slice each call.
+func AllDownloadFields() []DownloadField {
+	return downloadFields.Names()
+}
+
+// DownloadFields returns the download fields as a FieldSet, as TrackerFields does for trackers.
+func DownloadFields() *FieldSet[DownloadField] {
+	return downloadFields
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// ratioFromAny converts d.ratio, which rTorrent scales up to report as an integer, back into a ratio
+func ratioFromAny(data any) (float64, error) {
+	ratio, err := intFromAny(data)
+	return float64(ratio) / ratioScale, err
+}
+
+// DownloadField is used to specify download related fields that can be retrieved from rTorrent
+type DownloadField string
+
+func (df DownloadField) AsXMLRPCArgument() string {
+	return PrefixDownload + string(df) + "="
+}
+
+func (df DownloadField) String() string {
+	return string(df)
+}
+
+// Download is used to represent information about a download in rTorrent. Like a Tracker, it only holds the fields it
+// was built with, and the getters for any others return ErrNoField.
+type Download struct {
+	dData Record[DownloadField]
+}
+
+// NewDownload builds a Download from data already gathered, for sources of download information other than a live
+// DownloadService such as rTorrent's session files. As with NewTracker, values are converted as the getters are called.
+func NewDownload(data map[DownloadField]any) *Download {
+	return &Download{dData: data}
+}
+
+// GetFieldValueAsString renders the value of f as a string, returning "<ne>" for unknown fields and "<na>" for values
+// that couldn't be read off this particular download, as Tracker.GetFieldValueAsString does
+func (d *Download) GetFieldValueAsString(f DownloadField) string {
+	field, ok := downloadFields.Lookup(f)
+	if !ok {
+		return noFieldStr
+	}
+	str, err := field.Format(d.dData)
+	if err != nil {
+		return noValueStr
+	}
+	return str
+}
+
+func (d *Download) String() string {
+	var sb strings.Builder
+	for i, k := range slices.Sorted(maps.Keys(d.dData)) {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(k.String())
+		sb.WriteString(": ")
+		sb.WriteString(d.GetFieldValueAsString(k))
+	}
+
+	return fmt.Sprintf("Download: data: <%s>", sb.String())
+}
+
+// Hash Returns the download's info-hash, in the upper-case hex rTorrent uses.
+func (d *Download) Hash() (string, error) {
+	return RecordValue(d.dData, DownloadFieldHash, stringFromAny)
+}
+
+// Name Returns the name from the torrent's metainfo.
+func (d *Download) Name() (string, error) {
+	return RecordValue(d.dData, DownloadFieldName, stringFromAny)
+}
+
+// IsStarted Returns true if the download is started, which includes paused downloads, and false if it is stopped.
+func (d *Download) IsStarted() (bool, error) {
+	return RecordValue(d.dData, DownloadFieldState, boolFromAny)
+}
+
+// StateChanged Returns the last time the download was started or stopped.
+func (d *Download) StateChanged() (time.Time, error) {
+	return RecordValue(d.dData, DownloadFieldStateChanged, timeFromAny)
+}
+
+// IsComplete Returns true if every wanted chunk of the download has been downloaded.
+func (d *Download) IsComplete() (bool, error) {
+	return RecordValue(d.dData, DownloadFieldComplete, boolFromAny)
+}
+
+// Directory Returns where the download's data lives. For a multi-file download this is the directory holding its files,
+// for a single file download it is the directory holding that file.
+func (d *Download) Directory() (string, error) {
+	return RecordValue(d.dData, DownloadFieldDirectory, stringFromAny)
+}
+
+// DirectoryBase Returns the download's base directory, which is the same as Directory for multi-file downloads.
+func (d *Download) DirectoryBase() (string, error) {
+	return RecordValue(d.dData, DownloadFieldDirectoryBase, stringFromAny)
+}
+
+// TiedToFile Returns the path of the .torrent file the download is tied to, if any.
+func (d *Download) TiedToFile() (string, error) {
+	return RecordValue(d.dData, DownloadFieldTiedToFile, stringFromAny)
+}
+
+// IsMultiFile Returns true if the torrent holds a directory of files rather than a single file.
+func (d *Download) IsMultiFile() (bool, error) {
+	return RecordValue(d.dData, DownloadFieldIsMultiFile, boolFromAny)
+}
+
+// IsOpen Returns true if rTorrent has the download's files open, which a started download always does.
+func (d *Download) IsOpen() (bool, error) {
+	return RecordValue(d.dData, DownloadFieldIsOpen, boolFromAny)
+}
+
+// BasePath Returns the path of the download's data, the file itself for a single file download and its directory for a
+// multi-file one. rTorrent only reports it for open downloads, giving "" otherwise.
+func (d *Download) BasePath() (string, error) {
+	return RecordValue(d.dData, DownloadFieldBasePath, stringFromAny)
+}
+
+// BaseFilename Returns the name rTorrent's UI shows for the download, the last element of its base path.
+func (d *Download) BaseFilename() (string, error) {
+	return RecordValue(d.dData, DownloadFieldBaseFilename, stringFromAny)
+}
+
+// Custom Returns the value of one of the five numbered custom slots, d.custom1 through d.custom5. ruTorrent keeps a
+// download's label in the first.
+func (d *Download) Custom(slot int) (string, error) {
+	if slot < 1 || slot > len(customFields) {
+		return "", fmt.Errorf("%w: custom%d", ErrUnknownField, slot)
+	}
+	return RecordValue(d.dData, customFields[slot-1], stringFromAny)
+}
+
+// SizeBytes Returns the total size of the download's content in bytes.
+func (d *Download) SizeBytes() (int, error) {
+	return RecordValue(d.dData, DownloadFieldSizeBytes, intFromAny)
+}
+
+// SizeChunks Returns the number of chunks (pieces) the download's content is split into.
+func (d *Download) SizeChunks() (int, error) {
+	return RecordValue(d.dData, DownloadFieldSizeChunks, intFromAny)
+}
+
+// CompletedChunks Returns the number of chunks that have been downloaded and verified.
+func (d *Download) CompletedChunks() (int, error) {
+	return RecordValue(d.dData, DownloadFieldCompletedChunks, intFromAny)
+}
+
+// Progress Returns the fraction of the download's chunks that are complete, from 0 to 1. It needs both the
+// completed_chunks and size_chunks fields.
+func (d *Download) Progress() (float64, error) {
+	completed, err := d.CompletedChunks()
+	if err != nil {
+		return 0, err
+	}
+	size, err := d.SizeChunks()
+	if err != nil {
+		return 0, err
+	}
+	if size == 0 {
+		return 0, nil
+	}
+	return float64(completed) / float64(size), nil
+}
+
+// UpTotal Returns the total bytes uploaded for the download.
+func (d *Download) UpTotal() (int, error) {
+	return RecordValue(d.dData, DownloadFieldUpTotal, intFromAny)
+}
+
+// DownTotal Returns the total bytes downloaded for the download.
+func (d *Download) DownTotal() (int, error) {
+	return RecordValue(d.dData, DownloadFieldDownTotal, intFromAny)
+}
+
+// Message Returns the download's latest message, which is where rTorrent puts tracker errors.
+func (d *Download) Message() (string, error) {
+	return RecordValue(d.dData, DownloadFieldMessage, stringFromAny)
+}
+
+// Ratio Returns the download's upload to download ratio, which rTorrent reports multiplied by 1000.
+func (d *Download) Ratio() (float64, error) {
+	return RecordValue(d.dData, DownloadFieldRatio, ratioFromAny)
+}
+
+// FinishedTime Returns when the download completed, or the Unix epoch if it hasn't.
+func (d *Download) FinishedTime() (time.Time, error) {
+	return RecordValue(d.dData, DownloadFieldFinished, timeFromAny)
+}
+
 // A DownloadService is a wrapper for Client methods which operate on downloads.
 type DownloadService struct {
 	C Client
 }
 
+// List retrieves the info-hashes of the downloads in view, or of every download if view is empty. The methods below
+// list the common views without a context, so List is the one to use when a call has to be cancellable, as when the
+// client has a limiter it may have to wait on.
+func (s *DownloadService) List(ctx context.Context, view string) ([]string, error) {
+	if view == "" {
+		return s.C.getStringSlice(ctx, downloadList)
+	}
+	return s.C.getStringSlice(ctx, downloadList, view)
+}
+
 // All retrieves a list of all downloads from rTorrent.
 func (s *DownloadService) All() ([]string, error) {
-	return s.C.getStringSlice(downloadList)
+	return s.List(context.Background(), "")
 }
 
 // Started retrieves a list of started downloads from rTorrent.
 func (s *DownloadService) Started() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "started")
+	return s.List(context.Background(), "started")
 }
 
 // Stopped retrieves a list of stopped downloads from rTorrent.
 func (s *DownloadService) Stopped() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "stopped")
+	return s.List(context.Background(), "stopped")
 }
 
 // Complete retrieves a list of complete downloads from rTorrent.
 func (s *DownloadService) Complete() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "complete")
+	return s.List(context.Background(), "complete")
 }
 
 // Incomplete retrieves a list of incomplete downloads from rTorrent.
 func (s *DownloadService) Incomplete() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "incomplete")
+	return s.List(context.Background(), "incomplete")
 }
 
 // Hashing retrieves a list of hashing downloads from rTorrent.
 func (s *DownloadService) Hashing() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "hashing")
+	return s.List(context.Background(), "hashing")
 }
 
 // Seeding retrieves a list of seeding downloads from rTorrent.
 func (s *DownloadService) Seeding() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "seeding")
+	return s.List(context.Background(), "seeding")
 }
 
 // Leeching retrieves a list of leeching downloads from rTorrent.
 func (s *DownloadService) Leeching() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "leeching")
+	return s.List(context.Background(), "leeching")
 }
 
 // Active retrieves a list of active downloads from rTorrent.
 func (s *DownloadService) Active() ([]string, error) {
-	return s.C.getStringSlice(downloadList, "active")
+	return s.List(context.Background(), "active")
 }
 
 // DownloadWithDetails retrieves a list of downloads from rTorrent along with additional details as specified by the commands slice.
+// DownloadRows does the same into a struct of your own, with the values converted, and Downloads into Downloads, both
+// with a context.
 func (s *DownloadService) DownloadWithDetails(commands []string) ([][]any, error) {
-	return s.C.getSliceSlice(downloadListMultiCall, slices.Concat([]string{"default"}, commands)...)
+	return s.C.getSliceSlice(context.Background(), downloadListMultiCall, slices.Concat([]string{"default"}, commands)...)
+}
+
+// Downloads retrieves every download in view, or the default view if view is empty, with the requested fields. The
+// hash is always fetched, whether asked for or not, so the downloads can be told apart. Any error still returns the
+// downloads populated as far as they got.
+func (s *DownloadService) Downloads(ctx context.Context, view string, fields []DownloadField) ([]*Download, error) {
+	if view == "" {
+		view = "default"
+	}
+	if !slices.Contains(fields, DownloadFieldHash) {
+		fields = slices.Concat([]DownloadField{DownloadFieldHash}, fields)
+	}
+	args, err := downloadFields.Arguments(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	sliceOfSlices, err := s.C.getSliceSlice(ctx, downloadListMultiCall, slices.Concat([]string{view}, args)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeMulticall(downloadFields, fields, sliceOfSlices, func(_ int, r Record[DownloadField]) *Download {
+		return &Download{dData: r}
+	})
+}
+
+// DownloadDataFromSlice builds a download's data map by pairing the requested fields with the values rTorrent returned
+func DownloadDataFromSlice(fields []DownloadField, data []any) (map[DownloadField]any, error) {
+	return downloadFields.Record(fields, data)
+}
+
+// Download retrieves a single download by its info-hash, in either case, along with the requested fields, in one
+// request. It returns ErrDownloadNotFound if rTorrent has no download with that info-hash, and ErrBadData for an
+// infoHash that isn't 40 hex digits. Like Page, it needs rTorrent 0.9.7 or later.
+func (s *DownloadService) Download(ctx context.Context, infoHash string, fields []DownloadField) (*Download, error) {
+	args, err := downloadFields.Arguments(fields)
+	if err != nil {
+		return nil, err
+	}
+	// rTorrent compares hashes exactly, and only has them in upper case
+	downloads, err := s.pageDownloads(ctx, "", []sortKey{{hash: strings.ToUpper(infoHash)}}, fields, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(downloads) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDownloadNotFound, infoHash)
+	}
+	return downloads[0], nil
 }
 
 // BaseFilename retrieves the base filename shown in the rTorrent UI for a specific download, by its info-hash.
 func (s *DownloadService) BaseFilename(infoHash string) (string, error) {
-	return s.C.getString("d.base_filename", infoHash)
+	return s.C.getString(context.Background(), "d.base_filename", infoHash)
+}
+
+// Name retrieves the name from the torrent's metainfo for a specific download, by its info-hash.
+func (s *DownloadService) Name(ctx context.Context, infoHash string) (string, error) {
+	return s.C.getString(ctx, "d.name", infoHash)
+}
+
+// SizeBytes retrieves the total size in bytes of a specific download's content, by its info-hash.
+func (s *DownloadService) SizeBytes(ctx context.Context, infoHash string) (int, error) {
+	return s.C.getInt(ctx, "d.size_bytes", infoHash)
+}
+
+// Start starts a download, opening it first if it is closed.
+func (s *DownloadService) Start(ctx context.Context, infoHash string) error {
+	return s.C.execute(ctx, "d.start", infoHash)
+}
+
+// Stop stops a download, leaving its files open.
+func (s *DownloadService) Stop(ctx context.Context, infoHash string) error {
+	return s.C.execute(ctx, "d.stop", infoHash)
+}
+
+// Open opens a download's files without starting it.
+func (s *DownloadService) Open(ctx context.Context, infoHash string) error {
+	return s.C.execute(ctx, "d.open", infoHash)
+}
+
+// Close closes a stopped download's files, which rTorrent requires before its directory can be changed.
+func (s *DownloadService) Close(ctx context.Context, infoHash string) error {
+	return s.C.execute(ctx, "d.close", infoHash)
+}
+
+// Erase removes a download from rTorrent, leaving its data where it is.
+func (s *DownloadService) Erase(ctx context.Context, infoHash string) error {
+	return s.C.execute(ctx, "d.erase", infoHash)
+}
+
+// SetCustom sets one of the five numbered custom slots, d.custom1 through d.custom5, of a download.
+func (s *DownloadService) SetCustom(ctx context.Context, infoHash string, slot int, value string) error {
+	if slot < 1 || slot > len(customFields) {
+		return fmt.Errorf("%w: custom%d", ErrUnknownField, slot)
+	}
+	field, _ := downloadFields.Lookup(customFields[slot-1])
+	cmd, err := field.SetCommand()
+	if err != nil {
+		return err
+	}
+	return s.C.execute(ctx, cmd, infoHash, value)
+}
+
+// SetDirectory changes where rTorrent looks for a download's data, without moving the data itself. For a single file
+// download dir is the directory holding the file, while for a multi-file download it is the directory to hold the
+// download's own directory, which rTorrent names after the torrent. rTorrent refuses to change the directory of an open
+// download, so it must be stopped and closed first.
+func (s *DownloadService) SetDirectory(ctx context.Context, infoHash, dir string) error {
+	return s.C.execute(ctx, "d.directory.set", infoHash, dir)
+}
+
+// SetDirectoryBase changes where rTorrent looks for a download's data as SetDirectory does, except that for a
+// multi-file download dir is the download's own directory rather than its parent.
+func (s *DownloadService) SetDirectoryBase(ctx context.Context, infoHash, dir string) error {
+	return s.C.execute(ctx, "d.directory_base.set", infoHash, dir)
 }
 
-// DownloadRate retrieves the current download rate in bytes for a specific download, by its info-hash.
+// DownloadRate retrieves the current download rate in bytes for a specific download, by its info-hash. This and the
+// counters below take no context; Download reads any of them, and more besides, with one.
 func (s *DownloadService) DownloadRate(infoHash string) (int, error) {
-	return s.C.getInt("d.down.rate", infoHash)
+	return s.C.getInt(context.Background(), "d.down.rate", infoHash)
 }
 
 // DownloadTotal retrieves the total bytes downloaded for a specific download, by its info-hash.
 func (s *DownloadService) DownloadTotal(infoHash string) (int, error) {
-	return s.C.getInt("d.down.total", infoHash)
+	return s.C.getInt(context.Background(), "d.down.total", infoHash)
 }
 
 // UploadRate retrieves the current upload rate in bytes for a specific download, by its info-hash.
 func (s *DownloadService) UploadRate(infoHash string) (int, error) {
-	return s.C.getInt("d.up.rate", infoHash)
+	return s.C.getInt(context.Background(), "d.up.rate", infoHash)
 }
 
 // UploadTotal retrieves the total bytes uploaded for a specific download, by its info-hash.
 func (s *DownloadService) UploadTotal(infoHash string) (int, error) {
-	return s.C.getInt("d.up.total", infoHash)
+	return s.C.getInt(context.Background(), "d.up.total", infoHash)
 }
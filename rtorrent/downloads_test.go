@@ -1,8 +1,11 @@
 package rtorrent
 
 import (
+	"context"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,6 +55,15 @@ func TestDownloadServiceLists(t *testing.T) {
 	}
 }
 
+func TestDownloadServiceList(t *testing.T) {
+	t.Parallel()
+
+	ds := &DownloadService{C: testClient(t, downloadList, []string{"", "seeding"}, testDownloads)}
+	got, err := ds.List(t.Context(), "seeding")
+	require.NoError(t, err)
+	assert.Equal(t, testDownloads, got)
+}
+
 func TestDownloadServiceCountersByInfoHash(t *testing.T) {
 	t.Parallel()
 
@@ -64,6 +76,9 @@ func TestDownloadServiceCountersByInfoHash(t *testing.T) {
 		{"download total", "d.down.total", (*DownloadService).DownloadTotal},
 		{"upload rate", "d.up.rate", (*DownloadService).UploadRate},
 		{"upload total", "d.up.total", (*DownloadService).UploadTotal},
+		{"size bytes", "d.size_bytes", func(ds *DownloadService, hash string) (int, error) {
+			return ds.SizeBytes(t.Context(), hash)
+		}},
 	}
 
 	for _, tt := range tests {
@@ -79,16 +94,33 @@ func TestDownloadServiceCountersByInfoHash(t *testing.T) {
 	}
 }
 
-func TestDownloadServiceBaseFilename(t *testing.T) {
+func TestDownloadServiceNames(t *testing.T) {
 	t.Parallel()
 
 	const wantName = "foobar"
 
-	ds := &DownloadService{C: testClient(t, "d.base_filename", []string{testInfoHash}, wantName)}
+	tests := []struct {
+		name   string
+		method string
+		call   func(*DownloadService, string) (string, error)
+	}{
+		{"base filename", "d.base_filename", (*DownloadService).BaseFilename},
+		{"name", "d.name", func(ds *DownloadService, hash string) (string, error) {
+			return ds.Name(t.Context(), hash)
+		}},
+	}
 
-	got, err := ds.BaseFilename(testInfoHash)
-	require.NoError(t, err)
-	assert.Equal(t, wantName, got)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds := &DownloadService{C: testClient(t, tt.method, []string{testInfoHash}, wantName)}
+
+			got, err := tt.call(ds, testInfoHash)
+			require.NoError(t, err)
+			assert.Equal(t, wantName, got)
+		})
+	}
 }
 
 func TestDownloadServiceWithDetails(t *testing.T) {
@@ -99,10 +131,180 @@ func TestDownloadServiceWithDetails(t *testing.T) {
 	ds := &DownloadService{C: mockClient}
 
 	// DownloadWithDetails always prepends "default" to the caller's commands
-	mockClient.EXPECT().getSliceSlice(downloadListMultiCall, "default", "d.name=").
+	mockClient.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.name=").
 		Return([][]any{{"a name"}}, nil)
 
 	got, err := ds.DownloadWithDetails([]string{"d.name="})
 	require.NoError(t, err)
 	assert.Equal(t, [][]any{{"a name"}}, got)
 }
+
+func TestDownloadServiceDownloads(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hash is always fetched first", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockClient := NewMockClient(ctrl)
+		ds := &DownloadService{C: mockClient}
+
+		mockClient.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.name=", "d.state=").
+			Return([][]any{{testDownloads[0], "a", int64(1)}, {testDownloads[1], "b", int64(0)}}, nil)
+
+		got, err := ds.Downloads(t.Context(), "", []DownloadField{DownloadFieldName, DownloadFieldState})
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+
+		hash, err := got[1].Hash()
+		require.NoError(t, err)
+		assert.Equal(t, testDownloads[1], hash)
+		started, err := got[1].IsStarted()
+		require.NoError(t, err)
+		assert.False(t, started)
+	})
+
+	t.Run("named view", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockClient := NewMockClient(ctrl)
+		ds := &DownloadService{C: mockClient}
+
+		mockClient.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "seeding", "d.hash=").Return(nil, nil)
+
+		got, err := ds.Downloads(t.Context(), "seeding", []DownloadField{DownloadFieldHash})
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		t.Parallel()
+
+		// No call is expected, the field should be rejected before anything reaches rTorrent
+		ds := &DownloadService{C: NewMockClient(gomock.NewController(t))}
+		_, err := ds.Downloads(t.Context(), "", []DownloadField{"bogus"})
+		require.ErrorIs(t, err, ErrUnknownField)
+	})
+
+	t.Run("short row", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mockClient := NewMockClient(ctrl)
+		ds := &DownloadService{C: mockClient}
+
+		mockClient.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.name=").
+			Return([][]any{{testDownloads[0], "a"}, {testDownloads[1]}}, nil)
+
+		got, err := ds.Downloads(t.Context(), "", []DownloadField{DownloadFieldName})
+		require.ErrorIs(t, err, ErrBadData)
+		assert.Len(t, got, 1, "the downloads before the bad row are still returned")
+	})
+}
+
+func TestDownloadGetters(t *testing.T) {
+	t.Parallel()
+
+	d := NewDownload(map[DownloadField]any{
+		DownloadFieldHash:            testInfoHash,
+		DownloadFieldDirectory:       "/data/a",
+		DownloadFieldCustom1:         "linux",
+		DownloadFieldComplete:        int64(0),
+		DownloadFieldSizeChunks:      int64(8),
+		DownloadFieldCompletedChunks: int64(2),
+		DownloadFieldStateChanged:    int64(1700000000),
+		DownloadFieldRatio:           int64(2500),
+	})
+
+	ratio, err := d.Ratio()
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, ratio, 0)
+
+	label, err := d.Custom(1)
+	require.NoError(t, err)
+	assert.Equal(t, "linux", label)
+
+	_, err = d.Custom(2)
+	require.ErrorIs(t, err, ErrNoField)
+	_, err = d.Custom(6)
+	require.ErrorIs(t, err, ErrUnknownField)
+
+	progress, err := d.Progress()
+	require.NoError(t, err)
+	assert.InDelta(t, 0.25, progress, 0)
+
+	assert.Equal(t, "false", d.GetFieldValueAsString(DownloadFieldComplete))
+	assert.Equal(t, "<na>", d.GetFieldValueAsString(DownloadFieldName))
+	assert.Equal(t, "<ne>", d.GetFieldValueAsString("bogus"))
+	assert.Equal(t, "Download: data: <complete: false, completed_chunks: 2, custom1: linux, directory: /data/a, hash: "+
+		testInfoHash+", ratio: 2.5, size_chunks: 8, state_changed: "+time.Unix(1700000000, 0).String()+">", d.String())
+}
+
+func TestAllDownloadFields(t *testing.T) {
+	t.Parallel()
+
+	fields := AllDownloadFields()
+	assert.True(t, slices.IsSorted(fields))
+	assert.Contains(t, fields, DownloadFieldCustom5)
+	assert.Equal(t, "d.up.total=", DownloadFieldUpTotal.AsXMLRPCArgument())
+}
+
+func TestDownloadServiceSetDirectory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		method string
+		call   func(*DownloadService, context.Context, string, string) error
+	}{
+		{"directory", "d.directory.set", (*DownloadService).SetDirectory},
+		{"directory base", "d.directory_base.set", (*DownloadService).SetDirectoryBase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// rTorrent answers a successful set with a 0
+			ds := &DownloadService{C: testClient(t, tt.method, []string{testInfoHash, "/mnt/new"}, 0)}
+			require.NoError(t, tt.call(ds, t.Context(), testInfoHash, "/mnt/new"))
+		})
+	}
+}
+
+func TestDownloadServiceActions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		method string
+		call   func(*DownloadService, context.Context, string) error
+	}{
+		{"start", "d.start", (*DownloadService).Start},
+		{"stop", "d.stop", (*DownloadService).Stop},
+		{"open", "d.open", (*DownloadService).Open},
+		{"close", "d.close", (*DownloadService).Close},
+		{"erase", "d.erase", (*DownloadService).Erase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds := &DownloadService{C: testClient(t, tt.method, []string{testInfoHash}, 0)}
+			require.NoError(t, tt.call(ds, t.Context(), testInfoHash))
+		})
+	}
+}
+
+func TestDownloadServiceSetCustom(t *testing.T) {
+	t.Parallel()
+
+	ds := &DownloadService{C: testClient(t, "d.custom3.set", []string{testInfoHash, "keep"}, 0)}
+	require.NoError(t, ds.SetCustom(t.Context(), testInfoHash, 3, "keep"))
+
+	// An out of range slot never reaches rTorrent, which the mock's lack of expectations checks
+	ds = &DownloadService{C: NewMockClient(gomock.NewController(t))}
+	require.ErrorIs(t, ds.SetCustom(t.Context(), testInfoHash, 0, "keep"), ErrUnknownField)
+}
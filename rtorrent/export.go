@@ -0,0 +1,225 @@
+package rtorrent
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export columns that aren't fields of their own
+const (
+	columnInfoHash = "info_hash"
+	columnIndex    = "index"
+)
+
+// plainValue turns a field's typed value into one encoders handle without help. Enums become their names, and timestamps
+// are given in UTC, except for the zero rTorrent uses for never, which becomes nil.
+func plainValue(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		if v.Unix() == 0 {
+			return nil
+		}
+		return v.UTC()
+	case TrackerEvent:
+		return v.String()
+	case TrackerType:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// columnName is a field's name as an export column, with the dots of names such as up.total replaced so that tools
+// like jq don't read them as paths
+func columnName(field string) string {
+	return strings.ReplaceAll(field, ".", "_")
+}
+
+// record collects the tracker's typed values by column name, with nil for every field it doesn't have or can't read
+func (t *Tracker) record() map[string]any {
+	// The extra two are the info-hash and index columns
+	rec := fieldRecord(trackerFields, t.tData, 2)
+	rec[columnInfoHash], rec[columnIndex] = nil, nil
+	if t.ti != nil {
+		rec[columnInfoHash] = t.ti.InfoHash
+		if t.ti.Index >= 0 {
+			rec[columnIndex] = t.ti.Index
+		}
+	}
+	return rec
+}
+
+// fieldRecord collects the typed value of every field of fs in r by column name, with nil for those r doesn't have
+// or can't read, leaving room for extra columns of the caller's own
+func fieldRecord[K ~string](fs *FieldSet[K], r Record[K], extra int) map[string]any {
+	rec := make(map[string]any, len(fs.fields)+extra)
+	for name, f := range fs.fields {
+		v, err := f.Value(r)
+		if err != nil {
+			v = nil
+		}
+		rec[columnName(string(name))] = plainValue(v)
+	}
+	return rec
+}
+
+// MarshalJSON writes the tracker as a JSON object of every tracker field, along with its info_hash and index. Values
+// are typed, timestamps are RFC 3339, and fields the tracker wasn't retrieved with are null, as are timestamps of
+// events that haven't happened.
+func (t *Tracker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.record())
+}
+
+// MarshalYAML gives YAML encoders the same mapping MarshalJSON writes.
+func (t *Tracker) MarshalYAML() (any, error) {
+	return t.record(), nil
+}
+
+// record collects the download's typed values by column name, as Tracker.record does
+func (d *Download) record() map[string]any {
+	return fieldRecord(downloadFields, d.dData, 0)
+}
+
+// MarshalJSON writes the download as a JSON object of every download field, in the same way as Tracker.MarshalJSON.
+func (d *Download) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.record())
+}
+
+// MarshalYAML gives YAML encoders the same mapping MarshalJSON writes.
+func (d *Download) MarshalYAML() (any, error) {
+	return d.record(), nil
+}
+
+// record collects the file's typed values by column name, along with its info_hash and index
+func (f *File) record() map[string]any {
+	rec := fieldRecord(fileFields, f.fData, 2)
+	rec[columnInfoHash], rec[columnIndex] = f.infoHash, f.index
+	return rec
+}
+
+// MarshalJSON writes the file as a JSON object of every file field, along with its info_hash and index, in the same
+// way as Tracker.MarshalJSON.
+func (f *File) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.record())
+}
+
+// MarshalYAML gives YAML encoders the same mapping MarshalJSON writes.
+func (f *File) MarshalYAML() (any, error) {
+	return f.record(), nil
+}
+
+// record collects the peer's typed values by column name, along with its info_hash
+func (p *Peer) record() map[string]any {
+	rec := fieldRecord(peerFields, p.pData, 1)
+	rec[columnInfoHash] = p.infoHash
+	return rec
+}
+
+// MarshalJSON writes the peer as a JSON object of every peer field, along with its info_hash, in the same way as
+// Tracker.MarshalJSON.
+func (p *Peer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.record())
+}
+
+// MarshalYAML gives YAML encoders the same mapping MarshalJSON writes.
+func (p *Peer) MarshalYAML() (any, error) {
+	return p.record(), nil
+}
+
+// WriteTrackersCSV writes trackers to w as CSV, with a header row and then a row per tracker of its info-hash, its
+// index and the given fields. Values are written as MarshalJSON would write them, with nulls left empty.
+func WriteTrackersCSV(w io.Writer, trackers []*Tracker, fields []TrackerField) error {
+	return writeTrackers(w, ',', trackers, fields)
+}
+
+// WriteTrackersTSV is WriteTrackersCSV with tabs between the values.
+func WriteTrackersTSV(w io.Writer, trackers []*Tracker, fields []TrackerField) error {
+	return writeTrackers(w, '\t', trackers, fields)
+}
+
+func writeTrackers(w io.Writer, comma rune, trackers []*Tracker, fields []TrackerField) error {
+	columns := []string{columnInfoHash, columnIndex}
+	for _, f := range fields {
+		if _, ok := trackerFields.Lookup(f); !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownField, f)
+		}
+		columns = append(columns, columnName(f.String()))
+	}
+	rows := make([]map[string]any, 0, len(trackers))
+	for _, t := range trackers {
+		rows = append(rows, t.record())
+	}
+	return writeTable(w, comma, columns, rows)
+}
+
+// WriteDownloadsCSV writes downloads to w as CSV, with a header row and then a row per download of the given fields,
+// written as WriteTrackersCSV writes them.
+func WriteDownloadsCSV(w io.Writer, downloads []*Download, fields []DownloadField) error {
+	return writeDownloads(w, ',', downloads, fields)
+}
+
+// WriteDownloadsTSV is WriteDownloadsCSV with tabs between the values.
+func WriteDownloadsTSV(w io.Writer, downloads []*Download, fields []DownloadField) error {
+	return writeDownloads(w, '\t', downloads, fields)
+}
+
+func writeDownloads(w io.Writer, comma rune, downloads []*Download, fields []DownloadField) error {
+	columns := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if _, ok := downloadFields.Lookup(f); !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownField, f)
+		}
+		columns = append(columns, columnName(f.String()))
+	}
+	rows := make([]map[string]any, 0, len(downloads))
+	for _, d := range downloads {
+		rows = append(rows, d.record())
+	}
+	return writeTable(w, comma, columns, rows)
+}
+
+// writeTable writes the given columns of rows, headed by the column names
+func writeTable(w io.Writer, comma rune, columns []string, rows []map[string]any) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	line := make([]string, len(columns))
+	for _, row := range rows {
+		for i, col := range columns {
+			line[i] = tableValue(row[col])
+		}
+		if err := cw.Write(line); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// tableValue renders a record's value as a CSV cell
+func tableValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return formatFloat(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
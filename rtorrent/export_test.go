@@ -0,0 +1,112 @@
+package rtorrent
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func exportTracker() *Tracker {
+	return NewTracker(NewTrackerWithIndex(testInfoHash, 1), map[TrackerField]any{
+		FieldURL:            "udp://tracker.example:1337/announce",
+		FieldType:           int64(TypeUDP),
+		FieldIsEnabled:      int64(1),
+		FieldSuccessCounter: int64(12),
+		FieldSuccessLast:    int64(1700000000),
+		FieldFailedLast:     int64(0),
+		FieldMinInterval:    "not a number",
+	})
+}
+
+func TestTracker_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	b, err := json.Marshal(exportTracker())
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Len(t, got, len(trackerFields.fields)+2, "every field is present, null or not")
+	assert.Equal(t, testInfoHash, got["info_hash"])
+	assert.InDelta(t, 1, got["index"], 0)
+	assert.Equal(t, "udp://tracker.example:1337/announce", got["url"])
+	assert.Equal(t, "UDP", got["type"])
+	assert.True(t, got["is_enabled"].(bool))
+	assert.InDelta(t, 12, got["success_counter"], 0)
+	assert.Equal(t, "2023-11-14T22:13:20Z", got["success_time_last"])
+	assert.Nil(t, got["failed_time_last"], "a timestamp of never")
+	assert.Nil(t, got["min_interval"], "an unreadable value")
+	assert.Nil(t, got["id"], "a field the tracker wasn't retrieved with")
+
+	// A tracker for every one of a download's trackers has no single index
+	b, err = json.Marshal(NewTracker(NewTrackerNoIndex(testInfoHash), nil))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"index":null`)
+}
+
+func TestDownload_MarshalJSONAndYAML(t *testing.T) {
+	t.Parallel()
+
+	d := NewDownload(map[DownloadField]any{
+		DownloadFieldHash:    testInfoHash,
+		DownloadFieldUpTotal: int64(2048),
+		DownloadFieldRatio:   int64(1500),
+		DownloadFieldState:   int64(0),
+	})
+
+	b, err := json.Marshal(d)
+	require.NoError(t, err)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, testInfoHash, got["hash"])
+	assert.InDelta(t, 2048, got["up_total"], 0, "dotted names are flattened")
+	assert.InDelta(t, 1.5, got["ratio"], 0)
+	assert.False(t, got["state"].(bool))
+	assert.Contains(t, got, "timestamp_finished")
+	assert.Nil(t, got["timestamp_finished"])
+
+	y, err := yaml.Marshal(d)
+	require.NoError(t, err)
+	assert.Contains(t, string(y), "ratio: 1.5\n")
+	assert.Contains(t, string(y), "up_total: 2048\n")
+	assert.Contains(t, string(y), "name: null\n")
+}
+
+func TestWriteTrackersCSV(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	fields := []TrackerField{FieldURL, FieldType, FieldSuccessLast, FieldFailedLast}
+	require.NoError(t, WriteTrackersCSV(&buf, []*Tracker{exportTracker()}, fields))
+	assert.Equal(t, "info_hash,index,url,type,success_time_last,failed_time_last\n"+
+		testInfoHash+",1,udp://tracker.example:1337/announce,UDP,2023-11-14T22:13:20Z,\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteTrackersTSV(&buf, []*Tracker{exportTracker()}, []TrackerField{FieldIsEnabled}))
+	assert.Equal(t, "info_hash\tindex\tis_enabled\n"+testInfoHash+"\t1\ttrue\n", buf.String())
+
+	require.ErrorIs(t, WriteTrackersCSV(&buf, nil, []TrackerField{"bogus"}), ErrUnknownField)
+}
+
+func TestWriteDownloadsCSV(t *testing.T) {
+	t.Parallel()
+
+	downloads := []*Download{
+		NewDownload(map[DownloadField]any{DownloadFieldName: "a, with a comma", DownloadFieldDownTotal: int64(7)}),
+		NewDownload(map[DownloadField]any{DownloadFieldName: "b"}),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDownloadsCSV(&buf, downloads, []DownloadField{DownloadFieldName, DownloadFieldDownTotal}))
+	assert.Equal(t, "name,down_total\n\"a, with a comma\",7\nb,\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteDownloadsTSV(&buf, downloads, []DownloadField{DownloadFieldName}))
+	assert.Equal(t, "name\na, with a comma\nb\n", buf.String())
+
+	require.ErrorIs(t, WriteDownloadsCSV(&buf, nil, []DownloadField{"bogus"}), ErrUnknownField)
+}
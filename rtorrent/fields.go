@@ -0,0 +1,191 @@
+package rtorrent
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// Command prefixes of the kinds of entity rTorrent reports fields for
+const (
+	PrefixDownload = "d."
+	PrefixTracker  = "t."
+	PrefixFile     = "f."
+	PrefixPeer     = "p."
+)
+
+// ErrNotSettable is returned by Field.SetCommand for fields rTorrent only lets us read.
+var ErrNotSettable = errors.New("field is not settable")
+
+// Field describes one field of an rTorrent entity, such as a tracker's URL, whose fields are named by K: the command
+// that reads it, how its raw value converts to a typed one, how that renders as a string, and whether rTorrent lets
+// it be set. Fields are kept in a FieldSet, one per kind of entity.
+type Field[K ~string] struct {
+	name     K
+	prefix   string
+	settable bool
+	typ      reflect.Type
+	value    func(any) (any, error)
+	format   func(any) (string, error)
+}
+
+// NewField describes the field name of entities whose commands start with prefix, one of the Prefix constants. conv
+// converts the raw value rTorrent sends into a typed one, and format renders that for GetFieldValueAsString.
+func NewField[K ~string, T any](prefix string, name K, conv func(any) (T, error), format func(T) string) Field[K] {
+	return Field[K]{
+		name:   name,
+		prefix: prefix,
+		typ:    reflect.TypeFor[T](),
+		value: func(raw any) (any, error) {
+			return conv(raw)
+		},
+		format: func(raw any) (string, error) {
+			v, err := conv(raw)
+			if err != nil {
+				return "", err
+			}
+			return format(v), nil
+		},
+	}
+}
+
+// AsSettable returns a copy of the field marked as one rTorrent has a .set command for.
+func (f Field[K]) AsSettable() Field[K] {
+	f.settable = true
+	return f
+}
+
+// Name Returns the field's name, such as url.
+func (f Field[K]) Name() K {
+	return f.name
+}
+
+// Type Returns the type of the field's converted value, the one Value returns.
+func (f Field[K]) Type() reflect.Type {
+	return f.typ
+}
+
+// Command Returns the command that reads the field, such as t.url.
+func (f Field[K]) Command() string {
+	return f.prefix + string(f.name)
+}
+
+// AsXMLRPCArgument Returns the field as an argument to a multicall, such as t.url=.
+func (f Field[K]) AsXMLRPCArgument() string {
+	return f.Command() + "="
+}
+
+// IsSettable Returns true if rTorrent lets the field be set.
+func (f Field[K]) IsSettable() bool {
+	return f.settable
+}
+
+// SetCommand Returns the command that sets the field, such as t.is_enabled.set, or ErrNotSettable if there is none.
+func (f Field[K]) SetCommand() (string, error) {
+	if !f.settable {
+		return "", fmt.Errorf("%w: %s", ErrNotSettable, f.Command())
+	}
+	return f.Command() + ".set", nil
+}
+
+// Value Returns the field's typed value in r, or ErrNoField if r doesn't have it.
+func (f Field[K]) Value(r Record[K]) (any, error) {
+	return RecordValue(r, f.name, f.value)
+}
+
+// Format Returns the field's value in r rendered as a string, or ErrNoField if r doesn't have it.
+func (f Field[K]) Format(r Record[K]) (string, error) {
+	return RecordValue(r, f.name, f.format)
+}
+
+// FieldSet is every field of one kind of entity, by name. Its key set is the authoritative list of valid fields, so
+// that the fields retrieved, rendered and exported can't drift apart.
+type FieldSet[K ~string] struct {
+	fields map[K]Field[K]
+	// noData, if set, is returned for a row with no values at all, which trackers have always reported distinctly
+	noData error
+}
+
+// NewFieldSet collects fields into a FieldSet. A later field with the same name as an earlier one replaces it.
+func NewFieldSet[K ~string](fields ...Field[K]) *FieldSet[K] {
+	return newFieldSet(nil, fields...)
+}
+
+func newFieldSet[K ~string](noData error, fields ...Field[K]) *FieldSet[K] {
+	fs := &FieldSet[K]{fields: make(map[K]Field[K], len(fields)), noData: noData}
+	for _, f := range fields {
+		fs.fields[f.name] = f
+	}
+	return fs
+}
+
+// Lookup Returns the field called name, and whether there is one.
+func (fs *FieldSet[K]) Lookup(name K) (Field[K], bool) {
+	f, ok := fs.fields[name]
+	return f, ok
+}
+
+// Names returns the name of every field in the set, sorted, in a fresh slice each call.
+func (fs *FieldSet[K]) Names() []K {
+	return slices.Sorted(maps.Keys(fs.fields))
+}
+
+// Arguments returns the multicall arguments that retrieve the named fields, in order, or ErrUnknownField for the
+// first name that isn't in the set.
+func (fs *FieldSet[K]) Arguments(names []K) ([]string, error) {
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		f, ok := fs.fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+		args = append(args, f.AsXMLRPCArgument())
+	}
+	return args, nil
+}
+
+// Record builds an entity's record by pairing the requested fields with the values rTorrent returned for them.
+func (fs *FieldSet[K]) Record(names []K, data []any) (Record[K], error) {
+	if len(data) == 0 && fs.noData != nil {
+		return nil, fs.noData
+	}
+	if len(data) < len(names) {
+		return nil, fmt.Errorf("%w: got %d values for %d requested fields", ErrBadData, len(data), len(names))
+	}
+	r := make(Record[K], len(names))
+	// We range the values rather than the names so that the index is provably in bounds for both slices
+	for i, v := range data[:len(names)] {
+		r[names[i]] = v
+	}
+	return r, nil
+}
+
+// Record is an entity's raw values by field name, as rTorrent returned them. Values are only converted as they are
+// read, by RecordValue or the entity's getters.
+type Record[K ~string] map[K]any
+
+// RecordValue looks name up in r and converts it with conv, returning ErrNoField if r doesn't have it.
+func RecordValue[K ~string, T any](r Record[K], name K, conv func(any) (T, error)) (T, error) {
+	data, ok := r[name]
+	if !ok {
+		var zero T
+		return zero, ErrNoField
+	}
+	return conv(data)
+}
+
+// DecodeMulticall turns the rows of a multicall's reply, which asked for the named fields of fs, into typed records,
+// handing build each row's position and record. Any error returns the records built before it.
+func DecodeMulticall[K ~string, R any](fs *FieldSet[K], names []K, rows [][]any, build func(i int, r Record[K]) R) ([]R, error) {
+	out := make([]R, 0, len(rows))
+	for i, row := range rows {
+		r, err := fs.Record(names, row)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, build(i, r))
+	}
+	return out, nil
+}
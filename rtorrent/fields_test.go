@@ -0,0 +1,114 @@
+package rtorrent
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileField stands in for the fields of a kind of entity the package has no type for yet
+type fileField string
+
+type testFile struct {
+	index int
+	path  string
+	size  int
+}
+
+func testFileFields() *FieldSet[fileField] {
+	return NewFieldSet(
+		NewField(PrefixFile, fileField("path"), stringFromAny, identity),
+		NewField(PrefixFile, fileField("size_bytes"), intFromAny, strconv.Itoa),
+		NewField(PrefixFile, fileField("priority"), intFromAny, strconv.Itoa).AsSettable(),
+	)
+}
+
+func TestField(t *testing.T) {
+	t.Parallel()
+
+	fs := testFileFields()
+	size, ok := fs.Lookup("size_bytes")
+	require.True(t, ok)
+	assert.Equal(t, fileField("size_bytes"), size.Name())
+	assert.Equal(t, "f.size_bytes", size.Command())
+	assert.Equal(t, "f.size_bytes=", size.AsXMLRPCArgument())
+	assert.False(t, size.IsSettable())
+	_, err := size.SetCommand()
+	require.ErrorIs(t, err, ErrNotSettable)
+
+	priority, _ := fs.Lookup("priority")
+	cmd, err := priority.SetCommand()
+	require.NoError(t, err)
+	assert.Equal(t, "f.priority.set", cmd)
+
+	r := Record[fileField]{"size_bytes": int64(2048), "path": []int{1}}
+	v, err := size.Value(r)
+	require.NoError(t, err)
+	assert.Equal(t, 2048, v)
+	str, err := size.Format(r)
+	require.NoError(t, err)
+	assert.Equal(t, "2048", str)
+
+	path, _ := fs.Lookup("path")
+	_, err = path.Format(r)
+	require.ErrorIs(t, err, ErrBadData)
+	_, err = priority.Value(r)
+	require.ErrorIs(t, err, ErrNoField)
+
+	_, ok = fs.Lookup("bogus")
+	assert.False(t, ok)
+	assert.Equal(t, []fileField{"path", "priority", "size_bytes"}, fs.Names())
+}
+
+func TestFieldSet_Arguments(t *testing.T) {
+	t.Parallel()
+
+	fs := testFileFields()
+	args, err := fs.Arguments([]fileField{"size_bytes", "path"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"f.size_bytes=", "f.path="}, args)
+
+	_, err = fs.Arguments([]fileField{"path", "bogus"})
+	require.ErrorIs(t, err, ErrUnknownField)
+}
+
+func TestDecodeMulticall(t *testing.T) {
+	t.Parallel()
+
+	fs := testFileFields()
+	fields := []fileField{"path", "size_bytes"}
+	build := func(i int, r Record[fileField]) testFile {
+		path, _ := RecordValue(r, "path", stringFromAny)
+		size, _ := RecordValue(r, "size_bytes", intFromAny)
+		return testFile{index: i, path: path, size: size}
+	}
+
+	files, err := DecodeMulticall(fs, fields, [][]any{{"a.mkv", int64(7)}, {"b.nfo", int64(1)}}, build)
+	require.NoError(t, err)
+	assert.Equal(t, []testFile{{0, "a.mkv", 7}, {1, "b.nfo", 1}}, files)
+
+	files, err = DecodeMulticall(fs, fields, [][]any{{"a.mkv", int64(7)}, {"b.nfo"}}, build)
+	require.ErrorIs(t, err, ErrBadData)
+	assert.Len(t, files, 1, "the records before the bad row are still returned")
+
+	// Only the tracker fields treat a row with nothing in it as a distinct error
+	_, err = DecodeMulticall(fs, nil, [][]any{{}}, build)
+	require.NoError(t, err)
+	_, err = TrackerDataFromSlice(nil, nil)
+	require.ErrorIs(t, err, ErrNoDataFromTracker)
+}
+
+func TestFieldSetsMatchFieldArguments(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range AllTrackerFields() {
+		f, _ := TrackerFields().Lookup(name)
+		assert.Equal(t, name.AsXMLRPCArgument(), f.AsXMLRPCArgument())
+	}
+	for _, name := range AllDownloadFields() {
+		f, _ := DownloadFields().Lookup(name)
+		assert.Equal(t, name.AsXMLRPCArgument(), f.AsXMLRPCArgument())
+	}
+}
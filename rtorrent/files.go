@@ -0,0 +1,121 @@
+package rtorrent
+
+import (
+	"context"
+	"slices"
+	"strconv"
+)
+
+// fileListMultiCall retrieves fields of each of a download's files
+const fileListMultiCall = "f.multicall"
+
+// XMLRPC File Fields
+const (
+	FileFieldPath            = FileField("path")
+	FileFieldSizeBytes       = FileField("size_bytes")
+	FileFieldSizeChunks      = FileField("size_chunks")
+	FileFieldCompletedChunks = FileField("completed_chunks")
+	FileFieldPriority        = FileField("priority")
+)
+
+// fileFields does for files what downloadFields does for downloads
+var fileFields = NewFieldSet(
+	NewField(PrefixFile, FileFieldPath, stringFromAny, identity),
+	NewField(PrefixFile, FileFieldSizeBytes, intFromAny, strconv.Itoa),
+	NewField(PrefixFile, FileFieldSizeChunks, intFromAny, strconv.Itoa),
+	NewField(PrefixFile, FileFieldCompletedChunks, intFromAny, strconv.Itoa),
+	NewField(PrefixFile, FileFieldPriority, intFromAny, strconv.Itoa).AsSettable(),
+)
+
+// AllFileFields returns every retrievable file field, sorted, in a fresh slice each call.
+func AllFileFields() []FileField {
+	return fileFields.Names()
+}
+
+// FileFields returns the file fields as a FieldSet, as TrackerFields does for trackers.
+func FileFields() *FieldSet[FileField] {
+	return fileFields
+}
+
+// FileField is used to specify file related fields that can be retrieved from rTorrent
+type FileField string
+
+func (ff FileField) AsXMLRPCArgument() string {
+	return PrefixFile + string(ff) + "="
+}
+
+func (ff FileField) String() string {
+	return string(ff)
+}
+
+// File is one of a download's files. Like a Download, it only holds the fields it was retrieved with.
+type File struct {
+	infoHash string
+	index    int
+	fData    Record[FileField]
+}
+
+// NewFile builds a File from data already gathered, for the file at index among the files of the download with the
+// given info-hash.
+func NewFile(infoHash string, index int, data map[FileField]any) *File {
+	return &File{infoHash: infoHash, index: index, fData: data}
+}
+
+// InfoHash Returns the info-hash of the download the file belongs to.
+func (f *File) InfoHash() string {
+	return f.infoHash
+}
+
+// Index Returns the file's position among its download's files, which is how rTorrent addresses it.
+func (f *File) Index() int {
+	return f.index
+}
+
+// Path Returns the file's path within the download.
+func (f *File) Path() (string, error) {
+	return RecordValue(f.fData, FileFieldPath, stringFromAny)
+}
+
+// SizeBytes Returns the file's size in bytes.
+func (f *File) SizeBytes() (int, error) {
+	return RecordValue(f.fData, FileFieldSizeBytes, intFromAny)
+}
+
+// SizeChunks Returns the number of chunks the file touches.
+func (f *File) SizeChunks() (int, error) {
+	return RecordValue(f.fData, FileFieldSizeChunks, intFromAny)
+}
+
+// CompletedChunks Returns the number of the file's chunks that have been downloaded and verified.
+func (f *File) CompletedChunks() (int, error) {
+	return RecordValue(f.fData, FileFieldCompletedChunks, intFromAny)
+}
+
+// Priority Returns the file's download priority: 0 to skip it, 1 for normal and 2 for high.
+func (f *File) Priority() (int, error) {
+	return RecordValue(f.fData, FileFieldPriority, intFromAny)
+}
+
+// A FileService is a wrapper for Client methods which operate on a download's files.
+type FileService struct {
+	C Client
+}
+
+// Files retrieves the files of a download along with the requested fields, or every file field if fields is empty, in
+// a single request.
+func (s *FileService) Files(ctx context.Context, infoHash string, fields []FileField) ([]*File, error) {
+	if len(fields) == 0 {
+		fields = AllFileFields()
+	}
+	args, err := fileFields.Arguments(fields)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.C.getSliceSliceByHash(ctx, fileListMultiCall, slices.Concat([]string{infoHash}, args)...)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMulticall(fileFields, fields, rows, func(i int, r Record[FileField]) *File {
+		return &File{infoHash: infoHash, index: i, fData: r}
+	})
+}
@@ -0,0 +1,57 @@
+package rtorrent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFileServiceFiles(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSliceByHash(gomock.Any(), fileListMultiCall, testInfoHash, "f.path=", "f.priority=").
+		Return([][]any{{"a.iso", int64(1)}, {"b.nfo", int64(0)}}, nil)
+
+	files, err := (&FileService{C: m}).Files(t.Context(), testInfoHash, []FileField{FileFieldPath, FileFieldPriority})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, testInfoHash, files[1].InfoHash())
+	assert.Equal(t, 1, files[1].Index())
+	path, err := files[1].Path()
+	require.NoError(t, err)
+	assert.Equal(t, "b.nfo", path)
+	priority, err := files[1].Priority()
+	require.NoError(t, err)
+	assert.Equal(t, 0, priority)
+
+	_, err = files[1].SizeBytes()
+	require.ErrorIs(t, err, ErrNoField)
+
+	b, err := json.Marshal(files[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"info_hash": "`+testInfoHash+`", "index": 0, "path": "a.iso", "priority": 1,
+		"size_bytes": null, "size_chunks": null, "completed_chunks": null}`, string(b))
+}
+
+func TestFileServiceFilesAllFields(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSliceByHash(gomock.Any(), fileListMultiCall, testInfoHash,
+		"f.completed_chunks=", "f.path=", "f.priority=", "f.size_bytes=", "f.size_chunks=").
+		Return([][]any{{int64(2), "a.iso", int64(1), int64(1 << 20), int64(4)}}, nil)
+
+	files, err := (&FileService{C: m}).Files(t.Context(), testInfoHash, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	size, err := files[0].SizeBytes()
+	require.NoError(t, err)
+	assert.Equal(t, 1<<20, size)
+
+	_, err = (&FileService{C: m}).Files(t.Context(), testInfoHash, []FileField{"bogus"})
+	require.ErrorIs(t, err, ErrUnknownField)
+}
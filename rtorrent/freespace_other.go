@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd)
+
+package rtorrent
+
+// freeSpace can't be asked on this platform, so Move skips its check
+func freeSpace(string) (uint64, error) {
+	return 0, errFreeSpaceUnknown
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd
+
+package rtorrent
+
+import "syscall"
+
+// freeSpace reports how many bytes are available to an unprivileged user on the filesystem holding path
+func freeSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil //nolint:gosec,unconvert // the field types differ between platforms
+}
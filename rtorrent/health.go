@@ -0,0 +1,294 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// downAfterFailures is how many announces in a row have to fail before a tracker that has worked before counts as down
+// rather than degraded
+const downAfterFailures = 3
+
+// unregisteredPhrases are what trackers commonly say about torrents they no longer know, as rTorrent passes it on in
+// d.message
+var unregisteredPhrases = []string{
+	"unregistered torrent",
+	"torrent not registered",
+	"torrent is not registered",
+	"torrent not found",
+	"infohash not found",
+	"torrent does not exist",
+}
+
+// trackerHealthFields are the fields ClassifyTracker reads
+var trackerHealthFields = []TrackerField{FieldURL, FieldType, FieldIsEnabled, FieldFailedCounter, FieldSuccessCounter}
+
+// Tracker Health
+const (
+	HealthUnknown TrackerHealth = iota
+	HealthHealthy
+	HealthDegraded
+	HealthDown
+	HealthUnregistered
+	HealthDisabled
+)
+
+// healthRank orders the health values from best to worst, for picking a download's overall health
+var healthRank = []TrackerHealth{HealthHealthy, HealthDegraded, HealthUnknown, HealthDown, HealthUnregistered, HealthDisabled}
+
+// TrackerHealth is how well a tracker is serving a download
+type TrackerHealth int
+
+// String returns the string representation of the TrackerHealth
+func (h TrackerHealth) String() string {
+	switch h {
+	case HealthUnknown:
+		return unknownStr
+	case HealthHealthy:
+		return "Healthy"
+	case HealthDegraded:
+		return "Degraded"
+	case HealthDown:
+		return "Down"
+	case HealthUnregistered:
+		return "Unregistered"
+	case HealthDisabled:
+		return "Disabled"
+	default:
+		return unknownStr
+	}
+}
+
+// Working Returns true for the health of a tracker that is still answering announces, if not always.
+func (h TrackerHealth) Working() bool {
+	return h == HealthHealthy || h == HealthDegraded
+}
+
+// IsUnregisteredMessage Returns true if msg, a download's d.message, says that its tracker doesn't know the torrent,
+// as trackers do for torrents that have been deleted from them.
+func IsUnregisteredMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, phrase := range unregisteredPhrases {
+		if strings.Contains(msg, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackerHealthFields returns the tracker fields ClassifyTracker needs, for callers gathering trackers themselves.
+func TrackerHealthFields() []TrackerField {
+	return slices.Clone(trackerHealthFields)
+}
+
+// ClassifyTracker works out the health of t, which needs the fields TrackerHealthFields lists, given message, the
+// download's d.message. rTorrent keeps no failure text per tracker, only the latest for the whole download, so a
+// tracker that is failing is taken to be the one that reported an unregistered torrent.
+//
+// A tracker that has never been announced to is HealthUnknown. One whose last announce worked is HealthHealthy, one
+// that has worked before but has just failed fewer than three times in a row is HealthDegraded, and one that has
+// failed for longer, or never worked, is HealthDown.
+func ClassifyTracker(t *Tracker, message string) (TrackerHealth, error) {
+	enabled, err := t.IsEnabled()
+	if err != nil {
+		return HealthUnknown, err
+	}
+	if !enabled {
+		return HealthDisabled, nil
+	}
+	// rTorrent resets the failed counter whenever an announce works, so it counts the failures since the last success
+	failed, err := t.FailedCounter()
+	if err != nil {
+		return HealthUnknown, err
+	}
+	succeeded, err := t.SuccessCounter()
+	if err != nil {
+		return HealthUnknown, err
+	}
+
+	switch {
+	case failed == 0 && succeeded == 0:
+		return HealthUnknown, nil
+	case failed == 0:
+		return HealthHealthy, nil
+	case IsUnregisteredMessage(message):
+		return HealthUnregistered, nil
+	case succeeded > 0 && failed < downAfterFailures:
+		return HealthDegraded, nil
+	default:
+		return HealthDown, nil
+	}
+}
+
+// TrackerStatus is a tracker along with its health.
+type TrackerStatus struct {
+	Tracker *Tracker
+	Health  TrackerHealth
+}
+
+// DownloadHealth is the health of each of a download's trackers.
+type DownloadHealth struct {
+	InfoHash string
+	Message  string
+	Trackers []TrackerStatus
+}
+
+// Health Returns the health of the download's best tracker, or HealthUnknown if it has none.
+func (dh *DownloadHealth) Health() TrackerHealth {
+	best := HealthUnknown
+	for i, t := range dh.Trackers {
+		if i == 0 || slices.Index(healthRank, t.Health) < slices.Index(healthRank, best) {
+			best = t.Health
+		}
+	}
+	return best
+}
+
+// Working Returns true if at least one of the download's trackers is working.
+func (dh *DownloadHealth) Working() bool {
+	return dh.Health().Working()
+}
+
+// HostHealth tallies the health of every tracker on a host, across downloads.
+type HostHealth struct {
+	Host   string
+	Counts map[TrackerHealth]int
+}
+
+// Total Returns how many trackers on the host were counted.
+func (hh *HostHealth) Total() int {
+	var total int
+	for _, n := range hh.Counts {
+		total += n
+	}
+	return total
+}
+
+// Health checks the health of each of a download's trackers.
+func (ts *TrackerService) Health(ctx context.Context, infoHash string) (*DownloadHealth, error) {
+	message, err := ts.C.getString(ctx, "d.message", infoHash)
+	if err != nil {
+		return nil, err
+	}
+	return ts.downloadHealth(ctx, infoHash, message)
+}
+
+// HealthAll checks the health of the trackers of every download in view, or every download if view is "". It takes
+// two requests however many downloads there are, one for their messages and one for all their trackers, and leaves
+// out any download added between the two. A download whose trackers can't be classified doesn't stop the others, and
+// comes back in the error.
+func (ts *TrackerService) HealthAll(ctx context.Context, view string) ([]*DownloadHealth, error) {
+	downloads, err := (&DownloadService{C: ts.C}).Downloads(ctx, view, []DownloadField{DownloadFieldHash, DownloadFieldMessage})
+	if err != nil {
+		return nil, err
+	}
+	trackers, err := ts.TrackersByDownload(ctx, view, trackerHealthFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		health []*DownloadHealth
+		errs   []error
+	)
+	for _, d := range downloads {
+		hash, err := d.Hash()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dTrackers, ok := trackers[hash]
+		if !ok {
+			continue
+		}
+		message, err := d.Message()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hash, err))
+			continue
+		}
+		dh, err := NewDownloadHealth(hash, message, dTrackers)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hash, err))
+			continue
+		}
+		health = append(health, dh)
+	}
+	return health, errors.Join(errs...)
+}
+
+func (ts *TrackerService) downloadHealth(ctx context.Context, infoHash, message string) (*DownloadHealth, error) {
+	trackers, err := ts.TrackerWithDetails(ctx, NewTrackerNoIndex(infoHash), trackerHealthFields)
+	if err != nil {
+		return nil, err
+	}
+	return NewDownloadHealth(infoHash, message, trackers)
+}
+
+// NewDownloadHealth classifies trackers gathered some other way, which need the fields TrackerHealthFields lists, for
+// the download with the given info-hash and d.message.
+func NewDownloadHealth(infoHash, message string, trackers []*Tracker) (*DownloadHealth, error) {
+	dh := &DownloadHealth{InfoHash: infoHash, Message: message, Trackers: make([]TrackerStatus, 0, len(trackers))}
+	for _, t := range trackers {
+		h, err := ClassifyTracker(t, message)
+		if err != nil {
+			return nil, fmt.Errorf("tracker %s: %w", t.TrackerIndex(), err)
+		}
+		dh.Trackers = append(dh.Trackers, TrackerStatus{Tracker: t, Health: h})
+	}
+	return dh, nil
+}
+
+// HealthByHost tallies the health of the trackers of every download in health by the host they announce to, sorted
+// by host. DHT isn't a host, and is left out.
+func HealthByHost(health []*DownloadHealth) []*HostHealth {
+	hosts := make(map[string]*HostHealth)
+	for _, dh := range health {
+		for _, t := range dh.Trackers {
+			if typ, err := t.Tracker.Type(); err == nil && typ == TypeDHT {
+				continue
+			}
+			host := trackerHost(t.Tracker)
+			hh, ok := hosts[host]
+			if !ok {
+				hh = &HostHealth{Host: host, Counts: make(map[TrackerHealth]int)}
+				hosts[host] = hh
+			}
+			hh.Counts[t.Health]++
+		}
+	}
+
+	byHost := make([]*HostHealth, 0, len(hosts))
+	for _, hh := range hosts {
+		byHost = append(byHost, hh)
+	}
+	slices.SortFunc(byHost, func(a, b *HostHealth) int { return strings.Compare(a.Host, b.Host) })
+	return byHost
+}
+
+// NotWorking returns the downloads in health without a working tracker, which includes downloads with no trackers.
+func NotWorking(health []*DownloadHealth) []*DownloadHealth {
+	var broken []*DownloadHealth
+	for _, dh := range health {
+		if !dh.Working() {
+			broken = append(broken, dh)
+		}
+	}
+	return broken
+}
+
+// trackerHost is the host t announces to, or its whole URL if that doesn't parse
+func trackerHost(t *Tracker) string {
+	raw, err := t.URL()
+	if err != nil {
+		return noValueStr
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return raw
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,160 @@
+package rtorrent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const unregisteredMsg = `Tracker: [Failure reason "Unregistered torrent"]`
+
+// healthRow is a tracker as rTorrent reports trackerHealthFields
+func healthRow(u string, typ TrackerType, enabled bool, failed, succeeded int) []any {
+	isEnabled := int64(0)
+	if enabled {
+		isEnabled = 1
+	}
+	return []any{u, int64(typ), isEnabled, int64(failed), int64(succeeded)}
+}
+
+func healthTracker(t *testing.T, row []any) *Tracker {
+	t.Helper()
+
+	data, err := TrackerDataFromSlice(trackerHealthFields, row)
+	require.NoError(t, err)
+	return NewTracker(NewTrackerWithIndex(testInfoHash, 0), data)
+}
+
+func expectHealthTrackers(m *MockClient, hash string, rows ...[]any) {
+	args := []string{hash}
+	for _, f := range trackerHealthFields {
+		args = append(args, f.AsXMLRPCArgument())
+	}
+	m.EXPECT().getSliceSliceByHash(gomock.Any(), trackerListMultiCall, args).Return(rows, nil)
+}
+
+func TestClassifyTracker(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		row      []any
+		message  string
+		expected TrackerHealth
+	}{
+		{"never announced", healthRow("udp://a", TypeUDP, true, 0, 0), "", HealthUnknown},
+		{"working", healthRow("udp://a", TypeUDP, true, 0, 12), "", HealthHealthy},
+		{"just started failing", healthRow("udp://a", TypeUDP, true, 2, 12), "Tracker: [Timeout was reached]", HealthDegraded},
+		{"failing", healthRow("udp://a", TypeUDP, true, 3, 12), "Tracker: [Timeout was reached]", HealthDown},
+		{"never worked", healthRow("udp://a", TypeUDP, true, 1, 0), "", HealthDown},
+		{"unregistered", healthRow("udp://a", TypeUDP, true, 1, 12), unregisteredMsg, HealthUnregistered},
+		{"working despite the message", healthRow("udp://a", TypeUDP, true, 0, 12), unregisteredMsg, HealthHealthy},
+		{"disabled", healthRow("udp://a", TypeUDP, false, 5, 0), unregisteredMsg, HealthDisabled},
+	}
+	for _, tt := range tests {
+		h, err := ClassifyTracker(healthTracker(t, tt.row), tt.message)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, h, tt.name)
+	}
+
+	_, err := ClassifyTracker(NewTracker(NewTrackerNoIndex(testInfoHash), map[TrackerField]any{FieldIsEnabled: 1}), "")
+	require.ErrorIs(t, err, ErrNoField)
+}
+
+func TestTrackerHealth_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Unregistered", HealthUnregistered.String())
+	assert.Equal(t, unknownStr, TrackerHealth(99).String())
+	assert.True(t, HealthDegraded.Working())
+	assert.False(t, HealthUnknown.Working())
+}
+
+func TestIsUnregisteredMessage(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsUnregisteredMessage(unregisteredMsg))
+	assert.True(t, IsUnregisteredMessage(`Tracker: [Failure reason "Torrent not registered with this tracker"]`))
+	assert.False(t, IsUnregisteredMessage("Tracker: [Timeout was reached]"))
+	assert.False(t, IsUnregisteredMessage(""))
+}
+
+func TestTrackerService_Health(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getString(gomock.Any(), "d.message", testInfoHash).Return("", nil)
+	expectHealthTrackers(m, testInfoHash,
+		healthRow("udp://a.example:1337/announce", TypeUDP, true, 4, 1),
+		healthRow("https://b.example/announce", TypeHTTP, true, 0, 7),
+	)
+
+	dh, err := (&TrackerService{C: m}).Health(t.Context(), testInfoHash)
+	require.NoError(t, err)
+	require.Len(t, dh.Trackers, 2)
+	assert.Equal(t, HealthDown, dh.Trackers[0].Health)
+	assert.Equal(t, HealthHealthy, dh.Trackers[1].Health)
+	assert.Equal(t, HealthHealthy, dh.Health(), "a download is as healthy as its best tracker")
+	assert.True(t, dh.Working())
+}
+
+func TestTrackerService_HealthAll(t *testing.T) {
+	t.Parallel()
+
+	var (
+		hashB   = strings.Repeat("B", 40)
+		hashC   = strings.Repeat("C", 40)
+		hashBad = strings.Repeat("D", 40)
+		hashNew = strings.Repeat("E", 40)
+	)
+	nested := []string{trackerListMultiCall + "="}
+	for _, f := range trackerHealthFields {
+		nested = append(nested, f.AsXMLRPCArgument())
+	}
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.message=").Return([][]any{
+		{testInfoHash, ""},
+		{hashB, unregisteredMsg},
+		{hashC, ""},
+		{hashBad, ""},
+		{hashNew, ""},
+	}, nil)
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", strings.Join(nested, ",")).Return([][]any{
+		{testInfoHash, []any{
+			healthRow("udp://a.example:1337/announce", TypeUDP, true, 0, 3),
+			healthRow("dht://", TypeDHT, true, 0, 9),
+		}},
+		{hashB, []any{
+			healthRow("udp://a.example:1337/announce", TypeUDP, true, 1, 3),
+			healthRow("https://b.example/announce", TypeHTTP, false, 0, 0),
+		}},
+		{hashC, []any{}},
+		{hashBad, []any{[]any{"udp://c.example", int64(TypeUDP), "maybe", int64(0), int64(0)}}},
+	}, nil)
+
+	health, err := (&TrackerService{C: m}).HealthAll(t.Context(), "")
+	require.ErrorIs(t, err, ErrBadData)
+	assert.Contains(t, err.Error(), hashBad)
+	require.Len(t, health, 3, "one download's trackers failing doesn't stop the others, and new downloads are left out")
+
+	assert.Equal(t, HealthHealthy, health[0].Health())
+	assert.Equal(t, HealthUnregistered, health[1].Health())
+	assert.Equal(t, HealthUnknown, health[2].Health(), "a download without trackers")
+
+	broken := NotWorking(health)
+	require.Len(t, broken, 2)
+	assert.Equal(t, hashB, broken[0].InfoHash)
+	assert.Equal(t, hashC, broken[1].InfoHash)
+
+	byHost := HealthByHost(health)
+	require.Len(t, byHost, 2, "DHT isn't a host")
+	assert.Equal(t, "a.example", byHost[0].Host)
+	assert.Equal(t, map[TrackerHealth]int{HealthHealthy: 1, HealthUnregistered: 1}, byHost[0].Counts)
+	assert.Equal(t, 2, byHost[0].Total())
+	assert.Equal(t, "b.example", byHost[1].Host)
+	assert.Equal(t, map[TrackerHealth]int{HealthDisabled: 1}, byHost[1].Counts)
+}
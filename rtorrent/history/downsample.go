@@ -0,0 +1,107 @@
+package history
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Tier keeps one sample per Step for samples older than After.
+type Tier struct {
+	After time.Duration
+	Step  time.Duration
+}
+
+// Downsampling thins out older samples, keeping fewer the older they get. Each sample falls in the tier with the
+// greatest After its age has reached, and samples newer than every tier are kept as they are.
+type Downsampling []Tier
+
+// Apply returns samples, which are in time order, thinned out by d as of now. The samples of each step are merged
+// into one at the time of the last of them, with its totals and the mean of their rates, so the totals, which are
+// what Usage counts, lose nothing. Steps are aligned to the zero time, so applying d again later only merges steps
+// that have since moved into a coarser tier. A step rTorrent restarted during, which its totals going down gives away,
+// is split at the restart, or the data moved before it would be lost.
+func (d Downsampling) Apply(samples []Sample, now time.Time) []Sample {
+	tiers := slices.SortedFunc(slices.Values(d), func(a, b Tier) int {
+		return cmp.Compare(a.After, b.After)
+	})
+
+	type stepKey struct {
+		step  time.Duration
+		start time.Time
+	}
+	var (
+		out   []Sample
+		group []Sample
+		key   stepKey
+	)
+	flush := func() {
+		if len(group) > 0 {
+			out = append(out, mergeSamples(group))
+			group = nil
+		}
+	}
+	for _, s := range samples {
+		step := tierStep(tiers, now.Sub(s.Time))
+		if step <= 0 {
+			flush()
+			out = append(out, s)
+			continue
+		}
+		k := stepKey{step: step, start: s.Time.Truncate(step)}
+		if len(group) > 0 && (k != key || restarted(group[len(group)-1], s)) {
+			flush()
+		}
+		key = k
+		group = append(group, s)
+	}
+	flush()
+	return out
+}
+
+// finestStep is the shortest step of any tier, or 0 if there are none
+func (d Downsampling) finestStep() time.Duration {
+	var finest time.Duration
+	for _, t := range d {
+		if t.Step > 0 && (finest == 0 || t.Step < finest) {
+			finest = t.Step
+		}
+	}
+	return finest
+}
+
+// tierStep is the step of the tier a sample of the given age falls in, or 0 if it falls in none
+func tierStep(tiers []Tier, age time.Duration) time.Duration {
+	var step time.Duration
+	for _, t := range tiers {
+		if age < t.After {
+			break
+		}
+		step = t.Step
+	}
+	return step
+}
+
+// restarted reports whether rTorrent restarted between two samples, which resets its totals
+func restarted(a, b Sample) bool {
+	return b.Globals.UploadTotal < a.Globals.UploadTotal || b.Globals.DownloadTotal < a.Globals.DownloadTotal
+}
+
+// mergeSamples merges the samples of a step into one, keeping the totals of any download that went before the last
+func mergeSamples(group []Sample) Sample {
+	last := group[len(group)-1]
+	if len(group) == 1 {
+		return last
+	}
+	merged := Sample{Time: last.Time, Globals: last.Globals, Downloads: make(map[string]Transfer)}
+	var upRate, downRate int
+	for _, s := range group {
+		upRate += s.Globals.UploadRate
+		downRate += s.Globals.DownloadRate
+		maps.Copy(merged.Downloads, s.Downloads)
+	}
+	merged.Globals.UploadRate = upRate / len(group)
+	merged.Globals.DownloadRate = downRate / len(group)
+	return merged
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+)
+
+// sampleAt is a sample taken minutes after start, with the given upload total for rTorrent and for hashA
+func sampleAt(minutes, up, rate int) Sample {
+	return Sample{
+		Time:      start.Add(time.Duration(minutes) * time.Minute),
+		Globals:   rtorrent.Globals{UploadTotal: up, UploadRate: rate},
+		Downloads: map[string]Transfer{hashA: {Up: up}},
+	}
+}
+
+func TestDownsampling_Apply(t *testing.T) {
+	t.Parallel()
+
+	d := Downsampling{
+		{After: 24 * time.Hour, Step: time.Hour},
+		{After: time.Hour, Step: 10 * time.Minute},
+	}
+	now := start.Add(26 * time.Hour)
+
+	gone := sampleAt(1, 100, 10)
+	gone.Downloads[hashB] = Transfer{Up: 5}
+	samples := []Sample{
+		// A day old, so merged by the hour
+		gone,
+		sampleAt(30, 200, 20),
+		// rTorrent restarted in the second hour, which splits it
+		sampleAt(61, 300, 0),
+		sampleAt(70, 50, 0),
+		sampleAt(80, 60, 0),
+		// Over an hour old, so merged by ten minutes
+		sampleAt(22*60+1, 500, 0),
+		sampleAt(22*60+5, 600, 0),
+		sampleAt(22*60+11, 700, 0),
+		// Recent enough to keep
+		sampleAt(25*60+30, 800, 0),
+		sampleAt(25*60+31, 900, 0),
+	}
+
+	merged := sampleAt(30, 200, 15)
+	merged.Downloads[hashB] = Transfer{Up: 5}
+	assert.Equal(t, []Sample{
+		merged,
+		sampleAt(61, 300, 0),
+		sampleAt(80, 60, 0),
+		sampleAt(22*60+5, 600, 0),
+		sampleAt(22*60+11, 700, 0),
+		sampleAt(25*60+30, 800, 0),
+		sampleAt(25*60+31, 900, 0),
+	}, d.Apply(samples, now))
+
+	assert.Equal(t, samples, Downsampling(nil).Apply(samples, now))
+	assert.Equal(t, 10*time.Minute, d.finestStep())
+}
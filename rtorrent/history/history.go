@@ -0,0 +1,155 @@
+// Package history records rTorrent's transfer statistics over time, which rTorrent itself forgets when it restarts.
+//
+// A Recorder samples the global rates and totals, and the byte totals of every download, on a timer and appends them
+// to a Store. MemoryStore keeps a fixed number of samples in a ring buffer, and FileStore keeps them in a file so they
+// outlast the program. Old samples can be thinned out by a Downsampling policy, and Usage answers questions like how
+// much each torrent uploaded in the last 30 days:
+//
+//	store, err := history.OpenFileStore("/var/lib/rtorrent/history.jsonl")
+//	rec := history.NewRecorder(client, store, history.WithDownsampling(history.Downsampling{
+//		{After: 24 * time.Hour, Step: time.Hour},
+//		{After: 30 * 24 * time.Hour, Step: 24 * time.Hour},
+//	}))
+//	go rec.Run(ctx, time.Minute)
+//	...
+//	report, err := history.Usage(store, time.Now().AddDate(0, 0, -30), time.Time{})
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+// sampleFields are the download fields a Recorder takes in each sample
+var sampleFields = []rtorrent.DownloadField{
+	rtorrent.DownloadFieldHash,
+	rtorrent.DownloadFieldUpTotal,
+	rtorrent.DownloadFieldDownTotal,
+}
+
+// Transfer is an amount of data moved, in bytes.
+type Transfer struct {
+	Up   int `json:"up"`
+	Down int `json:"down"`
+}
+
+// Sample is rTorrent's transfer statistics at a moment.
+type Sample struct {
+	Time    time.Time
+	Globals rtorrent.Globals
+	// Downloads are the byte totals of each download, by info-hash, since rTorrent last started
+	Downloads map[string]Transfer
+}
+
+// Store keeps samples in time order. Implementations are safe for concurrent use.
+type Store interface {
+	// Append adds a sample, which must be later than any the store already has
+	Append(s Sample) error
+	// Samples returns the samples taken at or after from and before to, oldest first. A zero from or to leaves that
+	// end of the range open.
+	Samples(from, to time.Time) ([]Sample, error)
+	// Compact thins out the store's samples according to d, taking their ages from now
+	Compact(now time.Time, d Downsampling) error
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithView limits the recorder to the downloads in the given rTorrent view, rather than every download.
+func WithView(view string) Option {
+	return func(r *Recorder) {
+		r.view = view
+	}
+}
+
+// WithDownsampling makes Run compact the store by d as it goes, as often as d's finest step.
+func WithDownsampling(d Downsampling) Option {
+	return func(r *Recorder) {
+		r.downsampling = d
+	}
+}
+
+// WithErrorHandler is given the error from each of Run's samples or compactions that has one. Without it, Run carries
+// on past them silently.
+func WithErrorHandler(fn func(error)) Option {
+	return func(r *Recorder) {
+		r.onError = fn
+	}
+}
+
+// Recorder samples an rTorrent instance's transfer statistics into a Store.
+type Recorder struct {
+	snaps        *rtorrent.SnapshotService
+	store        Store
+	view         string
+	downsampling Downsampling
+	onError      func(error)
+	now          func() time.Time
+}
+
+// NewRecorder returns a Recorder that samples the rTorrent instance behind c into store.
+func NewRecorder(c rtorrent.Client, store Store, opts ...Option) *Recorder {
+	r := &Recorder{
+		snaps: &rtorrent.SnapshotService{C: c},
+		store: store,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Sample takes a single sample and appends it to the store. A download whose totals can't be read is left out of it.
+func (r *Recorder) Sample(ctx context.Context) (Sample, error) {
+	snap, err := r.snaps.Snapshot(ctx, rtorrent.WithSnapshotView(r.view), rtorrent.WithSnapshotFields(sampleFields...),
+		rtorrent.WithSnapshotTrackerFields())
+	if err != nil {
+		return Sample{}, err
+	}
+	s := Sample{Time: snap.Time, Globals: snap.Globals, Downloads: make(map[string]Transfer, len(snap.Downloads))}
+	for hash, d := range snap.Downloads {
+		up, err := d.UpTotal()
+		if err != nil {
+			continue
+		}
+		down, err := d.DownTotal()
+		if err != nil {
+			continue
+		}
+		s.Downloads[hash] = Transfer{Up: up, Down: down}
+	}
+	return s, r.store.Append(s)
+}
+
+// Run samples straight away and then every interval, until ctx is done, and returns ctx's error.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastCompact time.Time
+	for {
+		if _, err := r.Sample(ctx); err != nil && ctx.Err() == nil {
+			r.handle(err)
+		}
+		if step := r.downsampling.finestStep(); step > 0 && r.now().Sub(lastCompact) >= step {
+			lastCompact = r.now()
+			if err := r.store.Compact(lastCompact, r.downsampling); err != nil {
+				r.handle(err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Recorder) handle(err error) {
+	if r.onError != nil {
+		r.onError(err)
+	}
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	hashA = strings.Repeat("A", 40)
+	hashB = strings.Repeat("B", 40)
+	start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// fakeRTorrent answers d.multicall2 with rows of hash, up total and down total, and each global with its value in
+// globals
+type fakeRTorrent struct {
+	mu      sync.Mutex
+	rows    [][]any
+	globals map[string]int
+	fail    error
+}
+
+func (f *fakeRTorrent) client(t *testing.T) rtorrent.Client {
+	t.Helper()
+
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil, rtorrent.WithInterceptors(
+		func(_ context.Context, method string, _ []any, reply any, _ rtorrent.Invoker) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.fail != nil {
+				return f.fail
+			}
+			if method == "d.multicall2" {
+				*reply.(*[][]any) = f.rows
+				return nil
+			}
+			*reply.(*int) = f.globals[method]
+			return nil
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestRecorder_Sample(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{
+		rows: [][]any{
+			{hashA, int64(300), int64(100)},
+			{hashB, "unreadable", int64(0)},
+		},
+		globals: map[string]int{"up.total": 1000, "down.total": 2000, "up.rate": 10, "down.rate": 20},
+	}
+	store := NewMemoryStore(10)
+	rec := NewRecorder(fake.client(t), store)
+
+	s, err := rec.Sample(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, rtorrent.Globals{DownloadTotal: 2000, UploadTotal: 1000, DownloadRate: 20, UploadRate: 10}, s.Globals)
+	assert.Equal(t, map[string]Transfer{hashA: {Up: 300, Down: 100}}, s.Downloads, "unreadable totals are left out")
+
+	stored, err := store.Samples(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{s}, stored)
+}
+
+func TestRecorder_Run(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{fail: errors.New("connection refused")}
+	errs := make(chan error, 1)
+	rec := NewRecorder(fake.client(t), NewMemoryStore(1), WithErrorHandler(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() { done <- rec.Run(ctx, time.Hour) }()
+
+	require.ErrorContains(t, <-errs, "connection refused")
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
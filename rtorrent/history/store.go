@@ -0,0 +1,276 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+// ErrOutOfOrder is returned by Append for a sample no later than the latest the store has.
+var ErrOutOfOrder = errors.New("sample is not later than the store's latest")
+
+// inRange reports whether t is at or after from and before to, either of which may be zero for an open end
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+// MemoryStore is a Store that keeps the latest samples in memory, dropping the oldest once it is full.
+type MemoryStore struct {
+	mu    sync.Mutex
+	buf   []Sample
+	start int
+	n     int
+}
+
+// NewMemoryStore returns a MemoryStore that holds up to capacity samples, or one if capacity is less than that.
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{buf: make([]Sample, max(capacity, 1))}
+}
+
+// Append adds s, dropping the oldest sample if the store is full.
+func (m *MemoryStore) Append(s Sample) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.n > 0 && !s.Time.After(m.at(m.n-1).Time) {
+		return ErrOutOfOrder
+	}
+	if m.n < len(m.buf) {
+		m.buf[(m.start+m.n)%len(m.buf)] = s
+		m.n++
+		return nil
+	}
+	m.buf[m.start] = s
+	m.start = (m.start + 1) % len(m.buf)
+	return nil
+}
+
+// Samples returns the samples taken at or after from and before to, oldest first.
+func (m *MemoryStore) Samples(from, to time.Time) ([]Sample, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []Sample
+	for i := range m.n {
+		if s := m.at(i); inRange(s.Time, from, to) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// Compact thins out the samples according to d, which leaves room for more.
+func (m *MemoryStore) Compact(now time.Time, d Downsampling) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	all := make([]Sample, 0, m.n)
+	for i := range m.n {
+		all = append(all, m.at(i))
+	}
+	kept := d.Apply(all, now)
+	clear(m.buf)
+	m.start, m.n = 0, copy(m.buf, kept)
+	return nil
+}
+
+// at is the i'th oldest sample
+func (m *MemoryStore) at(i int) Sample {
+	return m.buf[(m.start+i)%len(m.buf)]
+}
+
+// fileSample is a sample as FileStore writes it, one per line
+type fileSample struct {
+	Time          time.Time           `json:"time"`
+	DownloadTotal int                 `json:"down_total"`
+	UploadTotal   int                 `json:"up_total"`
+	DownloadRate  int                 `json:"down_rate"`
+	UploadRate    int                 `json:"up_rate"`
+	Downloads     map[string]Transfer `json:"downloads,omitempty"`
+}
+
+func toFileSample(s Sample) fileSample {
+	return fileSample{
+		Time:          s.Time,
+		DownloadTotal: s.Globals.DownloadTotal,
+		UploadTotal:   s.Globals.UploadTotal,
+		DownloadRate:  s.Globals.DownloadRate,
+		UploadRate:    s.Globals.UploadRate,
+		Downloads:     s.Downloads,
+	}
+}
+
+func (f fileSample) sample() Sample {
+	return Sample{
+		Time: f.Time,
+		Globals: rtorrent.Globals{
+			DownloadTotal: f.DownloadTotal,
+			UploadTotal:   f.UploadTotal,
+			DownloadRate:  f.DownloadRate,
+			UploadRate:    f.UploadRate,
+		},
+		Downloads: f.Downloads,
+	}
+}
+
+// FileStore is a Store that keeps samples in a file, a line of JSON for each, so they outlast the program. Appending
+// only ever adds to the end of the file, and a line left half written by a crash is dropped when the file is next
+// opened. Samples and Compact read the whole file, so a long history wants a Downsampling policy to keep it small.
+type FileStore struct {
+	path string
+
+	mu     sync.Mutex
+	f      *os.File
+	latest time.Time
+}
+
+// OpenFileStore opens the FileStore at path, creating it if it doesn't exist.
+func OpenFileStore(path string) (*FileStore, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	samples, whole, err := parseSamples(path, raw)
+	if err != nil {
+		return nil, err
+	}
+	if whole < len(raw) {
+		if err := os.Truncate(path, int64(whole)); err != nil {
+			return nil, fmt.Errorf("dropping the half written sample at the end of %s: %w", path, err)
+		}
+	}
+
+	s := &FileStore{path: path}
+	if len(samples) > 0 {
+		s.latest = samples[len(samples)-1].Time
+	}
+	if s.f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Append writes s to the end of the file.
+func (s *FileStore) Append(sample Sample) error {
+	line, err := json.Marshal(toFileSample(sample))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.latest.IsZero() && !sample.Time.After(s.latest) {
+		return ErrOutOfOrder
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.latest = sample.Time
+	return nil
+}
+
+// Samples reads the samples taken at or after from and before to, oldest first.
+func (s *FileStore) Samples(from, to time.Time) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	var out []Sample
+	for _, sample := range all {
+		if inRange(sample.Time, from, to) {
+			out = append(out, sample)
+		}
+	}
+	return out, nil
+}
+
+// Compact thins out the samples according to d and rewrites the file with those left, replacing it in one go so that
+// a crash part way through leaves the old file as it was.
+func (s *FileStore) Compact(now time.Time, d Downsampling) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, sample := range d.Apply(all, now) {
+		if err := enc.Encode(toFileSample(sample)); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	// The old handle still points at the file we replaced
+	_ = s.f.Close()
+	s.f, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+// Close closes the file.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+func (s *FileStore) readAll() ([]Sample, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	samples, _, err := parseSamples(s.path, raw)
+	return samples, err
+}
+
+// parseSamples parses the lines of a FileStore, along with how many bytes of it are whole lines. A last line without
+// its newline is one a crash cut short, and is left out rather than being an error.
+func parseSamples(path string, raw []byte) ([]Sample, int, error) {
+	whole := bytes.LastIndexByte(raw, '\n') + 1
+	var samples []Sample
+	scanner := bufio.NewScanner(bytes.NewReader(raw[:whole]))
+	scanner.Buffer(nil, len(raw)+1)
+	for line := 1; scanner.Scan(); line++ {
+		var f fileSample
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			return nil, 0, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		samples = append(samples, f.sample())
+	}
+	return samples, whole, scanner.Err()
+}
@@ -0,0 +1,81 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore runs the tests every Store should pass against a new, empty store
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	for i := range 4 {
+		require.NoError(t, store.Append(sampleAt(i*10, i*100, 0)))
+	}
+	require.ErrorIs(t, store.Append(sampleAt(30, 0, 0)), ErrOutOfOrder)
+
+	got, err := store.Samples(start.Add(10*time.Minute), start.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{sampleAt(10, 100, 0), sampleAt(20, 200, 0)}, got)
+
+	require.NoError(t, store.Compact(start.Add(2*time.Hour), Downsampling{{After: time.Hour, Step: time.Hour}}))
+	got, err = store.Samples(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{sampleAt(30, 300, 0)}, got)
+
+	require.NoError(t, store.Append(sampleAt(40, 400, 0)), "appending carries on after compacting")
+	got, err = store.Samples(start.Add(35*time.Minute), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{sampleAt(40, 400, 0)}, got)
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	testStore(t, NewMemoryStore(10))
+
+	// Once full, the oldest samples make way
+	store := NewMemoryStore(2)
+	for i := range 3 {
+		require.NoError(t, store.Append(sampleAt(i, i, 0)))
+	}
+	got, err := store.Samples(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{sampleAt(1, 1, 0), sampleAt(2, 2, 0)}, got)
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+	testStore(t, store)
+	require.NoError(t, store.Close())
+
+	// A crash part way through writing a sample leaves half a line, which is dropped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2026-`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	store, err = OpenFileStore(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	require.ErrorIs(t, store.Append(sampleAt(40, 0, 0)), ErrOutOfOrder, "the latest sample is read back")
+	require.NoError(t, store.Append(sampleAt(50, 500, 0)))
+
+	got, err := store.Samples(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []Sample{sampleAt(30, 300, 0), sampleAt(40, 400, 0), sampleAt(50, 500, 0)}, got)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json\n"), 0o600))
+	_, err = store.Samples(time.Time{}, time.Time{})
+	require.ErrorContains(t, err, "line 1")
+}
@@ -0,0 +1,61 @@
+package history
+
+import "time"
+
+// Report is how much data rTorrent moved over a period, in total and by download.
+type Report struct {
+	// From and To are the times of the first and last samples the report was worked out from
+	From time.Time
+	To   time.Time
+	// Total is what rTorrent moved as a whole, which includes downloads since removed
+	Total Transfer
+	// Downloads are what each download moved, by info-hash, for those in at least two of the samples
+	Downloads map[string]Transfer
+}
+
+// Usage reports how much data rTorrent moved between from and to, out of the samples store has from then. Either may
+// be zero for an open end, so the last 30 days are Usage(store, time.Now().AddDate(0, 0, -30), time.Time{}).
+func Usage(store Store, from, to time.Time) (*Report, error) {
+	samples, err := store.Samples(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return UsageOf(samples), nil
+}
+
+// UsageOf works out a Report from samples, which are in time order. It counts what was moved between each sample and
+// the next, so what was moved before the first sample is left out. rTorrent's totals start again from zero when it
+// restarts, so when a total goes down, what was moved since the restart is counted along with what was counted
+// before it; only data moved between the last sample before a restart and the restart itself is lost.
+func UsageOf(samples []Sample) *Report {
+	r := &Report{Downloads: make(map[string]Transfer)}
+	if len(samples) == 0 {
+		return r
+	}
+	r.From, r.To = samples[0].Time, samples[len(samples)-1].Time
+
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		r.Total.Up += increase(prev.Globals.UploadTotal, cur.Globals.UploadTotal)
+		r.Total.Down += increase(prev.Globals.DownloadTotal, cur.Globals.DownloadTotal)
+		for hash, t := range cur.Downloads {
+			old, ok := prev.Downloads[hash]
+			if !ok {
+				continue
+			}
+			moved := r.Downloads[hash]
+			moved.Up += increase(old.Up, t.Up)
+			moved.Down += increase(old.Down, t.Down)
+			r.Downloads[hash] = moved
+		}
+	}
+	return r
+}
+
+// increase is how much a total grew from old to cur, counting all of cur if it went down as it restarted from zero
+func increase(old, cur int) int {
+	if cur < old {
+		return cur
+	}
+	return cur - old
+}
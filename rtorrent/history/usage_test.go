@@ -0,0 +1,37 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore(10)
+	latest := sampleAt(30, 70, 0)
+	latest.Downloads[hashB] = Transfer{Up: 1, Down: 2}
+	for _, s := range []Sample{
+		sampleAt(0, 100, 0),
+		sampleAt(10, 150, 0),
+		// rTorrent restarted
+		sampleAt(20, 30, 0),
+		latest,
+	} {
+		require.NoError(t, store.Append(s))
+	}
+
+	report, err := Usage(store, start.Add(5*time.Minute), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, &Report{
+		From:      start.Add(10 * time.Minute),
+		To:        start.Add(30 * time.Minute),
+		Total:     Transfer{Up: 70},
+		Downloads: map[string]Transfer{hashA: {Up: 70}},
+	}, report, "hashB is in only one sample")
+
+	assert.Equal(t, &Report{Downloads: map[string]Transfer{}}, UsageOf(nil))
+}
@@ -0,0 +1,270 @@
+package httpapi
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+// defaultDownloadFields are the download fields the API gives when a request doesn't ask for any
+var defaultDownloadFields = []rtorrent.DownloadField{
+	rtorrent.DownloadFieldHash,
+	rtorrent.DownloadFieldName,
+	rtorrent.DownloadFieldState,
+	rtorrent.DownloadFieldComplete,
+	rtorrent.DownloadFieldSizeBytes,
+	rtorrent.DownloadFieldSizeChunks,
+	rtorrent.DownloadFieldCompletedChunks,
+	rtorrent.DownloadFieldUpTotal,
+	rtorrent.DownloadFieldDownTotal,
+	rtorrent.DownloadFieldRatio,
+	rtorrent.DownloadFieldMessage,
+	rtorrent.DownloadFieldCustom1,
+	rtorrent.DownloadFieldDirectory,
+}
+
+// infoHashPattern is a v1 info-hash in hex, which is all rTorrent addresses downloads by
+var infoHashPattern = regexp.MustCompile(`^[0-9A-Fa-f]{40}$`)
+
+// stats is rTorrent's global transfer statistics, as GET /stats gives them
+type stats struct {
+	DownloadTotal int `json:"download_total"`
+	UploadTotal   int `json:"upload_total"`
+	DownloadRate  int `json:"download_rate"`
+	UploadRate    int `json:"upload_rate"`
+}
+
+// downloadList is a page of downloads, as GET /downloads gives it
+type downloadList struct {
+	Downloads []*rtorrent.Download `json:"downloads"`
+	Total     int                  `json:"total"`
+	Next      string               `json:"next,omitempty"`
+}
+
+// labelRequest is the body of PUT /downloads/{hash}/label
+type labelRequest struct {
+	Label string `json:"label"`
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) error {
+	g, err := s.ss.Globals(r.Context())
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, stats{
+		DownloadTotal: g.DownloadTotal, UploadTotal: g.UploadTotal, DownloadRate: g.DownloadRate, UploadRate: g.UploadRate,
+	})
+}
+
+// listDownloads pages through the downloads of a view, or those meeting a query
+func (s *Server) listDownloads(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	fields, err := fieldsParam(q.Get("fields"), rtorrent.DownloadFields(), defaultDownloadFields)
+	if err != nil {
+		return err
+	}
+	offset, err := intParam(q.Get("offset"), "offset")
+	if err != nil {
+		return err
+	}
+	limit, err := intParam(q.Get("limit"), "limit")
+	if err != nil {
+		return err
+	}
+	var descending bool
+	switch order := q.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		descending = true
+	default:
+		return badRequest("order must be asc or desc, not %q", order)
+	}
+	sortBy := rtorrent.DownloadField(q.Get("sort"))
+
+	expr := q.Get("q")
+	if expr == "" {
+		page, err := s.ds.Page(r.Context(), rtorrent.PageRequest{
+			View: q.Get("view"), SortBy: sortBy, Descending: descending, Offset: offset, After: q.Get("after"), Limit: limit,
+		}, fields)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusOK, downloadList{Downloads: page.Downloads, Total: page.Total, Next: page.Next})
+	}
+
+	// Queries are evaluated over every download at once, so their results are paged here, by offset alone
+	if q.Get("after") != "" || q.Get("view") != "" {
+		return badRequest("a query can't be combined with after or view")
+	}
+	if sortBy == "" {
+		sortBy = rtorrent.DownloadFieldHash
+	}
+	downloads, err := s.ds.Query(r.Context(), expr, slices.Concat(fields, []rtorrent.DownloadField{sortBy}))
+	if err != nil {
+		return err
+	}
+	if err := rtorrent.SortDownloads(downloads, sortBy, descending); err != nil {
+		return err
+	}
+	list := downloadList{Total: len(downloads)}
+	// A page of a query holds as many downloads as any other page does, however many met it
+	limit = cmp.Or(limit, rtorrent.DefaultPageLimit)
+	start := min(offset, len(downloads))
+	list.Downloads = downloads[start:min(start+limit, len(downloads))]
+	return writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) getDownload(w http.ResponseWriter, r *http.Request) error {
+	hash, err := hashParam(r)
+	if err != nil {
+		return err
+	}
+	fields, err := fieldsParam(r.URL.Query().Get("fields"), rtorrent.DownloadFields(), defaultDownloadFields)
+	if err != nil {
+		return err
+	}
+	d, err := s.ds.Download(r.Context(), hash, fields)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, d)
+}
+
+func (s *Server) trackers(w http.ResponseWriter, r *http.Request) error {
+	hash, err := s.existingHash(r)
+	if err != nil {
+		return err
+	}
+	fields, err := fieldsParam(r.URL.Query().Get("fields"), rtorrent.TrackerFields(), rtorrent.AllTrackerFields())
+	if err != nil {
+		return err
+	}
+	trackers, err := s.ts.TrackerWithDetails(r.Context(), rtorrent.NewTrackerNoIndex(hash), fields)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, trackers)
+}
+
+func (s *Server) files(w http.ResponseWriter, r *http.Request) error {
+	hash, err := s.existingHash(r)
+	if err != nil {
+		return err
+	}
+	fields, err := fieldsParam(r.URL.Query().Get("fields"), rtorrent.FileFields(), nil)
+	if err != nil {
+		return err
+	}
+	files, err := s.fs.Files(r.Context(), hash, fields)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, files)
+}
+
+func (s *Server) peers(w http.ResponseWriter, r *http.Request) error {
+	hash, err := s.existingHash(r)
+	if err != nil {
+		return err
+	}
+	fields, err := fieldsParam(r.URL.Query().Get("fields"), rtorrent.PeerFields(), nil)
+	if err != nil {
+		return err
+	}
+	peers, err := s.ps.Peers(r.Context(), hash, fields)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, peers)
+}
+
+func (s *Server) start(w http.ResponseWriter, r *http.Request) error {
+	return s.act(w, r, s.ds.Start)
+}
+
+func (s *Server) stop(w http.ResponseWriter, r *http.Request) error {
+	return s.act(w, r, s.ds.Stop)
+}
+
+func (s *Server) deleteDownload(w http.ResponseWriter, r *http.Request) error {
+	return s.act(w, r, s.ds.Erase)
+}
+
+func (s *Server) setLabel(w http.ResponseWriter, r *http.Request) error {
+	var req labelRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	return s.act(w, r, func(ctx context.Context, hash string) error {
+		return s.ds.SetCustom(ctx, hash, 1, req.Label)
+	})
+}
+
+// act takes an action on the download a request names
+func (s *Server) act(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, hash string) error) error {
+	hash, err := s.existingHash(r)
+	if err != nil {
+		return err
+	}
+	if err := action(r.Context(), hash); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// hashParam is the info-hash a request's path names, in the upper case rTorrent uses
+func hashParam(r *http.Request) (string, error) {
+	hash := r.PathValue("hash")
+	if !infoHashPattern.MatchString(hash) {
+		return "", badRequest("%q is not an info-hash", hash)
+	}
+	return strings.ToUpper(hash), nil
+}
+
+// existingHash is the info-hash a request's path names, once we've made sure rTorrent has the download, so that a
+// mistyped hash is a 404 rather than whatever rTorrent says about it
+func (s *Server) existingHash(r *http.Request) (string, error) {
+	hash, err := hashParam(r)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.ds.Download(r.Context(), hash, nil); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// fieldsParam parses a comma separated list of fields of fs, or gives defaults if there isn't one
+func fieldsParam[K ~string](param string, fs *rtorrent.FieldSet[K], defaults []K) ([]K, error) {
+	if param == "" {
+		return defaults, nil
+	}
+	var fields []K
+	for name := range strings.SplitSeq(param, ",") {
+		field := K(strings.TrimSpace(name))
+		if _, ok := fs.Lookup(field); !ok {
+			return nil, badRequest("%q is not a field", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// intParam parses a query parameter that has to be a whole number, or 0 if it isn't given
+func intParam(param, name string) (int, error) {
+	if param == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(param)
+	if err != nil || n < 0 {
+		return 0, badRequest("%s must be a whole number, not %q", name, param)
+	}
+	return n, nil
+}
@@ -0,0 +1,179 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode[T any](t *testing.T, body []byte) T {
+	t.Helper()
+
+	var v T
+	require.NoError(t, json.Unmarshal(body, &v))
+	return v
+}
+
+func TestServer_Stats(t *testing.T) {
+	t.Parallel()
+
+	w := do(t, newTestServer(t, newFakeRTorrent()), http.MethodGet, "/stats", readSecret, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"download_total": 10, "upload_total": 8, "download_rate": 9, "upload_rate": 7}`, w.Body.String())
+
+	// The globals are read with the request's context, so a client that has gone away isn't waited on
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/stats", nil)
+	r.Header.Set("Authorization", "Bearer "+readSecret)
+	w = httptest.NewRecorder()
+	newTestServer(t, newFakeRTorrent()).ServeHTTP(w, r)
+	assert.NotEqual(t, http.StatusOK, w.Code)
+}
+
+func TestServer_ListDownloads(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, newFakeRTorrent())
+
+	type list struct {
+		Downloads []map[string]any `json:"downloads"`
+		Total     int              `json:"total"`
+		Next      string           `json:"next"`
+	}
+	names := func(l list) []any {
+		var names []any
+		for _, d := range l.Downloads {
+			names = append(names, d["name"])
+		}
+		return names
+	}
+
+	w := do(t, srv, http.MethodGet, "/downloads?fields=hash,name&sort=name&limit=1", readSecret, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	page := decode[list](t, w.Body.Bytes())
+	assert.Equal(t, []any{"a"}, names(page))
+	assert.Equal(t, 2, page.Total)
+	require.NotEmpty(t, page.Next)
+
+	w = do(t, srv, http.MethodGet, "/downloads?fields=hash,name&sort=name&limit=1&after="+page.Next, readSecret, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	page = decode[list](t, w.Body.Bytes())
+	assert.Equal(t, []any{"b"}, names(page))
+	assert.Empty(t, page.Next)
+
+	w = do(t, srv, http.MethodGet, "/downloads?fields=name&q=ratio+>+1&order=desc", readSecret, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	page = decode[list](t, w.Body.Bytes())
+	assert.Equal(t, []any{"a"}, names(page))
+	assert.Equal(t, 1, page.Total)
+
+	// A query's results are paged as a view's are, rather than given all at once without a limit
+	many := newFakeRTorrent()
+	for i := range rtorrent.DefaultPageLimit + 1 {
+		hash := fmt.Sprintf("%040X", i)
+		many.downloads[hash] = map[string]any{"d.hash=": hash, "d.name=": hash, "d.complete=": int64(1)}
+	}
+	w = do(t, newTestServer(t, many), http.MethodGet, "/downloads?fields=name&q=complete+%3D+true", readSecret, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	page = decode[list](t, w.Body.Bytes())
+	assert.Len(t, page.Downloads, rtorrent.DefaultPageLimit)
+	assert.Equal(t, rtorrent.DefaultPageLimit+2, page.Total)
+
+	for _, target := range []string{
+		"/downloads?order=sideways",
+		"/downloads?limit=-1",
+		"/downloads?q=ratio+>",
+		"/downloads?q=complete&view=main",
+		"/downloads?after=bogus",
+	} {
+		w = do(t, srv, http.MethodGet, target, readSecret, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestServer_GetDownload(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, newFakeRTorrent())
+
+	w := do(t, srv, http.MethodGet, "/downloads/"+hashA+"?fields=name,complete", readSecret, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	d := decode[map[string]any](t, w.Body.Bytes())
+	assert.Equal(t, "b", d["name"])
+	assert.Equal(t, true, d["complete"])
+
+	// Info-hashes are upper case to rTorrent, but clients may well give them in lower case
+	w = do(t, srv, http.MethodGet, "/downloads/"+"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", readSecret, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServer_Lists(t *testing.T) {
+	t.Parallel()
+
+	f := newFakeRTorrent()
+	f.trackers = [][]any{{"http://tracker.example/announce"}}
+	f.files = [][]any{{"a.iso", int64(1024)}}
+	f.peers = [][]any{{"192.0.2.1", int64(51413)}}
+	srv := newTestServer(t, f)
+
+	// Fields that weren't asked for are written as null, so only the ones that were are checked
+	tests := []struct {
+		target string
+		want   map[string]any
+	}{
+		{"/downloads/" + hashA + "/trackers?fields=url", map[string]any{"info_hash": hashA, "url": "http://tracker.example/announce"}},
+		{"/downloads/" + hashA + "/files?fields=path,size_bytes", map[string]any{"index": 0.0, "path": "a.iso", "size_bytes": 1024.0}},
+		{"/downloads/" + hashA + "/peers?fields=address,port", map[string]any{"info_hash": hashA, "address": "192.0.2.1", "port": 51413.0}},
+	}
+	for _, tt := range tests {
+		w := do(t, srv, http.MethodGet, tt.target, readSecret, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		got := decode[[]map[string]any](t, w.Body.Bytes())
+		require.Len(t, got, 1, tt.target)
+		for k, v := range tt.want {
+			assert.Equal(t, v, got[0][k], "%s: %s", tt.target, k)
+		}
+	}
+
+	w := do(t, srv, http.MethodGet, "/downloads/"+hashA+"/peers?fields=bogus", readSecret, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(t, srv, http.MethodGet, "/downloads/"+"C"+hashA[1:]+"/files", readSecret, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestServer_Actions(t *testing.T) {
+	t.Parallel()
+
+	f := newFakeRTorrent()
+	srv := newTestServer(t, f)
+
+	w := do(t, srv, http.MethodPost, "/downloads/"+hashA+"/start", controlSecret, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(t, srv, http.MethodPost, "/downloads/"+hashB+"/stop", controlSecret, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(t, srv, http.MethodPut, "/downloads/"+hashA+"/label", controlSecret, `{"label": "linux isos"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(t, srv, http.MethodDelete, "/downloads/"+hashB, adminSecret, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.Equal(t, [][]any{
+		{"d.start", hashA},
+		{"d.stop", hashB},
+		{"d.custom1.set", hashA, "linux isos"},
+		{"d.erase", hashB},
+	}, f.executed)
+
+	w = do(t, srv, http.MethodPut, "/downloads/"+hashA+"/label", controlSecret, `{"lable": "typo"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(t, srv, http.MethodPost, "/downloads/"+"C"+hashA[1:]+"/start", controlSecret, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, f.executed, 4, "nothing is done to a download that doesn't exist")
+}
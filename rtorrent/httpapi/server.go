@@ -0,0 +1,302 @@
+// Package httpapi serves a REST/JSON API in front of rTorrent, for clients that would rather not speak XML-RPC.
+//
+// Every route but the OpenAPI spec needs a bearer token, and each token is granted permissions, so a dashboard can be
+// given a token that reads and nothing else:
+//
+//	srv := httpapi.New(client, httpapi.WithTokens(
+//		httpapi.Token{Name: "dashboard", Secret: os.Getenv("DASHBOARD_TOKEN"), Permissions: []httpapi.Permission{httpapi.PermRead}},
+//	))
+//	http.ListenAndServe(":8080", srv)
+//
+// The routes, and the permission each needs, are described by the OpenAPI spec served at /openapi.yaml.
+package httpapi
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed" // for the OpenAPI spec
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+// Permissions a token can be granted
+const (
+	// PermRead allows reading downloads, their trackers, files and peers, and the global stats
+	PermRead Permission = "read"
+	// PermControl allows starting and stopping downloads and setting their labels
+	PermControl Permission = "control"
+	// PermDelete allows removing downloads from rTorrent
+	PermDelete Permission = "delete"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// Permission is something a token is allowed to do.
+type Permission string
+
+// Token is a bearer token a client authenticates with, along with what it is allowed to do.
+type Token struct {
+	// Name identifies the token in logs, as the secret mustn't be logged
+	Name        string       `json:"name"`
+	Secret      string       `json:"secret"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// ReadTokens reads tokens from a JSON file holding an array of them.
+func ReadTokens(path string) ([]Token, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tokens []Token
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("reading tokens from %s: %w", path, err)
+	}
+	for i, t := range tokens {
+		if t.Secret == "" {
+			return nil, fmt.Errorf("token %d (%q) in %s has no secret", i, t.Name, path)
+		}
+	}
+	return tokens, nil
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithTokens sets the tokens the server accepts. A server without any rejects every request but those for the spec.
+func WithTokens(tokens ...Token) Option {
+	return func(s *Server) {
+		s.tokens = make([]storedToken, 0, len(tokens))
+		for _, t := range tokens {
+			s.tokens = append(s.tokens, storedToken{name: t.Name, sum: sha256.Sum256([]byte(t.Secret)), perms: t.Permissions})
+		}
+	}
+}
+
+// WithLogger logs each request the server answers, and the detail of errors from rTorrent, which clients only get a
+// summary of.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// storedToken is a token as the server keeps it, with only a hash of its secret so comparing takes the same time
+// whatever its length
+type storedToken struct {
+	name  string
+	sum   [sha256.Size]byte
+	perms []Permission
+}
+
+// route is an endpoint of the API and the permission it needs, or "" for none
+type route struct {
+	pattern string
+	perm    Permission
+	handle  func(w http.ResponseWriter, r *http.Request) error
+}
+
+// Server is an http.Handler serving the API.
+type Server struct {
+	ds     *rtorrent.DownloadService
+	ts     *rtorrent.TrackerService
+	fs     *rtorrent.FileService
+	ps     *rtorrent.PeerService
+	ss     *rtorrent.SnapshotService
+	tokens []storedToken
+	logger *slog.Logger
+	mux    *http.ServeMux
+}
+
+// New returns a Server for the rTorrent instance behind c.
+func New(c rtorrent.Client, opts ...Option) *Server {
+	s := &Server{
+		ds:  &rtorrent.DownloadService{C: c},
+		ts:  &rtorrent.TrackerService{C: c},
+		fs:  &rtorrent.FileService{C: c},
+		ps:  &rtorrent.PeerService{C: c},
+		ss:  &rtorrent.SnapshotService{C: c},
+		mux: http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, rt := range s.routes() {
+		s.mux.Handle(rt.pattern, s.wrap(rt))
+	}
+	return s
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{"GET /openapi.yaml", "", s.spec},
+		{"GET /stats", PermRead, s.stats},
+		{"GET /downloads", PermRead, s.listDownloads},
+		{"GET /downloads/{hash}", PermRead, s.getDownload},
+		{"DELETE /downloads/{hash}", PermDelete, s.deleteDownload},
+		{"GET /downloads/{hash}/trackers", PermRead, s.trackers},
+		{"GET /downloads/{hash}/files", PermRead, s.files},
+		{"GET /downloads/{hash}/peers", PermRead, s.peers},
+		{"POST /downloads/{hash}/start", PermControl, s.start},
+		{"POST /downloads/{hash}/stop", PermControl, s.stop},
+		{"PUT /downloads/{hash}/label", PermControl, s.setLabel},
+	}
+}
+
+// ServeHTTP serves the API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// wrap checks a request is allowed on rt, and writes any error rt's handler gives back
+func (s *Server) wrap(rt route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if rt.perm != "" {
+			t, ok := s.authenticate(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="rtorrent"`)
+				writeError(w, http.StatusUnauthorized, "missing or unknown bearer token")
+				s.log(r, name, http.StatusUnauthorized, nil)
+				return
+			}
+			name = t.name
+			if !slices.Contains(t.perms, rt.perm) {
+				writeError(w, http.StatusForbidden, fmt.Sprintf("token %q lacks the %s permission", t.name, rt.perm))
+				s.log(r, name, http.StatusForbidden, nil)
+				return
+			}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		if err := rt.handle(rec, r); err != nil {
+			status, msg := errorStatus(err)
+			if !rec.written {
+				writeError(w, status, msg)
+			}
+			s.log(r, name, status, err)
+			return
+		}
+		s.log(r, name, rec.status, nil)
+	})
+}
+
+// statusRecorder notes the status a handler answered with, and whether it has answered at all
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status, sr.written = status, true
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	sr.written = true
+	return sr.ResponseWriter.Write(b)
+}
+
+// authenticate finds the token a request's Authorization header holds
+func (s *Server) authenticate(r *http.Request) (storedToken, bool) {
+	secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || secret == "" {
+		return storedToken{}, false
+	}
+	sum := sha256.Sum256([]byte(secret))
+	var (
+		found storedToken
+		match bool
+	)
+	// Every token is compared, so how long it takes says nothing about which matched
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare(sum[:], t.sum[:]) == 1 {
+			found, match = t, true
+		}
+	}
+	return found, match
+}
+
+func (s *Server) log(r *http.Request, token string, status int, err error) {
+	if s.logger == nil {
+		return
+	}
+	attrs := []any{"method", r.Method, "path", r.URL.Path, "token", token, "status", status}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	s.logger.InfoContext(r.Context(), "request", attrs...)
+}
+
+func (s *Server) spec(w http.ResponseWriter, _ *http.Request) error {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, err := w.Write(openAPISpec)
+	return err
+}
+
+// apiError is an error the client caused, and the status to answer it with
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...any) error {
+	return &apiError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// errorStatus works out the status and message to answer an error with. Errors the library puts down to the request
+// are the client's to fix, and anything else is rTorrent failing, whose detail is left out as it can say more about
+// the server than a client should know.
+func errorStatus(err error) (int, string) {
+	var ae *apiError
+	switch {
+	case errors.As(err, &ae):
+		return ae.status, ae.msg
+	case errors.Is(err, rtorrent.ErrDownloadNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, rtorrent.ErrUnknownField), errors.Is(err, rtorrent.ErrBadQuery), errors.Is(err, rtorrent.ErrBadCursor):
+		return http.StatusBadRequest, err.Error()
+	default:
+		return http.StatusBadGateway, "rTorrent request failed"
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	_ = writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// readJSON decodes a small JSON request body into v
+func readJSON(r *http.Request, v any) error {
+	const maxBody = 1 << 16
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("bad request body: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	readSecret    = "read-secret"
+	controlSecret = "control-secret"
+	adminSecret   = "admin-secret"
+)
+
+var (
+	hashA = strings.Repeat("A", 40)
+	hashB = strings.Repeat("B", 40)
+
+	// filterHashPattern picks the info-hashes out of the filter Page and Download hand d.multicall.filtered
+	filterHashPattern = regexp.MustCompile(`cat=([0-9A-F]{40})`)
+)
+
+// fakeRTorrent answers the download, tracker, file and peer multicalls from downloads, which holds each download's
+// values by the argument that retrieves them, such as d.name=, and notes every command it's asked to execute
+type fakeRTorrent struct {
+	mu        sync.Mutex
+	downloads map[string]map[string]any
+	trackers  [][]any
+	files     [][]any
+	peers     [][]any
+	executed  [][]any
+	fail      bool
+}
+
+func newFakeRTorrent() *fakeRTorrent {
+	return &fakeRTorrent{downloads: map[string]map[string]any{
+		hashA: {"d.hash=": hashA, "d.name=": "b", "d.complete=": int64(1), "d.ratio=": int64(500)},
+		hashB: {"d.hash=": hashB, "d.name=": "a", "d.complete=": int64(0), "d.ratio=": int64(2000)},
+	}}
+}
+
+func (f *fakeRTorrent) client(t *testing.T) rtorrent.Client {
+	t.Helper()
+
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil, rtorrent.WithInterceptors(
+		func(ctx context.Context, method string, args []any, reply any, _ rtorrent.Invoker) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.fail {
+				return io.ErrUnexpectedEOF
+			}
+			// As a real call would, one whose request has gone away fails
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			switch method {
+			case "d.multicall2":
+				*reply.(*[][]any) = f.rows(slices.Sorted(maps.Keys(f.downloads)), args[2:])
+			case "d.multicall.filtered":
+				var hashes []string
+				for _, m := range filterHashPattern.FindAllStringSubmatch(args[2].(string), -1) {
+					if _, ok := f.downloads[m[1]]; ok {
+						hashes = append(hashes, m[1])
+					}
+				}
+				*reply.(*[][]any) = f.rows(hashes, args[3:])
+			case "t.multicall":
+				*reply.(*[][]any) = f.trackers
+			case "f.multicall":
+				*reply.(*[][]any) = f.files
+			case "p.multicall":
+				*reply.(*[][]any) = f.peers
+			case "down.total", "up.total", "down.rate", "up.rate":
+				*reply.(*int) = len(method)
+			default:
+				f.executed = append(f.executed, slices.Concat([]any{method}, args))
+			}
+			return nil
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func (f *fakeRTorrent) rows(hashes []string, args []any) [][]any {
+	rows := make([][]any, 0, len(hashes))
+	for _, hash := range hashes {
+		row := make([]any, 0, len(args))
+		for _, arg := range args {
+			row = append(row, f.downloads[hash][arg.(string)])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func newTestServer(t *testing.T, f *fakeRTorrent) *Server {
+	t.Helper()
+
+	return New(f.client(t), WithTokens(
+		Token{Name: "reader", Secret: readSecret, Permissions: []Permission{PermRead}},
+		Token{Name: "controller", Secret: controlSecret, Permissions: []Permission{PermRead, PermControl}},
+		Token{Name: "admin", Secret: adminSecret, Permissions: []Permission{PermRead, PermControl, PermDelete}},
+	))
+}
+
+// do sends a request to srv with the given token, or none if it's empty
+func do(t *testing.T, srv http.Handler, method, target, secret, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequestWithContext(t.Context(), method, target, strings.NewReader(body))
+	if secret != "" {
+		r.Header.Set("Authorization", "Bearer "+secret)
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	return w
+}
+
+func errorOf(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body["error"]
+}
+
+func TestServer_Auth(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, newFakeRTorrent())
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		secret string
+		want   int
+	}{
+		{"no token", http.MethodGet, "/stats", "", http.StatusUnauthorized},
+		{"unknown token", http.MethodGet, "/stats", "bogus", http.StatusUnauthorized},
+		{"read", http.MethodGet, "/stats", readSecret, http.StatusOK},
+		{"read can't control", http.MethodPost, "/downloads/" + hashA + "/start", readSecret, http.StatusForbidden},
+		{"control", http.MethodPost, "/downloads/" + hashA + "/start", controlSecret, http.StatusNoContent},
+		{"control can't delete", http.MethodDelete, "/downloads/" + hashA, controlSecret, http.StatusForbidden},
+		{"delete", http.MethodDelete, "/downloads/" + hashA, adminSecret, http.StatusNoContent},
+		{"spec needs no token", http.MethodGet, "/openapi.yaml", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := do(t, srv, tt.method, tt.target, tt.secret, "")
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusUnauthorized {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestServer_ErrorStatus(t *testing.T) {
+	t.Parallel()
+
+	f := newFakeRTorrent()
+	srv := newTestServer(t, f)
+
+	w := do(t, srv, http.MethodGet, "/downloads/nothex", readSecret, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(t, srv, http.MethodGet, "/downloads/"+strings.Repeat("C", 40), readSecret, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(t, srv, http.MethodGet, "/downloads?fields=bogus", readSecret, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, errorOf(t, w), "bogus")
+
+	f.mu.Lock()
+	f.fail = true
+	f.mu.Unlock()
+	w = do(t, srv, http.MethodGet, "/stats", readSecret, "")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Equal(t, "rTorrent request failed", errorOf(t, w), "rTorrent's errors are only logged")
+}
+
+func TestReadTokens(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	require.NoError(t, os.WriteFile(good, []byte(`[{"name": "dashboard", "secret": "s3cret", "permissions": ["read"]}]`), 0o600))
+	tokens, err := ReadTokens(good)
+	require.NoError(t, err)
+	assert.Equal(t, []Token{{Name: "dashboard", Secret: "s3cret", Permissions: []Permission{PermRead}}}, tokens)
+
+	noSecret := filepath.Join(dir, "nosecret.json")
+	require.NoError(t, os.WriteFile(noSecret, []byte(`[{"name": "dashboard", "permissions": ["read"]}]`), 0o600))
+	_, err = ReadTokens(noSecret)
+	require.ErrorContains(t, err, "no secret")
+
+	unknown := filepath.Join(dir, "unknown.json")
+	require.NoError(t, os.WriteFile(unknown, []byte(`[{"name": "dashboard", "secret": "s", "perms": ["read"]}]`), 0o600))
+	_, err = ReadTokens(unknown)
+	require.Error(t, err)
+}
+
+// TestOpenAPISpec makes sure the spec describes every route, with the permission the server checks for
+func TestOpenAPISpec(t *testing.T) {
+	t.Parallel()
+
+	var spec struct {
+		Paths map[string]map[string]yaml.Node `yaml:"paths"`
+	}
+	require.NoError(t, yaml.Unmarshal(openAPISpec, &spec))
+
+	srv := New(newFakeRTorrent().client(t))
+	for _, rt := range srv.routes() {
+		method, path, _ := strings.Cut(rt.pattern, " ")
+		op, ok := spec.Paths[path][strings.ToLower(method)]
+		if !assert.True(t, ok, "%s isn't in the spec", rt.pattern) {
+			continue
+		}
+		var details struct {
+			Permission Permission `yaml:"x-permission"`
+		}
+		require.NoError(t, op.Decode(&details))
+		assert.Equal(t, rt.perm, details.Permission, rt.pattern)
+	}
+}
@@ -0,0 +1,56 @@
+package rtorrent
+
+import (
+	"context"
+	"reflect"
+)
+
+// An Invoker performs a single XML-RPC call, decoding the response into reply, which is always a pointer.
+type Invoker func(ctx context.Context, method string, args []any, reply any) error
+
+// An Interceptor wraps every XML-RPC call a XMLRPCClient makes. It may inspect or rewrite the method and arguments,
+// time or log the call, or fill in reply itself and return without calling next at all. Interceptors must be safe for
+// concurrent use, since a Client is shared between goroutines.
+type Interceptor func(ctx context.Context, method string, args []any, reply any, next Invoker) error
+
+// WithInterceptors appends interceptors to the client's chain. The first interceptor given is the outermost, so it
+// sees each call before and each result after all of the others. It may be passed to New more than once.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *XMLRPCClient) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// chainInterceptors folds interceptors around final so that interceptors[0] runs first
+func chainInterceptors(interceptors []Interceptor, final Invoker) Invoker {
+	invoke := final
+	// Wrapping from the inside out is what leaves the first interceptor on the outside
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoke
+		invoke = func(ctx context.Context, method string, args []any, reply any) error {
+			return interceptor(ctx, method, args, reply, next)
+		}
+	}
+	return invoke
+}
+
+// ReplySize reports how many values a decoded reply holds, for interceptors that want to measure responses without
+// knowing their shape. Lists count their elements, so a multicall counts one per row, scalars count as one, and a nil
+// or unfilled reply counts as zero.
+func ReplySize(reply any) int {
+	v := reflect.ValueOf(reply)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0
+		}
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return 0
+	}
+	if k := v.Kind(); k == reflect.Slice || k == reflect.Map || k == reflect.Array {
+		return v.Len()
+	}
+	return 1
+}
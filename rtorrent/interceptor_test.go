@@ -0,0 +1,113 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptorsRunOutermostFirst(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		trace []string
+	)
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, method string, args []any, reply any, next Invoker) error {
+			mu.Lock()
+			trace = append(trace, name+" before "+method)
+			mu.Unlock()
+
+			err := next(ctx, method, args, reply)
+
+			mu.Lock()
+			trace = append(trace, name+" after")
+			mu.Unlock()
+			return err
+		}
+	}
+
+	// Passing WithInterceptors twice appends, so "c" still ends up innermost
+	c := testClient(t, "d.down.rate", []string{testInfoHash}, testBytes,
+		WithInterceptors(record("a"), record("b")), WithInterceptors(record("c")))
+
+	ds := &DownloadService{C: c}
+	got, err := ds.DownloadRate(testInfoHash)
+	require.NoError(t, err)
+	assert.Equal(t, testBytes, got)
+	assert.Equal(t, []string{
+		"a before d.down.rate", "b before d.down.rate", "c before d.down.rate",
+		"c after", "b after", "a after",
+	}, trace)
+}
+
+func TestInterceptorSeesArguments(t *testing.T) {
+	t.Parallel()
+
+	var seen []any
+	c := testClient(t, downloadList, []string{"", "seeding"}, testDownloads,
+		WithInterceptors(func(ctx context.Context, method string, args []any, reply any, next Invoker) error {
+			seen = args
+			return next(ctx, method, args, reply)
+		}))
+
+	_, err := (&DownloadService{C: c}).Seeding()
+	require.NoError(t, err)
+	assert.Equal(t, []any{"", "seeding"}, seen)
+}
+
+func TestInterceptorCanShortCircuit(t *testing.T) {
+	t.Parallel()
+
+	// Nothing listens here, so the call only succeeds if the interceptor really does keep it off the wire
+	c, err := New("http://127.0.0.1:1/RPC2", nil,
+		WithInterceptors(func(_ context.Context, _ string, _ []any, reply any, _ Invoker) error {
+			*reply.(*int) = 42
+			return nil
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	got, err := c.UploadRate()
+	require.NoError(t, err)
+	assert.Equal(t, 42, got)
+}
+
+func TestInterceptorErrorsAreTaggedWithMethod(t *testing.T) {
+	t.Parallel()
+
+	errInjected := errors.New("injected")
+	c, err := New("http://127.0.0.1:1/RPC2", nil,
+		WithInterceptors(func(context.Context, string, []any, any, Invoker) error {
+			return errInjected
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	_, err = c.DownloadTotal()
+	require.ErrorIs(t, err, errInjected)
+	assert.Contains(t, err.Error(), `"down.total"`)
+}
+
+func TestReplySize(t *testing.T) {
+	t.Parallel()
+
+	var (
+		unfilledRows [][]any
+		rows         = [][]any{{"a"}, {"b"}, {"c"}}
+		hashes       = testDownloads
+		rate         = testBytes
+	)
+
+	assert.Equal(t, 0, ReplySize(nil))
+	assert.Equal(t, 0, ReplySize((*int)(nil)))
+	assert.Equal(t, 0, ReplySize(&unfilledRows))
+	assert.Equal(t, 3, ReplySize(&rows))
+	assert.Equal(t, len(testDownloads), ReplySize(&hashes))
+	assert.Equal(t, 1, ReplySize(&rate))
+}
@@ -0,0 +1,96 @@
+package rtorrent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithMaxInFlight caps how many calls the client will have outstanding at once at n, so that a crowd of goroutines
+// sharing one Client queue up on our side instead of stalling rTorrent's single-threaded XML-RPC handler. Queued calls
+// give up when their context is done, so a cap is best paired with the methods that take one: calls made without, such
+// as DownloadService.All, wait as long as it takes. An n of zero or less means no cap.
+func WithMaxInFlight(n int) Option {
+	return func(c *XMLRPCClient) {
+		if n > 0 {
+			c.limiters = append(c.limiters, maxInFlight(n))
+		}
+	}
+}
+
+// WithRateLimit paces the client to perSecond calls on average, allowing bursts of up to burst calls, using a token
+// bucket that starts full. Calls wait for a token until their context is done, or for as long as it takes if made
+// without one, as WithMaxInFlight's are. A perSecond of zero or less means no limit, and a burst below one is treated
+// as one.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *XMLRPCClient) {
+		if perSecond > 0 {
+			c.limiters = append(c.limiters, rateLimit(perSecond, max(burst, 1)))
+		}
+	}
+}
+
+// maxInFlight builds the Interceptor behind WithMaxInFlight out of a counting semaphore
+func maxInFlight(n int) Interceptor {
+	sem := make(chan struct{}, n)
+	return func(ctx context.Context, method string, args []any, reply any, next Invoker) error {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-sem }()
+
+		return next(ctx, method, args, reply)
+	}
+}
+
+// rateLimit builds the Interceptor behind WithRateLimit
+func rateLimit(perSecond float64, burst int) Interceptor {
+	b := &tokenBucket{rate: perSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+	return func(ctx context.Context, method string, args []any, reply any, next Invoker) error {
+		if err := b.wait(ctx); err != nil {
+			return err
+		}
+		return next(ctx, method, args, reply)
+	}
+}
+
+// tokenBucket refills at rate tokens per second up to burst. Waiters reserve a token up front, driving the balance
+// negative, which queues them in arrival order rather than having them race each other for every refill.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait takes a token, sleeping until it has been earned. If ctx is done first the reservation is handed back so it
+// doesn't hold up the callers queued behind it.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
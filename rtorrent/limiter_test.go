@@ -0,0 +1,137 @@
+package rtorrent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	const limit = 2
+	interceptor := maxInFlight(limit)
+
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+	next := func(context.Context, string, []any, any) error {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		inFlight.Add(-1)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			assert.NoError(t, interceptor(t.Context(), "d.name", nil, nil, next))
+		})
+	}
+
+	// Give every goroutine a chance to pile in before letting any of them finish
+	require.Eventually(t, func() bool { return inFlight.Load() == limit }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(limit), peak.Load())
+}
+
+func TestMaxInFlightGivesUpOnContext(t *testing.T) {
+	t.Parallel()
+
+	interceptor := maxInFlight(1)
+
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go func() {
+		_ = interceptor(t.Context(), "d.name", nil, nil, func(context.Context, string, []any, any) error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	err := interceptor(ctx, "d.name", nil, nil, func(context.Context, string, []any, any) error {
+		t.Error("queued call ran while the only slot was held")
+		return nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	const perSecond = 50
+	interceptor := rateLimit(perSecond, 1)
+	noop := func(context.Context, string, []any, any) error { return nil }
+
+	// The bucket starts full, so the first call is free and each one after waits out a refill
+	start := time.Now()
+	for range 4 {
+		require.NoError(t, interceptor(t.Context(), "d.name", nil, nil, noop))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 3*time.Second/perSecond-5*time.Millisecond)
+}
+
+func TestRateLimitGivesUpOnContext(t *testing.T) {
+	t.Parallel()
+
+	interceptor := rateLimit(1, 1)
+	noop := func(context.Context, string, []any, any) error { return nil }
+
+	require.NoError(t, interceptor(t.Context(), "d.name", nil, nil, noop))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, interceptor(ctx, "d.name", nil, nil, noop), context.DeadlineExceeded)
+}
+
+func TestLimitersSitInsideInterceptors(t *testing.T) {
+	t.Parallel()
+
+	// An interceptor that answers calls itself must not be held up by a limiter that would otherwise block for ages
+	c, err := New("http://127.0.0.1:1/RPC2", nil,
+		WithRateLimit(0.001, 1),
+		WithInterceptors(func(_ context.Context, _ string, _ []any, reply any, _ Invoker) error {
+			*reply.(*int) = testBytes
+			return nil
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	for range 3 {
+		got, err := c.DownloadRate()
+		require.NoError(t, err)
+		assert.Equal(t, testBytes, got)
+	}
+}
+
+func TestRateLimitGivesUpThroughPublicAPI(t *testing.T) {
+	t.Parallel()
+
+	// The first call takes the only token, leaving the second waiting on a refill that's far off, until its context ends
+	ds := &DownloadService{C: testClient(t, downloadList, []string{""}, testDownloads, WithRateLimit(0.001, 1))}
+	_, err := ds.List(t.Context(), "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = ds.List(ctx, "")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
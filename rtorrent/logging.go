@@ -0,0 +1,100 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/kolo/xmlrpc"
+)
+
+const (
+	// logMessage is the message every call is logged under, so they can be filtered on it
+	logMessage = "xml-rpc call"
+
+	// redacted replaces anything in the endpoint that might be a credential
+	redacted = "REDACTED"
+)
+
+// LogLevels are the levels a XMLRPCClient logs calls at, split by whether the call succeeded.
+type LogLevels struct {
+	Success slog.Level
+	Failure slog.Level
+}
+
+// DefaultLogLevels keeps successful calls out of the way at debug, while failures are logged as warnings.
+var DefaultLogLevels = LogLevels{Success: slog.LevelDebug, Failure: slog.LevelWarn}
+
+// WithLogger logs every call the client makes to logger: the method, how long it took, how many arguments it was sent,
+// the size of the result as ReplySize counts it, and for failures the error and any rTorrent fault code. Argument
+// values are never logged, and credentials in the endpoint's user info or query string are redacted. Logging happens
+// outside of any interceptors, so durations include the time they take.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *XMLRPCClient) {
+		c.logger = logger
+	}
+}
+
+// WithLogLevels overrides DefaultLogLevels for a client configured WithLogger.
+func WithLogLevels(levels LogLevels) Option {
+	return func(c *XMLRPCClient) {
+		c.logLevels = levels
+	}
+}
+
+// logInterceptor is the Interceptor WithLogger installs
+func (c *XMLRPCClient) logInterceptor(ctx context.Context, method string, args []any, reply any, next Invoker) error {
+	start := time.Now()
+	err := next(ctx, method, args, reply)
+
+	level := c.logLevels.Success
+	if err != nil {
+		level = c.logLevels.Failure
+	}
+	// Checking first spares us building attributes that a quiet handler would only throw away
+	if !c.logger.Enabled(ctx, level) {
+		return err
+	}
+
+	attrs := []slog.Attr{
+		slog.String("endpoint", c.endpoint),
+		slog.String("method", method),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("args", len(args)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		var fault xmlrpc.FaultError
+		if errors.As(err, &fault) {
+			attrs = append(attrs, slog.Int("fault_code", fault.Code))
+		}
+	} else {
+		attrs = append(attrs, slog.Int("result_size", ReplySize(reply)))
+	}
+
+	c.logger.LogAttrs(ctx, level, logMessage, attrs...)
+	return err
+}
+
+// redactEndpoint strips the password and query values from addr so it is safe to log, falling back to a placeholder
+// when it can't be parsed well enough to know which parts are secret
+func redactEndpoint(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return redacted
+	}
+
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q.Set(k, redacted)
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
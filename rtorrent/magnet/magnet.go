@@ -0,0 +1,290 @@
+// Package magnet parses and builds magnet links, and can produce one for a download an rTorrent instance already has.
+//
+// Info-hashes are normalised to upper-case hex, the form rTorrent's download_list reports them in, whichever encoding
+// the link used, so a parsed link can be compared directly against what a running instance has loaded:
+//
+//	link, err := magnet.Parse("magnet:?xt=urn:btih:...")
+//	if err != nil {
+//		return err
+//	}
+//	name, err := ds.Name(ctx, link.InfoHash)
+package magnet
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+const (
+	scheme = "magnet"
+
+	// URN prefixes for the exact topic, BEP 9 for v1 and BEP 52 for v2
+	urnBTIH = "urn:btih:"
+	urnBTMH = "urn:btmh:"
+
+	// multihashSHA256 prefixes a v2 info-hash in a btmh URN: the sha2-256 function code and a 32 byte digest length
+	multihashSHA256 = "1220"
+
+	v1HexLen    = 40
+	v1Base32Len = 32
+	v2HexLen    = 64
+)
+
+// ErrInvalid is returned when a magnet link can't be parsed.
+var ErrInvalid = errors.New("invalid magnet link")
+
+// Link is a parsed magnet link.
+type Link struct {
+	// InfoHash is the v1 info-hash (btih) as upper-case hex
+	InfoHash string
+
+	// InfoHashV2 is the v2 info-hash (btmh) as upper-case hex, without its multihash prefix
+	InfoHashV2 string
+
+	// Name is the display name (dn)
+	Name string
+
+	// Trackers lists the tracker URLs (tr) in the order they appeared, unnumbered ones first and numbered ones by number
+	Trackers []string
+
+	// WebSeeds lists the web seed URLs (ws), ordered as Trackers is
+	WebSeeds []string
+
+	// Length is the exact length of the content in bytes (xl), or 0 if the link didn't say
+	Length int64
+}
+
+// Parse parses a magnet link. It needs at least one BitTorrent exact topic, either btih or btmh, and accepts the
+// numbered form of repeated parameters (xt.1, tr.1 and so on). Parameters it doesn't know are ignored.
+func Parse(uri string) (*Link, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if u.Scheme != scheme {
+		return nil, fmt.Errorf("%w: scheme is %q, not %q", ErrInvalid, u.Scheme, scheme)
+	}
+	params, err := parseParams(u.RawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var l Link
+	for _, p := range params {
+		if err := l.set(p.name, p.value); err != nil {
+			return nil, err
+		}
+	}
+	if l.InfoHash == "" && l.InfoHashV2 == "" {
+		return nil, fmt.Errorf("%w: no btih or btmh exact topic", ErrInvalid)
+	}
+	return &l, nil
+}
+
+// param is a query parameter of a magnet link, with any number it was given split off its name
+type param struct {
+	name  string
+	index int
+	value string
+}
+
+// parseParams splits a magnet link's query into its parameters, in the order they are meant to be read in: unnumbered
+// ones first, as they appeared, then numbered ones by number, so that tr.2 comes before tr.10. url.ParseQuery can't be
+// used, as it forgets the order parameters appeared in.
+func parseParams(rawQuery string) ([]param, error) {
+	var params []param
+	for part := range strings.SplitSeq(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(part, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+		p := param{name: key, value: value}
+		// Repeated parameters may be numbered, which only tells us their order, so xt.1 is just another xt. Others,
+		// such as x.pe, just have a dot in their name.
+		if name, number, ok := strings.Cut(key, "."); ok {
+			if n, err := strconv.Atoi(number); err == nil && n >= 0 {
+				// Numbering can start at 0 or 1, so numbered parameters are kept after unnumbered ones either way
+				p.name, p.index = name, n+1
+			}
+		}
+		params = append(params, p)
+	}
+	slices.SortStableFunc(params, func(a, b param) int { return a.index - b.index })
+	return params, nil
+}
+
+// set applies a single query parameter to the link
+func (l *Link) set(key, value string) error {
+	var err error
+	switch key {
+	case "xt":
+		switch {
+		case strings.HasPrefix(value, urnBTIH):
+			err = setOnce(&l.InfoHash, "btih", strings.TrimPrefix(value, urnBTIH), NormalizeInfoHash)
+		case strings.HasPrefix(value, urnBTMH):
+			err = setOnce(&l.InfoHashV2, "btmh", strings.TrimPrefix(value, urnBTMH), parseMultihash)
+		}
+	case "dn":
+		l.Name = value
+	case "tr":
+		l.Trackers = append(l.Trackers, value)
+	case "ws":
+		l.WebSeeds = append(l.WebSeeds, value)
+	case "xl":
+		if l.Length, err = strconv.ParseInt(value, 10, 64); err == nil && l.Length < 0 {
+			err = errors.New("negative length")
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: xl: %w", ErrInvalid, err)
+		}
+	}
+	return err
+}
+
+// setOnce parses an exact topic into hash, which may already hold one from an earlier xt, in which case they have to
+// agree: a link naming two different torrents is more likely a mistake than anything we should pick a side in
+func setOnce(hash *string, urn, value string, parse func(string) (string, error)) error {
+	parsed, err := parse(value)
+	if err != nil {
+		return err
+	}
+	if *hash != "" && *hash != parsed {
+		return fmt.Errorf("%w: conflicting %s exact topics %s and %s", ErrInvalid, urn, *hash, parsed)
+	}
+	*hash = parsed
+	return nil
+}
+
+// NormalizeInfoHash returns a v1 info-hash given as 40 hex or 32 base32 characters as upper-case hex, the form rTorrent
+// reports it in.
+func NormalizeInfoHash(hash string) (string, error) {
+	var (
+		raw []byte
+		err error
+	)
+	switch len(hash) {
+	case v1HexLen:
+		raw, err = hex.DecodeString(hash)
+	case v1Base32Len:
+		raw, err = base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+	default:
+		return "", fmt.Errorf("%w: info-hash %q is neither hex nor base32", ErrInvalid, hash)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: info-hash %q: %w", ErrInvalid, hash, err)
+	}
+	return strings.ToUpper(hex.EncodeToString(raw)), nil
+}
+
+// parseMultihash pulls the digest out of a btmh multihash, which BEP 52 only defines for sha2-256
+func parseMultihash(mh string) (string, error) {
+	digest, ok := strings.CutPrefix(strings.ToLower(mh), multihashSHA256)
+	if !ok || len(digest) != v2HexLen {
+		return "", fmt.Errorf("%w: btmh %q is not a sha2-256 multihash", ErrInvalid, mh)
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", fmt.Errorf("%w: btmh %q: %w", ErrInvalid, mh, err)
+	}
+	return strings.ToUpper(digest), nil
+}
+
+// String builds the magnet link. Parameters come out in a fixed order, exact topics first, so the same Link always
+// renders the same way. Hashes are written as hex, which every client accepts.
+func (l *Link) String() string {
+	var params []string
+	add := func(key, value string) {
+		params = append(params, key+"="+url.QueryEscape(value))
+	}
+
+	// The URNs' colons are left alone, since every client expects to find them that way
+	if l.InfoHash != "" {
+		params = append(params, "xt="+urnBTIH+l.InfoHash)
+	}
+	if l.InfoHashV2 != "" {
+		params = append(params, "xt="+urnBTMH+multihashSHA256+l.InfoHashV2)
+	}
+	if l.Name != "" {
+		add("dn", l.Name)
+	}
+	if l.Length > 0 {
+		add("xl", strconv.FormatInt(l.Length, 10))
+	}
+	for _, tr := range l.Trackers {
+		add("tr", tr)
+	}
+	for _, ws := range l.WebSeeds {
+		add("ws", ws)
+	}
+	return scheme + ":?" + strings.Join(params, "&")
+}
+
+// FromDownload builds a magnet link for a download loaded in rTorrent, naming it as rTorrent does and listing every
+// enabled tracker it has, DHT aside, since that isn't something another client can announce to.
+func FromDownload(ctx context.Context, c rtorrent.Client, infoHash string) (*Link, error) {
+	hash, err := NormalizeInfoHash(infoHash)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &rtorrent.DownloadService{C: c}
+	name, err := ds.Name(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("getting name of %s: %w", hash, err)
+	}
+	size, err := ds.SizeBytes(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("getting size of %s: %w", hash, err)
+	}
+
+	ts := &rtorrent.TrackerService{C: c}
+	trackers, err := ts.TrackerWithDetails(ctx, rtorrent.NewTrackerNoIndex(hash),
+		[]rtorrent.TrackerField{rtorrent.FieldURL, rtorrent.FieldType, rtorrent.FieldIsEnabled})
+	if err != nil {
+		return nil, fmt.Errorf("getting trackers of %s: %w", hash, err)
+	}
+
+	l := &Link{InfoHash: hash, Name: name, Length: int64(size)}
+	for _, t := range trackers {
+		announce, err := announceURL(t)
+		if err != nil {
+			return nil, fmt.Errorf("reading tracker of %s: %w", hash, err)
+		}
+		if announce != "" {
+			l.Trackers = append(l.Trackers, announce)
+		}
+	}
+	return l, nil
+}
+
+// announceURL returns the URL of a tracker worth putting in a magnet link, or "" for one that isn't
+func announceURL(t *rtorrent.Tracker) (string, error) {
+	typ, err := t.Type()
+	if err != nil {
+		return "", err
+	}
+	enabled, err := t.IsEnabled()
+	if err != nil {
+		return "", err
+	}
+	if typ == rtorrent.TypeDHT || !enabled {
+		return "", nil
+	}
+	return t.URL()
+}
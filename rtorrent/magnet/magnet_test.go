@@ -0,0 +1,212 @@
+package magnet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// testHex and testBase32 are the same 20 bytes, 0x00 through 0x13, in each of the encodings btih allows
+	testHex    = "000102030405060708090A0B0C0D0E0F10111213"
+	testBase32 = "AAAQEAYEAUDAOCAJBIFQYDIOB4IBCEQT"
+
+	testV2 = "0102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F20"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		uri      string
+		expected *Link
+	}{
+		{
+			name:     "hex",
+			uri:      "magnet:?xt=urn:btih:" + strings.ToLower(testHex),
+			expected: &Link{InfoHash: testHex},
+		},
+		{
+			name:     "base32",
+			uri:      "magnet:?xt=urn:btih:" + testBase32,
+			expected: &Link{InfoHash: testHex},
+		},
+		{
+			name:     "lower-case base32",
+			uri:      "magnet:?xt=urn:btih:" + strings.ToLower(testBase32),
+			expected: &Link{InfoHash: testHex},
+		},
+		{
+			name:     "v2 only",
+			uri:      "magnet:?xt=urn:btmh:1220" + strings.ToLower(testV2),
+			expected: &Link{InfoHashV2: testV2},
+		},
+		{
+			name: "everything",
+			uri: "magnet:?xt=urn:btih:" + testHex + "&xt=urn:btmh:1220" + testV2 + "&dn=Some+Name&xl=1234" +
+				"&tr=http%3A%2F%2Ftracker.example%2Fannounce&tr=udp%3A%2F%2Fbackup.example%3A6969&ws=http%3A%2F%2Fseed.example%2F" +
+				"&x.pe=10.0.0.1%3A6881",
+			expected: &Link{
+				InfoHash:   testHex,
+				InfoHashV2: testV2,
+				Name:       "Some Name",
+				Length:     1234,
+				Trackers:   []string{"http://tracker.example/announce", "udp://backup.example:6969"},
+				WebSeeds:   []string{"http://seed.example/"},
+			},
+		},
+		{
+			name:     "numbered parameters",
+			uri:      "magnet:?xt.1=urn:btih:" + testHex + "&tr.2=http%3A%2F%2Fb&tr=http%3A%2F%2Fa&tr.1=http%3A%2F%2Fc",
+			expected: &Link{InfoHash: testHex, Trackers: []string{"http://a", "http://c", "http://b"}},
+		},
+		{
+			name: "numbered past nine",
+			uri: "magnet:?xt=urn:btih:" + testHex + "&tr.10=http%3A%2F%2Fj&tr.2=http%3A%2F%2Fb&tr=http%3A%2F%2Fz" +
+				"&tr=http%3A%2F%2Fy",
+			expected: &Link{InfoHash: testHex, Trackers: []string{"http://z", "http://y", "http://b", "http://j"}},
+		},
+		{
+			name:     "repeated exact topic",
+			uri:      "magnet:?xt=urn:btih:" + testHex + "&xt.1=urn:btih:" + testBase32,
+			expected: &Link{InfoHash: testHex},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(tt.uri)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		uri  string
+	}{
+		{"wrong scheme", "http://example.com/?xt=urn:btih:" + testHex},
+		{"no exact topic", "magnet:?dn=name"},
+		{"foreign exact topic only", "magnet:?xt=urn:sha1:" + testBase32},
+		{"short hash", "magnet:?xt=urn:btih:ABCDEF"},
+		{"bad hex", "magnet:?xt=urn:btih:" + strings.Repeat("Z", v1HexLen)},
+		{"bad base32", "magnet:?xt=urn:btih:" + strings.Repeat("1", v1Base32Len)},
+		{"btmh not sha2-256", "magnet:?xt=urn:btmh:1320" + testV2},
+		{"btmh short digest", "magnet:?xt=urn:btmh:1220ABCD"},
+		{"bad length", "magnet:?xt=urn:btih:" + testHex + "&xl=lots"},
+		{"negative length", "magnet:?xt=urn:btih:" + testHex + "&xl=-1"},
+		{"conflicting btih", "magnet:?xt=urn:btih:" + testHex + "&xt=urn:btih:" + strings.Repeat("0", v1HexLen)},
+		{"bad escape", "magnet:?xt=urn:btih:" + testHex + "&dn=%zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(tt.uri)
+			require.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestStringRoundTrips(t *testing.T) {
+	t.Parallel()
+
+	l := &Link{
+		InfoHash:   testHex,
+		InfoHashV2: testV2,
+		Name:       "a name & more",
+		Length:     42,
+		Trackers:   []string{"http://tracker.example/announce?passkey=x", "udp://backup.example:6969"},
+		WebSeeds:   []string{"http://seed.example/"},
+	}
+
+	s := l.String()
+	assert.True(t, strings.HasPrefix(s, "magnet:?xt=urn:btih:"+testHex+"&xt=urn:btmh:1220"+testV2+"&"), s)
+
+	got, err := Parse(s)
+	require.NoError(t, err)
+	assert.Equal(t, l, got)
+}
+
+func TestNormalizeInfoHash(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{testHex, strings.ToLower(testHex), testBase32} {
+		got, err := NormalizeInfoHash(in)
+		require.NoError(t, err)
+		assert.Equal(t, testHex, got, in)
+	}
+}
+
+// callerKey marks the context a test calls FromDownload with, so fakeRTorrent can check every call was made with it
+type callerKey struct{}
+
+// fakeRTorrent answers the calls FromDownload makes, so they never leave the process
+func fakeRTorrent(t *testing.T, trackers [][]any) rtorrent.Client {
+	t.Helper()
+
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil, rtorrent.WithInterceptors(
+		func(ctx context.Context, method string, args []any, reply any, _ rtorrent.Invoker) error {
+			if ctx.Value(callerKey{}) == nil {
+				return fmt.Errorf("%s wasn't made with the caller's context", method)
+			}
+			if len(args) == 0 || args[0] != testHex {
+				return fmt.Errorf("unexpected args %v", args)
+			}
+			switch method {
+			case "d.name":
+				*reply.(*string) = "ubuntu.iso"
+			case "d.size_bytes":
+				*reply.(*int) = 1024
+			case "t.multicall":
+				*reply.(*[][]any) = trackers
+			default:
+				return fmt.Errorf("unexpected method %s", method)
+			}
+			return nil
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestFromDownload(t *testing.T) {
+	t.Parallel()
+
+	c := fakeRTorrent(t, [][]any{
+		{"http://tracker.example/announce", int64(rtorrent.TypeHTTP), int64(1)},
+		{"dht://", int64(rtorrent.TypeDHT), int64(1)},
+		{"udp://disabled.example:6969", int64(rtorrent.TypeUDP), int64(0)},
+		{"udp://backup.example:6969", int64(rtorrent.TypeUDP), int64(1)},
+	})
+
+	// The hash is asked for in base32 to check it reaches rTorrent in the form rTorrent uses
+	got, err := FromDownload(context.WithValue(t.Context(), callerKey{}, true), c, testBase32)
+	require.NoError(t, err)
+	assert.Equal(t, &Link{
+		InfoHash: testHex,
+		Name:     "ubuntu.iso",
+		Length:   1024,
+		Trackers: []string{"http://tracker.example/announce", "udp://backup.example:6969"},
+	}, got)
+}
+
+func TestFromDownloadRejectsBadHash(t *testing.T) {
+	t.Parallel()
+
+	_, err := FromDownload(context.WithValue(t.Context(), callerKey{}, true), fakeRTorrent(t, nil), "nope")
+	require.ErrorIs(t, err, ErrInvalid)
+}
@@ -0,0 +1,388 @@
+package metainfo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Bencode type markers
+const (
+	markInt   = 'i'
+	markList  = 'l'
+	markDict  = 'd'
+	markEnd   = 'e'
+	markColon = ':'
+)
+
+const (
+	// maxDepth bounds how deeply lists and dicts may nest, so hostile input can't exhaust the stack
+	maxDepth = 64
+
+	// maxStringLen bounds a single byte string. The largest thing in a sane torrent is the piece hashes, which stay
+	// well under this even for very large torrents.
+	maxStringLen = 256 << 20
+)
+
+var (
+	// Custom error definitions
+	ErrSyntax      = errors.New("bencode syntax error")
+	ErrUnsupported = errors.New("bencode unsupported type")
+)
+
+// RawMessage is a complete, encoded bencode value. The Encoder writes it out verbatim, which is how an info dict keeps
+// the exact bytes its hash was computed over.
+type RawMessage []byte
+
+// A Decoder reads bencode values from a stream. Decoded values are int64, string (byte strings may hold arbitrary
+// binary data), []any and map[string]any.
+type Decoder struct {
+	r     *bufio.Reader
+	depth int
+
+	// capture, when set, receives a copy of every byte consumed, which is how DecodeRaw recovers a value's encoding
+	capture *bytes.Buffer
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Unmarshal decodes the single bencode value in data, rejecting anything left over after it.
+func Unmarshal(data []byte) (any, error) {
+	d := NewDecoder(bytes.NewReader(data))
+	v, err := d.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.r.ReadByte(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: trailing data after value", ErrSyntax)
+	}
+	return v, nil
+}
+
+// Decode reads the next value from the stream.
+func (d *Decoder) Decode() (any, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeValue(b)
+}
+
+// DecodeRaw reads the next value from the stream, returning its encoding untouched rather than decoding it.
+func (d *Decoder) DecodeRaw() (RawMessage, error) {
+	outer := d.capture
+	d.capture = new(bytes.Buffer)
+	_, err := d.Decode()
+	raw := d.capture.Bytes()
+	d.capture = outer
+	if outer != nil {
+		outer.Write(raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// DecodeDict streams the next value, which must be a dict, calling fn with each key in turn. fn must consume the key's
+// value from d, whether by Decode, DecodeRaw or DecodeDict, before returning. This lets callers pick apart a large dict,
+// such as a torrent's, without holding a generic copy of all of it.
+func (d *Decoder) DecodeDict(fn func(key string, d *Decoder) error) error {
+	b, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	if b != markDict {
+		return fmt.Errorf("%w: expected dict, found %q", ErrSyntax, b)
+	}
+	return d.decodeDictEntries(func(key string) error { return fn(key, d) })
+}
+
+// decodeValue decodes the value whose first byte, b, has already been read
+func (d *Decoder) decodeValue(b byte) (any, error) {
+	switch {
+	case b == markInt:
+		return d.decodeInt(markEnd)
+	case b == markList:
+		return d.decodeList()
+	case b == markDict:
+		m := make(map[string]any)
+		err := d.decodeDictEntries(func(key string) error {
+			v, err := d.Decode()
+			m[key] = v
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	case b >= '0' && b <= '9':
+		if err := d.r.UnreadByte(); err != nil {
+			return nil, err
+		}
+		d.uncapture()
+		return d.decodeString()
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, b)
+	}
+}
+
+func (d *Decoder) decodeList() ([]any, error) {
+	if err := d.descend(); err != nil {
+		return nil, err
+	}
+	defer d.ascend()
+
+	list := []any{}
+	for {
+		b, err := d.readByte()
+		if err != nil {
+			return nil, d.eof(err)
+		}
+		if b == markEnd {
+			return list, nil
+		}
+		v, err := d.decodeValue(b)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+// decodeDictEntries reads keys up to the dict's end marker, handing each to fn to consume the value
+func (d *Decoder) decodeDictEntries(fn func(key string) error) error {
+	if err := d.descend(); err != nil {
+		return err
+	}
+	defer d.ascend()
+
+	for {
+		b, err := d.readByte()
+		if err != nil {
+			return d.eof(err)
+		}
+		if b == markEnd {
+			return nil
+		}
+		if b < '0' || b > '9' {
+			return fmt.Errorf("%w: dict keys must be strings, found %q", ErrSyntax, b)
+		}
+		if err := d.r.UnreadByte(); err != nil {
+			return err
+		}
+		d.uncapture()
+
+		key, err := d.decodeString()
+		if err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+}
+
+// decodeInt reads an integer terminated by end, rejecting the leading zeros and negative zero bencode forbids
+func (d *Decoder) decodeInt(end byte) (int64, error) {
+	digits, err := d.r.ReadSlice(end)
+	if err != nil {
+		return 0, d.eof(err)
+	}
+	if d.capture != nil {
+		d.capture.Write(digits)
+	}
+	digits = digits[:len(digits)-1]
+
+	s := string(digits)
+	if s == "" || s == "-0" || (len(s) > 1 && s[0] == '0') || (len(s) > 2 && s[:2] == "-0") {
+		return 0, fmt.Errorf("%w: malformed integer %q", ErrSyntax, s)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrSyntax, err)
+	}
+	return i, nil
+}
+
+func (d *Decoder) decodeString() (string, error) {
+	n, err := d.decodeInt(markColon)
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || n > maxStringLen {
+		return "", fmt.Errorf("%w: string length %d out of range", ErrSyntax, n)
+	}
+
+	// Copying rather than allocating n up front means a lying length prefix costs no more than the data that's there
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, n); err != nil {
+		return "", d.eof(err)
+	}
+	if d.capture != nil {
+		d.capture.Write(buf.Bytes())
+	}
+	return buf.String(), nil
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if d.capture != nil {
+		d.capture.WriteByte(b)
+	}
+	return b, nil
+}
+
+// uncapture takes back the last captured byte after it has been unread from the stream
+func (d *Decoder) uncapture() {
+	if d.capture != nil {
+		d.capture.Truncate(d.capture.Len() - 1)
+	}
+}
+
+func (d *Decoder) descend() error {
+	d.depth++
+	if d.depth > maxDepth {
+		return fmt.Errorf("%w: nested deeper than %d", ErrSyntax, maxDepth)
+	}
+	return nil
+}
+
+func (d *Decoder) ascend() {
+	d.depth--
+}
+
+// eof reports running out of input partway through a value as a syntax error, since it's only at a value boundary
+// that EOF means a clean end of stream
+func (d *Decoder) eof(err error) error {
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %w", ErrSyntax, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// An Encoder writes bencode values to a stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns an Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Marshal returns the bencode encoding of v, as Encoder.Encode writes it.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode writes v to the stream. Strings and byte slices become byte strings, every integer kind and bool (as 0 or 1)
+// becomes an integer, slices become lists, and maps with string keys become dicts with their keys sorted, as bencode
+// requires. A RawMessage is written as is. Each value is encoded in full before any of it is written, so a value that
+// can't be encoded leaves the stream untouched.
+func (e *Encoder) Encode(v any) error {
+	b, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("%w: nil", ErrUnsupported)
+	}
+	if raw, ok := v.Interface().(RawMessage); ok {
+		buf.Write(raw)
+		return nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive // kinds with no bencode equivalent share the default
+	case reflect.Interface, reflect.Pointer:
+		return encodeValue(buf, v.Elem())
+	case reflect.String:
+		writeString(buf, v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeInt(buf, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		writeInt(buf, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Bool:
+		writeInt(buf, strconv.Itoa(boolToInt(v.Bool())))
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			writeString(buf, string(byteSlice(v)))
+			return nil
+		}
+		buf.WriteByte(markList)
+		for i := range v.Len() {
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(markEnd)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%w: map keyed by %s", ErrUnsupported, v.Type().Key())
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		buf.WriteByte(markDict)
+		for _, k := range keys {
+			writeString(buf, k.String())
+			if err := encodeValue(buf, v.MapIndex(k)); err != nil {
+				return fmt.Errorf("%s: %w", k.String(), err)
+			}
+		}
+		buf.WriteByte(markEnd)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupported, v.Type())
+	}
+	return nil
+}
+
+// byteSlice gives the bytes of a byte slice or array. Bytes only works on an array that's addressable, which one held in
+// an interface or a map isn't, so arrays are copied out
+func byteSlice(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(strconv.Itoa(len(s)))
+	buf.WriteByte(markColon)
+	buf.WriteString(s)
+}
+
+func writeInt(buf *bytes.Buffer, digits string) {
+	buf.WriteByte(markInt)
+	buf.WriteString(digits)
+	buf.WriteByte(markEnd)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,197 @@
+package metainfo
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected any
+	}{
+		{"int", "i42e", int64(42)},
+		{"negative int", "i-7e", int64(-7)},
+		{"zero", "i0e", int64(0)},
+		{"string", "4:spam", "spam"},
+		{"empty string", "0:", ""},
+		{"binary string", "3:\x00\xff\x01", "\x00\xff\x01"},
+		{"list", "l4:spami42ee", []any{"spam", int64(42)}},
+		{"empty list", "le", []any{}},
+		{"dict", "d3:bar4:spam3:fooi42ee", map[string]any{"bar": "spam", "foo": int64(42)}},
+		{"nested", "d4:listld1:ai1eeee", map[string]any{"list": []any{map[string]any{"a": int64(1)}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Unmarshal([]byte(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestUnmarshalRejectsMalformed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"leading zero", "i03e"},
+		{"negative zero", "i-0e"},
+		{"negative leading zero", "i-03e"},
+		{"empty int", "ie"},
+		{"not a number", "i4xe"},
+		{"truncated string", "10:short"},
+		{"unterminated list", "l4:spam"},
+		{"unterminated dict", "d3:fooi1e"},
+		{"non-string key", "di1ei2ee"},
+		{"unknown marker", "x"},
+		{"trailing data", "i1ei2e"},
+		{"too deep", strings.Repeat("l", maxDepth+1) + strings.Repeat("e", maxDepth+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Unmarshal([]byte(tt.input))
+			require.ErrorIs(t, err, ErrSyntax)
+		})
+	}
+}
+
+func TestDecoderStreamsValues(t *testing.T) {
+	t.Parallel()
+
+	d := NewDecoder(strings.NewReader("i1e4:spamle"))
+	for _, want := range []any{int64(1), "spam", []any{}} {
+		got, err := d.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err := d.Decode()
+	require.ErrorIs(t, err, io.EOF, "a clean end of stream between values is plain EOF")
+}
+
+func TestDecodeDictAndRaw(t *testing.T) {
+	t.Parallel()
+
+	// The keys here are deliberately out of order, which re-encoding would "fix", so only the raw bytes will do
+	const inner = "d1:zi1e1:ai2ee"
+	d := NewDecoder(strings.NewReader("d5:first" + inner + "6:second3:abce"))
+
+	var (
+		raw    RawMessage
+		second any
+	)
+	err := d.DecodeDict(func(key string, d *Decoder) error {
+		var err error
+		switch key {
+		case "first":
+			raw, err = d.DecodeRaw()
+		case "second":
+			second, err = d.Decode()
+		}
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, inner, string(raw))
+	assert.Equal(t, "abc", second)
+}
+
+func TestDecodeDictRequiresDict(t *testing.T) {
+	t.Parallel()
+
+	err := NewDecoder(strings.NewReader("le")).DecodeDict(func(string, *Decoder) error { return nil })
+	require.ErrorIs(t, err, ErrSyntax)
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    any
+		expected string
+	}{
+		{"int", 42, "i42e"},
+		{"uint", uint8(7), "i7e"},
+		{"bool", true, "i1e"},
+		{"string", "spam", "4:spam"},
+		{"bytes", []byte{0, 1}, "2:\x00\x01"},
+		{"list", []any{"a", int64(1)}, "l1:ai1ee"},
+		{"strings", []string{"a", "b"}, "l1:a1:be"},
+		{"dict keys sorted", map[string]any{"z": 1, "a": "x"}, "d1:a1:x1:zi1ee"},
+		{"raw", map[string]any{"info": RawMessage("d1:zi1e1:ai2ee")}, "d4:infod1:zi1e1:ai2eee"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Marshal(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(got))
+		})
+	}
+}
+
+func TestMarshalRejectsUnsupported(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []any{nil, 1.5, map[int]any{1: "a"}, []any{struct{}{}}} {
+		_, err := Marshal(v)
+		require.ErrorIs(t, err, ErrUnsupported, "%#v", v)
+	}
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	v := map[string]any{
+		"announce": "http://tracker/announce",
+		"list":     []any{int64(1), "two", []any{}},
+		"nested":   map[string]any{"k": "\x00binary\xff"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf).Encode(v))
+
+	got, err := Unmarshal(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, v, got)
+}
+
+func TestMarshalByteArrayRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	// An info-hash as a [20]byte, which reflect can't take the bytes of directly when it isn't addressable
+	var hash [20]byte
+	for i := range hash {
+		hash[i] = byte(i * 13)
+	}
+
+	for _, v := range []any{hash, &hash, map[string]any{"info hash": hash}} {
+		b, err := Marshal(v)
+		require.NoError(t, err, "%T", v)
+		got, err := Unmarshal(b)
+		require.NoError(t, err)
+		if m, ok := got.(map[string]any); ok {
+			got = m["info hash"]
+		}
+		assert.Equal(t, string(hash[:]), got, "%T", v)
+	}
+}
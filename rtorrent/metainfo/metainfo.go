@@ -0,0 +1,344 @@
+// Package metainfo reads .torrent files, so a torrent can be inspected before it is handed to rTorrent. It handles v1,
+// v2 and hybrid torrents, though rTorrent will only load those with v1 info, and comes with the bencode codec the
+// format is built on.
+//
+// InfoHash is rendered the way rTorrent's download_list reports hashes, so a torrent can be checked against what a
+// running instance already has loaded:
+//
+//	mi, err := metainfo.LoadFile("ubuntu.torrent")
+//	if err != nil {
+//		return err
+//	}
+//	loaded, err := mi.IsLoaded(ctx, &rtorrent.DownloadService{C: c})
+package metainfo
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // the v1 info-hash is defined as a SHA-1, we aren't relying on it for security
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+const (
+	// metaVersion2 is the meta version BEP 52 gives v2 and hybrid torrents
+	metaVersion2 = 2
+
+	// fileTreeLeaf is the key BEP 52 hangs a file's details off of inside the file tree
+	fileTreeLeaf = ""
+
+	// attrPadding marks a v1 file entry as padding, which hybrid torrents use to align files to pieces
+	attrPadding = "p"
+)
+
+// ErrInvalid is returned when a torrent decodes as bencode but lacks what a torrent needs.
+var ErrInvalid = errors.New("invalid metainfo")
+
+// MetaInfo is the content of a .torrent file.
+type MetaInfo struct {
+	Announce     string
+	AnnounceList [][]string
+	URLList      []string
+	Comment      string
+	CreatedBy    string
+	CreationDate time.Time
+	Info         Info
+
+	// InfoBytes is the info dict exactly as it appeared in the file, which is what the info-hashes are computed over
+	InfoBytes RawMessage
+}
+
+// Info is a torrent's info dict, which describes the content being shared.
+type Info struct {
+	Name        string
+	PieceLength int64
+	Private     bool
+	Source      string
+	MetaVersion int
+
+	// Pieces holds the concatenated SHA-1 hashes of every piece, for torrents with v1 info
+	Pieces string
+
+	// Files lists the torrent's content. A single file torrent has one entry, pathed by the torrent's name. Hybrid
+	// torrents list their v1 files, padding included, with each real file's v2 pieces root filled in.
+	Files []File
+}
+
+// File is a single file within a torrent.
+type File struct {
+	Path    []string
+	Length  int64
+	Padding bool
+
+	// PiecesRoot is the raw root hash of the file's v2 merkle tree, empty for v1 only torrents and empty files
+	PiecesRoot string
+}
+
+// Load reads a torrent from r.
+func Load(r io.Reader) (*MetaInfo, error) {
+	var (
+		m   MetaInfo
+		top = make(map[string]any)
+	)
+
+	err := NewDecoder(r).DecodeDict(func(key string, d *Decoder) error {
+		if key == "info" {
+			raw, err := d.DecodeRaw()
+			m.InfoBytes = raw
+			return err
+		}
+		v, err := d.Decode()
+		top[key] = v
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decoding torrent: %w", err)
+	}
+	if m.InfoBytes == nil {
+		return nil, fmt.Errorf("%w: no info dict", ErrInvalid)
+	}
+
+	m.Announce = stringOf(top["announce"])
+	m.Comment = stringOf(top["comment"])
+	m.CreatedBy = stringOf(top["created by"])
+	if date, ok := top["creation date"].(int64); ok {
+		m.CreationDate = time.Unix(date, 0)
+	}
+	for _, tier := range listOf(top["announce-list"]) {
+		if urls := stringsOf(tier); len(urls) > 0 {
+			m.AnnounceList = append(m.AnnounceList, urls)
+		}
+	}
+	// url-list may be a single web seed rather than a list of them
+	if seed := stringOf(top["url-list"]); seed != "" {
+		m.URLList = []string{seed}
+	} else {
+		m.URLList = stringsOf(top["url-list"])
+	}
+
+	info, err := Unmarshal(m.InfoBytes)
+	if err != nil {
+		return nil, fmt.Errorf("decoding info dict: %w", err)
+	}
+	infoDict, ok := info.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: info is not a dict", ErrInvalid)
+	}
+	if m.Info, err = parseInfo(infoDict); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// LoadFile reads the torrent at path.
+func LoadFile(path string) (*MetaInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// InfoHash returns the torrent's v1 info-hash as upper-case hex, the form rTorrent reports it in. rTorrent identifies
+// hybrid torrents by this hash too.
+func (m *MetaInfo) InfoHash() string {
+	sum := sha1.Sum(m.InfoBytes) //nolint:gosec // see the import
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// InfoHashV2 returns the torrent's full v2 info-hash as upper-case hex, or "" if it has no v2 info.
+func (m *MetaInfo) InfoHashV2() string {
+	if !m.HasV2() {
+		return ""
+	}
+	sum := sha256.Sum256(m.InfoBytes)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// HasV1 reports whether the torrent carries v1 info, which every torrent rTorrent can load does.
+func (m *MetaInfo) HasV1() bool {
+	return m.Info.Pieces != ""
+}
+
+// HasV2 reports whether the torrent carries v2 info.
+func (m *MetaInfo) HasV2() bool {
+	return m.Info.MetaVersion == metaVersion2
+}
+
+// IsHybrid reports whether the torrent carries both v1 and v2 info.
+func (m *MetaInfo) IsHybrid() bool {
+	return m.HasV1() && m.HasV2()
+}
+
+// Trackers returns every announce URL in the torrent once, in tier order, falling back on the lone announce URL for
+// torrents with no announce-list.
+func (m *MetaInfo) Trackers() []string {
+	var urls []string
+	for _, tier := range m.AnnounceList {
+		for _, url := range tier {
+			if !slices.Contains(urls, url) {
+				urls = append(urls, url)
+			}
+		}
+	}
+	if len(urls) == 0 && m.Announce != "" {
+		urls = append(urls, m.Announce)
+	}
+	return urls
+}
+
+// TotalLength returns the size of the torrent's content in bytes, not counting padding.
+func (m *MetaInfo) TotalLength() int64 {
+	var total int64
+	for _, f := range m.Info.Files {
+		if !f.Padding {
+			total += f.Length
+		}
+	}
+	return total
+}
+
+// IsLoaded reports whether the torrent is already loaded in the rTorrent instance behind ds, which is worth checking
+// before calling one of rTorrent's load methods. It looks up just the torrent's own info-hash, with
+// DownloadService.Download, so it needs rTorrent 0.9.7 or later.
+func (m *MetaInfo) IsLoaded(ctx context.Context, ds *rtorrent.DownloadService) (bool, error) {
+	_, err := ds.Download(ctx, m.InfoHash(), nil)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, rtorrent.ErrDownloadNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// parseInfo pulls the fields we know about out of a decoded info dict
+func parseInfo(d map[string]any) (Info, error) {
+	info := Info{
+		Name:        stringOf(d["name"]),
+		PieceLength: intOf(d["piece length"]),
+		Pieces:      stringOf(d["pieces"]),
+		Private:     intOf(d["private"]) == 1,
+		Source:      stringOf(d["source"]),
+		MetaVersion: int(intOf(d["meta version"])),
+	}
+	if info.Name == "" {
+		return Info{}, fmt.Errorf("%w: info has no name", ErrInvalid)
+	}
+	if info.PieceLength <= 0 {
+		return Info{}, fmt.Errorf("%w: info has no piece length", ErrInvalid)
+	}
+
+	var tree []File
+	if info.MetaVersion == metaVersion2 {
+		treeDict, ok := d["file tree"].(map[string]any)
+		if !ok {
+			return Info{}, fmt.Errorf("%w: v2 info has no file tree", ErrInvalid)
+		}
+		tree = flattenFileTree(treeDict, nil)
+		// A single file sits at the root of the tree under the torrent's name, everything else sits beneath a directory
+		// with that name, which we spell out so the paths match the v1 ones
+		if len(tree) != 1 || !slices.Equal(tree[0].Path, []string{info.Name}) {
+			for i := range tree {
+				tree[i].Path = slices.Concat([]string{info.Name}, tree[i].Path)
+			}
+		}
+	}
+
+	switch {
+	case d["files"] != nil:
+		for _, entry := range listOf(d["files"]) {
+			fd, ok := entry.(map[string]any)
+			if !ok {
+				return Info{}, fmt.Errorf("%w: file entry is not a dict", ErrInvalid)
+			}
+			info.Files = append(info.Files, File{
+				Path:    slices.Concat([]string{info.Name}, stringsOf(fd["path"])),
+				Length:  intOf(fd["length"]),
+				Padding: strings.Contains(stringOf(fd["attr"]), attrPadding),
+			})
+		}
+	case d["length"] != nil:
+		info.Files = []File{{Path: []string{info.Name}, Length: intOf(d["length"])}}
+	case tree == nil:
+		return Info{}, fmt.Errorf("%w: info lists no files", ErrInvalid)
+	}
+
+	if info.Files == nil {
+		info.Files = tree
+		return info, nil
+	}
+
+	// A hybrid's v1 file list is the one with the padding in, so we keep it and borrow the roots from the tree
+	roots := make(map[string]string, len(tree))
+	for _, f := range tree {
+		roots[strings.Join(f.Path, "/")] = f.PiecesRoot
+	}
+	for i := range info.Files {
+		info.Files[i].PiecesRoot = roots[strings.Join(info.Files[i].Path, "/")]
+	}
+	return info, nil
+}
+
+// flattenFileTree walks a v2 file tree depth first in key order, which is the order BEP 52 lays the files out in
+func flattenFileTree(tree map[string]any, prefix []string) []File {
+	var files []File
+	for _, name := range slices.Sorted(maps.Keys(tree)) {
+		node, ok := tree[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		path := slices.Concat(prefix, []string{name})
+		if leaf, ok := node[fileTreeLeaf].(map[string]any); ok {
+			files = append(files, File{Path: path, Length: intOf(leaf["length"]), PiecesRoot: stringOf(leaf["pieces root"])})
+			continue
+		}
+		files = append(files, flattenFileTree(node, path)...)
+	}
+	return files
+}
+
+// The helpers below read decoded bencode values leniently, giving back zero values for anything missing or mistyped,
+// since plenty of torrents in the wild carry odd optional fields
+
+func stringOf(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func intOf(v any) int64 {
+	i, _ := v.(int64)
+	return i
+}
+
+func listOf(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+func stringsOf(v any) []string {
+	var out []string
+	for _, e := range listOf(v) {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
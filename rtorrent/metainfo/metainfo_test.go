@@ -0,0 +1,272 @@
+package metainfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec // matching the v1 info-hash definition
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAnnounce = "http://tracker.example/announce"
+
+var testPieces = strings.Repeat("\xaa", 20)
+
+// encodeTorrent bencodes top as a torrent, returning it along with the raw info dict it contains
+func encodeTorrent(t *testing.T, top map[string]any) ([]byte, []byte) {
+	t.Helper()
+
+	info, err := Marshal(top["info"])
+	require.NoError(t, err)
+	top["info"] = RawMessage(info)
+
+	data, err := Marshal(top)
+	require.NoError(t, err)
+	return data, info
+}
+
+func upperHex(b []byte) string {
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+func TestLoadSingleFile(t *testing.T) {
+	t.Parallel()
+
+	data, info := encodeTorrent(t, map[string]any{
+		"announce":      testAnnounce,
+		"announce-list": []any{[]any{testAnnounce, "udp://backup.example:6969"}, []any{testAnnounce}},
+		"comment":       "a comment",
+		"created by":    "mktorrent",
+		"creation date": 1700000000,
+		"url-list":      "http://seed.example/file.iso",
+		"info": map[string]any{
+			"name":         "file.iso",
+			"length":       1234,
+			"piece length": 16384,
+			"pieces":       testPieces,
+			"private":      1,
+			"source":       "SRC",
+		},
+	})
+
+	m, err := Load(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	sum := sha1.Sum(info) //nolint:gosec // see the import
+	assert.Equal(t, upperHex(sum[:]), m.InfoHash())
+	assert.Empty(t, m.InfoHashV2())
+	assert.True(t, m.HasV1())
+	assert.False(t, m.HasV2())
+	assert.False(t, m.IsHybrid())
+
+	assert.Equal(t, "a comment", m.Comment)
+	assert.Equal(t, "mktorrent", m.CreatedBy)
+	assert.Equal(t, time.Unix(1700000000, 0), m.CreationDate)
+	assert.Equal(t, []string{"http://seed.example/file.iso"}, m.URLList)
+	assert.Equal(t, []string{testAnnounce, "udp://backup.example:6969"}, m.Trackers(), "deduplicated across tiers")
+
+	assert.Equal(t, "file.iso", m.Info.Name)
+	assert.True(t, m.Info.Private)
+	assert.Equal(t, "SRC", m.Info.Source)
+	assert.Equal(t, []File{{Path: []string{"file.iso"}, Length: 1234}}, m.Info.Files)
+	assert.Equal(t, int64(1234), m.TotalLength())
+}
+
+func TestLoadKeepsNonCanonicalInfoBytes(t *testing.T) {
+	t.Parallel()
+
+	// Keys out of order, which re-encoding the decoded dict would sort and so change the hash
+	const info = "d6:lengthi10e4:name1:a6:pieces20:" + "\xaa\xaa\xaa\xaa\xaa\xaa\xaa\xaa\xaa\xaa" +
+		"\xaa\xaa\xaa\xaa\xaa\xaa\xaa\xaa\xaa\xaa" + "12:piece lengthi16384ee"
+	data := "d8:announce" + "31:" + testAnnounce + "4:info" + info + "e"
+
+	m, err := Load(strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, info, string(m.InfoBytes))
+
+	sum := sha1.Sum([]byte(info)) //nolint:gosec // see the import
+	assert.Equal(t, upperHex(sum[:]), m.InfoHash())
+	assert.Equal(t, []string{testAnnounce}, m.Trackers(), "falls back to the lone announce URL")
+}
+
+func TestLoadMultiFile(t *testing.T) {
+	t.Parallel()
+
+	data, _ := encodeTorrent(t, map[string]any{
+		"info": map[string]any{
+			"name":         "album",
+			"piece length": 16384,
+			"pieces":       testPieces,
+			"files": []any{
+				map[string]any{"length": 100, "path": []any{"cd1", "01.flac"}},
+				map[string]any{"length": 200, "path": []any{"cover.jpg"}},
+			},
+		},
+	})
+
+	m, err := Load(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, []File{
+		{Path: []string{"album", "cd1", "01.flac"}, Length: 100},
+		{Path: []string{"album", "cover.jpg"}, Length: 200},
+	}, m.Info.Files)
+	assert.Equal(t, int64(300), m.TotalLength())
+	assert.Empty(t, m.Trackers())
+}
+
+func TestLoadHybrid(t *testing.T) {
+	t.Parallel()
+
+	rootA, rootB := strings.Repeat("\x01", 32), strings.Repeat("\x02", 32)
+	data, info := encodeTorrent(t, map[string]any{
+		"announce": testAnnounce,
+		"info": map[string]any{
+			"name":         "dir",
+			"piece length": 16384,
+			"pieces":       testPieces,
+			"meta version": 2,
+			"files": []any{
+				map[string]any{"length": 100, "path": []any{"a.bin"}},
+				map[string]any{"length": 16284, "path": []any{".pad", "16284"}, "attr": "p"},
+				map[string]any{"length": 50, "path": []any{"sub", "b.bin"}},
+			},
+			"file tree": map[string]any{
+				"a.bin": map[string]any{"": map[string]any{"length": 100, "pieces root": rootA}},
+				"sub": map[string]any{
+					"b.bin": map[string]any{"": map[string]any{"length": 50, "pieces root": rootB}},
+				},
+			},
+		},
+	})
+
+	m, err := Load(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.True(t, m.IsHybrid())
+
+	sum := sha256.Sum256(info)
+	assert.Equal(t, upperHex(sum[:]), m.InfoHashV2())
+
+	assert.Equal(t, []File{
+		{Path: []string{"dir", "a.bin"}, Length: 100, PiecesRoot: rootA},
+		{Path: []string{"dir", ".pad", "16284"}, Length: 16284, Padding: true},
+		{Path: []string{"dir", "sub", "b.bin"}, Length: 50, PiecesRoot: rootB},
+	}, m.Info.Files)
+	assert.Equal(t, int64(150), m.TotalLength(), "padding doesn't count")
+}
+
+func TestLoadV2Only(t *testing.T) {
+	t.Parallel()
+
+	root := strings.Repeat("\x03", 32)
+	data, _ := encodeTorrent(t, map[string]any{
+		"info": map[string]any{
+			"name":         "only.bin",
+			"piece length": 16384,
+			"meta version": 2,
+			"file tree": map[string]any{
+				"only.bin": map[string]any{"": map[string]any{"length": 42, "pieces root": root}},
+			},
+		},
+	})
+
+	m, err := Load(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.False(t, m.HasV1())
+	assert.True(t, m.HasV2())
+	assert.Equal(t, []File{{Path: []string{"only.bin"}, Length: 42, PiecesRoot: root}}, m.Info.Files)
+}
+
+func TestLoadRejectsInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		top  map[string]any
+	}{
+		{"no info", map[string]any{"announce": testAnnounce}},
+		{"info not a dict", map[string]any{"info": "nope"}},
+		{"no name", map[string]any{"info": map[string]any{"piece length": 1, "length": 1}}},
+		{"no piece length", map[string]any{"info": map[string]any{"name": "a", "length": 1}}},
+		{"no files", map[string]any{"info": map[string]any{"name": "a", "piece length": 1}}},
+		{"v2 without tree", map[string]any{"info": map[string]any{"name": "a", "piece length": 1, "meta version": 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := Marshal(tt.top)
+			require.NoError(t, err)
+
+			_, err = Load(bytes.NewReader(data))
+			require.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Parallel()
+
+	data, _ := encodeTorrent(t, map[string]any{
+		"info": map[string]any{"name": "a", "piece length": 1, "length": 1, "pieces": testPieces},
+	})
+	path := filepath.Join(t.TempDir(), "a.torrent")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	m, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "a", m.Info.Name)
+
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.torrent"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestIsLoaded(t *testing.T) {
+	t.Parallel()
+
+	data, _ := encodeTorrent(t, map[string]any{
+		"info": map[string]any{"name": "a", "piece length": 1, "length": 1, "pieces": testPieces},
+	})
+	m, err := Load(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// loaded answers the lookup for m's hash as though rTorrent had the downloads with the given hashes loaded,
+	// comparing them exactly as rTorrent's equal does
+	loaded := func(hashes ...string) bool {
+		t.Helper()
+
+		c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil, rtorrent.WithInterceptors(
+			func(_ context.Context, method string, args []any, reply any, _ rtorrent.Invoker) error {
+				assert.Equal(t, "d.multicall.filtered", method)
+				var rows [][]any
+				for _, h := range hashes {
+					if strings.Contains(args[2].(string), "cat="+h+"}") {
+						rows = append(rows, []any{h})
+					}
+				}
+				*reply.(*[][]any) = rows
+				return nil
+			}))
+		require.NoError(t, err)
+		defer c.Close()
+
+		ok, err := m.IsLoaded(t.Context(), &rtorrent.DownloadService{C: c})
+		require.NoError(t, err)
+		return ok
+	}
+
+	assert.True(t, loaded(strings.Repeat("0", 40), m.InfoHash()))
+	assert.False(t, loaded(strings.Repeat("0", 40)))
+
+	// rTorrent only has hashes in upper case, so that is how the lookup has to ask for them
+	assert.True(t, loaded(strings.ToUpper(m.InfoHash())))
+}
@@ -0,0 +1,371 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// copyBufferSize is how much data Move copies between progress reports and context checks
+const copyBufferSize = 1 << 20
+
+var (
+	// ErrMoveTargetExists is returned by Move when the download's data already exists in the new directory, which
+	// Move won't overwrite.
+	ErrMoveTargetExists = errors.New("move target already exists")
+
+	// ErrInsufficientSpace is returned by Move when the new directory's filesystem hasn't room for the download's data.
+	ErrInsufficientSpace = errors.New("insufficient free space")
+
+	// errFreeSpaceUnknown is returned by freeSpace on platforms where we can't ask, which Move takes as a reason to
+	// skip the check rather than to fail
+	errFreeSpaceUnknown = errors.New("free space unknown")
+)
+
+// MoveStage is a step of Move, as reported to a progress callback.
+type MoveStage int
+
+// Move Stages
+const (
+	MoveStopping MoveStage = iota
+	MoveCopying
+	MoveUpdating
+	MoveStarting
+	MoveRollingBack
+	MoveDone
+)
+
+// String returns the string representation of the MoveStage
+func (ms MoveStage) String() string {
+	switch ms {
+	case MoveStopping:
+		return "Stopping"
+	case MoveCopying:
+		return "Copying"
+	case MoveUpdating:
+		return "Updating"
+	case MoveStarting:
+		return "Starting"
+	case MoveRollingBack:
+		return "RollingBack"
+	case MoveDone:
+		return "Done"
+	default:
+		return unknownStr
+	}
+}
+
+// MoveProgress is a report of how far Move has got. Bytes and TotalBytes are only counted while data is copied between
+// filesystems, since a move within one is a single rename.
+type MoveProgress struct {
+	Stage      MoveStage
+	Bytes      int64
+	TotalBytes int64
+}
+
+// A MoveOption configures optional behaviour of Move.
+type MoveOption func(*moveConfig)
+
+// WithMoveProgress has Move call fn as it passes each stage, and periodically while copying. fn is called from the
+// goroutine that called Move.
+func WithMoveProgress(fn func(MoveProgress)) MoveOption {
+	return func(c *moveConfig) {
+		c.progress = fn
+	}
+}
+
+type moveConfig struct {
+	progress func(MoveProgress)
+
+	// rename and freeSpace stand in for the OS, so tests can have a move cross filesystems
+	rename    func(oldPath, newPath string) error
+	freeSpace func(path string) (uint64, error)
+}
+
+func (c *moveConfig) report(p MoveProgress) {
+	if c.progress != nil {
+		c.progress(p)
+	}
+}
+
+// Move relocates a download's data to newDir and points rTorrent at it there. rTorrent has no command for this itself,
+// so Move stops and closes the download, moves its data, changes its directory with d.directory.set (or
+// d.directory_base.set for a multi-file download) and starts it again if it was started. The data is renamed where it
+// can be and otherwise copied, once the new directory's filesystem is found to have room, with the modification times
+// rTorrent checks on resume preserved. If anything fails before the download is pointed at its new directory, the
+// data is put back and the download restarted as it was. Move only makes sense where the caller shares a filesystem
+// with rTorrent, as it handles the data itself.
+//
+// For a single file download newDir is the directory to hold the file, while for a multi-file download it is the
+// directory to hold the download's own directory, as with SetDirectory.
+func (s *DownloadService) Move(ctx context.Context, infoHash, newDir string, opts ...MoveOption) error {
+	c := &moveConfig{rename: os.Rename, freeSpace: freeSpace}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	source, multi, err := s.dataPath(ctx, infoHash)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(newDir, filepath.Base(source))
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%w: %s", ErrMoveTargetExists, target)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(newDir, 0o755); err != nil {
+		return err
+	}
+
+	started, err := s.C.getInt(ctx, "d.state", infoHash)
+	if err != nil {
+		return fmt.Errorf("getting state of %s: %w", infoHash, err)
+	}
+	wasStarted := started == 1
+
+	c.report(MoveProgress{Stage: MoveStopping})
+	if wasStarted {
+		if err := s.Stop(ctx, infoHash); err != nil {
+			return fmt.Errorf("stopping %s: %w", infoHash, err)
+		}
+	}
+	if err := s.Close(ctx, infoHash); err != nil {
+		return s.moveRollback(ctx, c, infoHash, wasStarted, nil, fmt.Errorf("closing %s: %w", infoHash, err))
+	}
+
+	moved, err := c.moveData(ctx, source, target)
+	if err != nil {
+		return s.moveRollback(ctx, c, infoHash, wasStarted, nil, err)
+	}
+
+	// A multi-file download gets its exact new directory, in case it was renamed from the torrent's name
+	c.report(MoveProgress{Stage: MoveUpdating})
+	setDirectory := func() error { return s.SetDirectory(ctx, infoHash, newDir) }
+	if multi {
+		setDirectory = func() error { return s.SetDirectoryBase(ctx, infoHash, target) }
+	}
+	if err := setDirectory(); err != nil {
+		err = fmt.Errorf("setting directory of %s: %w", infoHash, err)
+		return s.moveRollback(ctx, c, infoHash, wasStarted, moved.undo, err)
+	}
+
+	// The data is where rTorrent now expects it, so from here on there is nothing to roll back, only errors to report
+	var errs []error
+	if moved.finish != nil {
+		if err := moved.finish(); err != nil {
+			errs = append(errs, fmt.Errorf("moved %s but removing its old data failed: %w", infoHash, err))
+		}
+	}
+	if wasStarted {
+		c.report(MoveProgress{Stage: MoveStarting})
+		if err := s.Start(ctx, infoHash); err != nil {
+			errs = append(errs, fmt.Errorf("moved %s but restarting it failed: %w", infoHash, err))
+		}
+	}
+	c.report(MoveProgress{Stage: MoveDone})
+	return errors.Join(errs...)
+}
+
+// dataPath works out where a download's data is and whether it is a multi-file download. d.base_path says exactly
+// where, but only while the download is open, so for a closed one we piece it together as rTorrent does.
+func (s *DownloadService) dataPath(ctx context.Context, infoHash string) (string, bool, error) {
+	multi, err := s.C.getInt(ctx, "d.is_multi_file", infoHash)
+	if err != nil {
+		return "", false, fmt.Errorf("getting is_multi_file of %s: %w", infoHash, err)
+	}
+	base, err := s.C.getString(ctx, "d.base_path", infoHash)
+	if err != nil {
+		return "", false, fmt.Errorf("getting base path of %s: %w", infoHash, err)
+	}
+	if base != "" {
+		return base, multi == 1, nil
+	}
+
+	dir, err := s.C.getString(ctx, "d.directory", infoHash)
+	if err != nil {
+		return "", false, fmt.Errorf("getting directory of %s: %w", infoHash, err)
+	}
+	// A multi-file download's directory is its own, while a single file sits in its directory under the torrent's name
+	if multi == 1 {
+		return dir, true, nil
+	}
+	name, err := s.C.getString(ctx, "d.name", infoHash)
+	if err != nil {
+		return "", false, fmt.Errorf("getting name of %s: %w", infoHash, err)
+	}
+	return filepath.Join(dir, name), false, nil
+}
+
+// moveRollback undoes what it can of a failed Move, running undo if there is data to put back, and then restarts
+// the download if it was started. It returns cause along with anything that went wrong on the way. It carries on
+// whether or not ctx is done, since leaving a download stopped is worse than finishing late.
+func (s *DownloadService) moveRollback(
+	ctx context.Context, c *moveConfig, infoHash string, wasStarted bool, undo func() error, cause error,
+) error {
+	c.report(MoveProgress{Stage: MoveRollingBack})
+	ctx = context.WithoutCancel(ctx)
+
+	errs := []error{cause}
+	if undo != nil {
+		if err := undo(); err != nil {
+			errs = append(errs, fmt.Errorf("putting data back: %w", err))
+		}
+	}
+	if wasStarted {
+		if err := s.Start(ctx, infoHash); err != nil {
+			errs = append(errs, fmt.Errorf("restarting: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// relocation is data moveData has moved, along with what it takes to put it back or, once rTorrent has been pointed
+// at its new home, to finish the job. Either may be nil when there is nothing to do.
+type relocation struct {
+	undo   func() error
+	finish func() error
+}
+
+// moveData renames source to target, falling back on a copy when they are on different filesystems. A copy leaves the
+// source in place until the relocation is finished, so that undoing it is only a matter of removing the copy. A
+// download with no data yet has nothing to move.
+func (c *moveConfig) moveData(ctx context.Context, source, target string) (relocation, error) {
+	if _, err := os.Lstat(source); errors.Is(err, fs.ErrNotExist) {
+		return relocation{}, nil
+	}
+
+	err := c.rename(source, target)
+	if err == nil {
+		return relocation{undo: func() error { return c.rename(target, source) }}, nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return relocation{}, err
+	}
+
+	total, err := treeSize(source)
+	if err != nil {
+		return relocation{}, err
+	}
+	free, err := c.freeSpace(filepath.Dir(target))
+	switch {
+	case errors.Is(err, errFreeSpaceUnknown):
+		// We can't check on this platform, so the copy itself will have to find out
+	case err != nil:
+		return relocation{}, fmt.Errorf("checking free space: %w", err)
+	case uint64(total) > free: //nolint:gosec // a tree's size is never negative
+		return relocation{}, fmt.Errorf("%w: %s needs %d bytes, %d are free",
+			ErrInsufficientSpace, filepath.Dir(target), total, free)
+	}
+
+	cp := &treeCopier{ctx: ctx, report: c.report, total: total}
+	if err := cp.copy(source, target); err != nil {
+		// Anything half copied is ours to clean up, the source being untouched
+		return relocation{}, errors.Join(err, os.RemoveAll(target))
+	}
+	return relocation{
+		undo:   func() error { return os.RemoveAll(target) },
+		finish: func() error { return os.RemoveAll(source) },
+	}, nil
+}
+
+// treeSize adds up the size of the regular files at or under path
+func treeSize(path string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// treeCopier copies a file or directory tree, reporting progress as it goes
+type treeCopier struct {
+	ctx    context.Context
+	report func(MoveProgress)
+	total  int64
+	copied int64
+}
+
+func (tc *treeCopier) copy(source, target string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(target, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if err := os.Mkdir(dst, info.Mode().Perm()); err != nil {
+				return err
+			}
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dst)
+		case d.Type().IsRegular():
+			if err := tc.copyFile(path, dst, info.Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: can't move %s", path, d.Type())
+		}
+		// libtorrent compares modification times on resume and rehashes anything that changed
+		return os.Chtimes(dst, info.ModTime(), info.ModTime())
+	})
+}
+
+func (tc *treeCopier) copyFile(source, target string, perm fs.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, copyBufferSize)
+	for {
+		if err := tc.ctx.Err(); err != nil {
+			return errors.Join(err, out.Close())
+		}
+		n, err := io.CopyBuffer(out, io.LimitReader(in, copyBufferSize), buf)
+		tc.copied += n
+		tc.report(MoveProgress{Stage: MoveCopying, Bytes: tc.copied, TotalBytes: tc.total})
+		if err != nil {
+			return errors.Join(err, out.Close())
+		}
+		if n < copyBufferSize {
+			break
+		}
+	}
+	if err := out.Sync(); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	return out.Close()
+}
@@ -0,0 +1,236 @@
+package rtorrent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var errSetDirectory = errors.New("cannot change directory")
+
+// crossDevice makes every rename fail as it would between filesystems, so Move has to copy
+func crossDevice() MoveOption {
+	return func(c *moveConfig) {
+		c.rename = func(oldPath, newPath string) error {
+			return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EXDEV}
+		}
+	}
+}
+
+func withFreeSpace(free uint64) MoveOption {
+	return func(c *moveConfig) {
+		c.freeSpace = func(string) (uint64, error) { return free, nil }
+	}
+}
+
+// recordStages collects the stages Move reports, once each
+func recordStages(stages *[]MoveStage) MoveOption {
+	return WithMoveProgress(func(p MoveProgress) {
+		if n := len(*stages); n == 0 || (*stages)[n-1] != p.Stage {
+			*stages = append(*stages, p.Stage)
+		}
+	})
+}
+
+// expectDataPath sets up the calls Move makes to find a download's data, for a closed download in dir
+func expectDataPath(m *MockClient, dir, name string, multi bool) {
+	isMulti := 0
+	if multi {
+		isMulti = 1
+	}
+	m.EXPECT().getInt(gomock.Any(), "d.is_multi_file", testInfoHash).Return(isMulti, nil)
+	m.EXPECT().getString(gomock.Any(), "d.base_path", testInfoHash).Return("", nil)
+	m.EXPECT().getString(gomock.Any(), "d.directory", testInfoHash).Return(dir, nil)
+	if !multi {
+		m.EXPECT().getString(gomock.Any(), "d.name", testInfoHash).Return(name, nil)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestMoveStage_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Copying", MoveCopying.String())
+	assert.Equal(t, "RollingBack", MoveRollingBack.String())
+	assert.Equal(t, unknownStr, MoveStage(99).String())
+}
+
+func TestMoveRenamesSingleFile(t *testing.T) {
+	t.Parallel()
+
+	oldDir, newDir := t.TempDir(), filepath.Join(t.TempDir(), "new")
+	writeTestFile(t, filepath.Join(oldDir, "file.iso"), "data")
+
+	m := NewMockClient(gomock.NewController(t))
+	expectDataPath(m, oldDir, "file.iso", false)
+	gomock.InOrder(
+		m.EXPECT().getInt(gomock.Any(), "d.state", testInfoHash).Return(1, nil),
+		m.EXPECT().execute(gomock.Any(), "d.stop", testInfoHash).Return(nil),
+		m.EXPECT().execute(gomock.Any(), "d.close", testInfoHash).Return(nil),
+		m.EXPECT().execute(gomock.Any(), "d.directory.set", testInfoHash, newDir).Return(nil),
+		m.EXPECT().execute(gomock.Any(), "d.start", testInfoHash).Return(nil),
+	)
+
+	var stages []MoveStage
+	err := (&DownloadService{C: m}).Move(t.Context(), testInfoHash, newDir, recordStages(&stages))
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(oldDir, "file.iso"))
+	assert.FileExists(t, filepath.Join(newDir, "file.iso"))
+	assert.Equal(t, []MoveStage{MoveStopping, MoveUpdating, MoveStarting, MoveDone}, stages)
+}
+
+func TestMoveCopiesAcrossFilesystems(t *testing.T) {
+	t.Parallel()
+
+	source := filepath.Join(t.TempDir(), "album")
+	newDir := t.TempDir()
+	writeTestFile(t, filepath.Join(source, "cd1", "01.flac"), "track one")
+	writeTestFile(t, filepath.Join(source, "cover.jpg"), "cover")
+	mtime := time.Unix(1700000000, 0)
+	require.NoError(t, os.Chtimes(filepath.Join(source, "cover.jpg"), mtime, mtime))
+
+	m := NewMockClient(gomock.NewController(t))
+	expectDataPath(m, source, "", true)
+	gomock.InOrder(
+		m.EXPECT().getInt(gomock.Any(), "d.state", testInfoHash).Return(0, nil),
+		m.EXPECT().execute(gomock.Any(), "d.close", testInfoHash).Return(nil),
+		// A multi-file download is given its exact new directory
+		m.EXPECT().execute(gomock.Any(), "d.directory_base.set", testInfoHash, filepath.Join(newDir, "album")).Return(nil),
+	)
+
+	var last MoveProgress
+	err := (&DownloadService{C: m}).Move(t.Context(), testInfoHash, newDir, crossDevice(), withFreeSpace(1<<30),
+		WithMoveProgress(func(p MoveProgress) {
+			if p.Stage == MoveCopying {
+				last = p
+			}
+		}))
+	require.NoError(t, err)
+
+	assert.NoDirExists(t, source, "the source is removed once the copy is in use")
+	got, err := os.ReadFile(filepath.Join(newDir, "album", "cd1", "01.flac"))
+	require.NoError(t, err)
+	assert.Equal(t, "track one", string(got))
+
+	info, err := os.Stat(filepath.Join(newDir, "album", "cover.jpg"))
+	require.NoError(t, err)
+	assert.True(t, mtime.Equal(info.ModTime()), "libtorrent rehashes files whose modification time changed")
+
+	assert.Equal(t, MoveProgress{Stage: MoveCopying, Bytes: 14, TotalBytes: 14}, last)
+}
+
+func TestMoveRollsBack(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not enough space", func(t *testing.T) {
+		t.Parallel()
+
+		oldDir, newDir := t.TempDir(), t.TempDir()
+		writeTestFile(t, filepath.Join(oldDir, "file.iso"), "data")
+
+		m := NewMockClient(gomock.NewController(t))
+		expectDataPath(m, oldDir, "file.iso", false)
+		gomock.InOrder(
+			m.EXPECT().getInt(gomock.Any(), "d.state", testInfoHash).Return(1, nil),
+			m.EXPECT().execute(gomock.Any(), "d.stop", testInfoHash).Return(nil),
+			m.EXPECT().execute(gomock.Any(), "d.close", testInfoHash).Return(nil),
+			m.EXPECT().execute(gomock.Any(), "d.start", testInfoHash).Return(nil),
+		)
+
+		err := (&DownloadService{C: m}).Move(t.Context(), testInfoHash, newDir, crossDevice(), withFreeSpace(1))
+		require.ErrorIs(t, err, ErrInsufficientSpace)
+		assert.FileExists(t, filepath.Join(oldDir, "file.iso"))
+		assert.NoFileExists(t, filepath.Join(newDir, "file.iso"))
+	})
+
+	t.Run("directory refused", func(t *testing.T) {
+		t.Parallel()
+
+		oldDir, newDir := t.TempDir(), t.TempDir()
+		writeTestFile(t, filepath.Join(oldDir, "file.iso"), "data")
+
+		m := NewMockClient(gomock.NewController(t))
+		expectDataPath(m, oldDir, "file.iso", false)
+		gomock.InOrder(
+			m.EXPECT().getInt(gomock.Any(), "d.state", testInfoHash).Return(1, nil),
+			m.EXPECT().execute(gomock.Any(), "d.stop", testInfoHash).Return(nil),
+			m.EXPECT().execute(gomock.Any(), "d.close", testInfoHash).Return(nil),
+			m.EXPECT().execute(gomock.Any(), "d.directory.set", testInfoHash, newDir).Return(errSetDirectory),
+			m.EXPECT().execute(gomock.Any(), "d.start", testInfoHash).Return(nil),
+		)
+
+		var stages []MoveStage
+		err := (&DownloadService{C: m}).Move(t.Context(), testInfoHash, newDir, recordStages(&stages))
+		require.ErrorIs(t, err, errSetDirectory)
+		assert.FileExists(t, filepath.Join(oldDir, "file.iso"), "the data is put back")
+		assert.NoFileExists(t, filepath.Join(newDir, "file.iso"))
+		assert.Equal(t, []MoveStage{MoveStopping, MoveUpdating, MoveRollingBack}, stages)
+	})
+
+	t.Run("directory refused after a copy", func(t *testing.T) {
+		t.Parallel()
+
+		oldDir, newDir := t.TempDir(), t.TempDir()
+		writeTestFile(t, filepath.Join(oldDir, "file.iso"), "data")
+
+		m := NewMockClient(gomock.NewController(t))
+		expectDataPath(m, oldDir, "file.iso", false)
+		gomock.InOrder(
+			m.EXPECT().getInt(gomock.Any(), "d.state", testInfoHash).Return(0, nil),
+			m.EXPECT().execute(gomock.Any(), "d.close", testInfoHash).Return(nil),
+			m.EXPECT().execute(gomock.Any(), "d.directory.set", testInfoHash, newDir).Return(errSetDirectory),
+		)
+
+		err := (&DownloadService{C: m}).Move(t.Context(), testInfoHash, newDir, crossDevice(), withFreeSpace(1<<30))
+		require.ErrorIs(t, err, errSetDirectory)
+		assert.FileExists(t, filepath.Join(oldDir, "file.iso"), "the source is only removed once the copy is in use")
+		assert.NoFileExists(t, filepath.Join(newDir, "file.iso"))
+	})
+}
+
+func TestMoveRefusesExistingTarget(t *testing.T) {
+	t.Parallel()
+
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(oldDir, "file.iso"), "old")
+	writeTestFile(t, filepath.Join(newDir, "file.iso"), "new")
+
+	// Nothing is stopped, the target is checked before anything is touched
+	m := NewMockClient(gomock.NewController(t))
+	expectDataPath(m, oldDir, "file.iso", false)
+
+	err := (&DownloadService{C: m}).Move(t.Context(), testInfoHash, newDir)
+	require.ErrorIs(t, err, ErrMoveTargetExists)
+}
+
+func TestMoveWithoutData(t *testing.T) {
+	t.Parallel()
+
+	oldDir, newDir := t.TempDir(), t.TempDir()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getInt(gomock.Any(), "d.is_multi_file", testInfoHash).Return(0, nil)
+	m.EXPECT().getString(gomock.Any(), "d.base_path", testInfoHash).Return(filepath.Join(oldDir, "file.iso"), nil)
+	gomock.InOrder(
+		m.EXPECT().getInt(gomock.Any(), "d.state", testInfoHash).Return(0, nil),
+		m.EXPECT().execute(gomock.Any(), "d.close", testInfoHash).Return(nil),
+		m.EXPECT().execute(gomock.Any(), "d.directory.set", testInfoHash, newDir).Return(nil),
+	)
+
+	// A download that hasn't written anything yet only needs pointing at its new directory
+	require.NoError(t, (&DownloadService{C: m}).Move(t.Context(), testInfoHash, newDir))
+}
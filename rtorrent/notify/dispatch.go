@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultDedupeWindow is how long a Dispatcher drops repeats of an event for unless told otherwise
+const defaultDedupeWindow = time.Hour
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithRetries makes the dispatcher try a notifier up to retries more times after it fails, waiting backoff before the
+// first retry and twice as long before each one after. Errors wrapping ErrPermanent aren't retried.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.retries = retries
+		d.backoff = backoff
+	}
+}
+
+// WithDedupeWindow sets how long after delivering an event the dispatcher drops others with the same key. A window of
+// 0 turns deduplication off.
+func WithDedupeWindow(window time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.window = window
+	}
+}
+
+// Dispatcher delivers events to notifiers. It is safe for concurrent use.
+type Dispatcher struct {
+	notifiers []Notifier
+	retries   int
+	backoff   time.Duration
+	window    time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// NewDispatcher returns a Dispatcher that delivers each event to every one of notifiers. By default it doesn't retry,
+// and drops repeats of an event for an hour.
+func NewDispatcher(notifiers []Notifier, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		notifiers: notifiers,
+		window:    defaultDedupeWindow,
+		now:       time.Now,
+		sent:      make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Dispatch delivers e to every notifier, retrying each as configured, and returns their errors joined. It does nothing
+// for an event with the same key as one delivered within the dedupe window. An event that no notifier delivered isn't
+// remembered, so it can be dispatched again.
+func (d *Dispatcher) Dispatch(ctx context.Context, e Event) error {
+	if d.seen(e) {
+		return nil
+	}
+
+	var (
+		errs      []error
+		delivered bool
+	)
+	for _, n := range d.notifiers {
+		if err := d.deliver(ctx, n, e); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivered = true
+	}
+	if delivered {
+		d.remember(e)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver tries n until it works, its retries run out, or it fails in a way retrying won't fix
+func (d *Dispatcher) deliver(ctx context.Context, n Notifier, e Event) error {
+	wait := d.backoff
+	for attempt := 0; ; attempt++ {
+		err := n.Notify(ctx, e)
+		if err == nil || attempt >= d.retries || errors.Is(err, ErrPermanent) {
+			if err != nil {
+				return fmt.Errorf("%s for %s: %w", e.Type, e.InfoHash, err)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (d *Dispatcher) seen(e Event) bool {
+	if d.window <= 0 {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	at, ok := d.sent[e.Key()]
+	return ok && d.now().Sub(at) < d.window
+}
+
+func (d *Dispatcher) remember(e Event) {
+	if d.window <= 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	// Forget the events whose windows have passed, so the map doesn't grow forever
+	for key, at := range d.sent {
+		if now.Sub(at) >= d.window {
+			delete(d.sent, key)
+		}
+	}
+	d.sent[e.Key()] = now
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyNotifier fails with each of errs in turn, and then works, counting its calls
+type flakyNotifier struct {
+	errs  []error
+	calls int
+}
+
+func (n *flakyNotifier) Notify(context.Context, Event) error {
+	n.calls++
+	if n.calls <= len(n.errs) {
+		return n.errs[n.calls-1]
+	}
+	return nil
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	t.Parallel()
+
+	e := Event{Type: EventDownloadCompleted, InfoHash: hashA}
+	flaky := &flakyNotifier{errs: []error{errors.New("timeout"), errors.New("timeout")}}
+	rejecting := &flakyNotifier{errs: []error{ErrPermanent, ErrPermanent}}
+	d := NewDispatcher([]Notifier{flaky, rejecting}, WithRetries(2, time.Millisecond))
+
+	err := d.Dispatch(t.Context(), e)
+	require.ErrorIs(t, err, ErrPermanent)
+	assert.Equal(t, 3, flaky.calls)
+	assert.Equal(t, 1, rejecting.calls, "permanent failures aren't retried")
+
+	// Retries run out
+	flaky = &flakyNotifier{errs: []error{errors.New("timeout"), errors.New("timeout")}}
+	d = NewDispatcher([]Notifier{flaky}, WithRetries(1, time.Millisecond))
+	require.ErrorContains(t, d.Dispatch(t.Context(), e), "timeout")
+	assert.Equal(t, 2, flaky.calls)
+
+	// and an event nobody got can be sent again
+	require.NoError(t, d.Dispatch(t.Context(), e))
+	assert.Equal(t, 3, flaky.calls)
+}
+
+func TestDispatcher_Dedupe(t *testing.T) {
+	t.Parallel()
+
+	n := &flakyNotifier{}
+	d := NewDispatcher([]Notifier{n})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	e := Event{Type: EventTrackerFailing, InfoHash: hashA, TrackerURL: "http://a.example/announce"}
+	require.NoError(t, d.Dispatch(t.Context(), e))
+	require.NoError(t, d.Dispatch(t.Context(), e))
+	assert.Equal(t, 1, n.calls)
+
+	other := e
+	other.TrackerURL = "http://b.example/announce"
+	require.NoError(t, d.Dispatch(t.Context(), other))
+	assert.Equal(t, 2, n.calls, "a different tracker is a different event")
+
+	now = now.Add(defaultDedupeWindow)
+	require.NoError(t, d.Dispatch(t.Context(), e))
+	assert.Equal(t, 3, n.calls, "the window has passed")
+
+	d = NewDispatcher([]Notifier{n}, WithDedupeWindow(0))
+	require.NoError(t, d.Dispatch(t.Context(), e))
+	require.NoError(t, d.Dispatch(t.Context(), e))
+	assert.Equal(t, 5, n.calls)
+}
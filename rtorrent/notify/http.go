@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// ntfyTags are the tags, which ntfy shows as emoji, for each type of event
+var ntfyTags = map[EventType]string{
+	EventDownloadCompleted: "white_check_mark",
+	EventTrackerFailing:    "warning",
+}
+
+// HTTPOption configures an HTTPNotifier.
+type HTTPOption func(*HTTPNotifier)
+
+// WithHTTPClient makes the notifier send its requests with client, rather than http.DefaultClient.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(n *HTTPNotifier) {
+		n.client = client
+	}
+}
+
+// WithHeader adds a header to every request the notifier sends, such as for authentication.
+func WithHeader(key, value string) HTTPOption {
+	return func(n *HTTPNotifier) {
+		n.header.Add(key, value)
+	}
+}
+
+// WithTemplate makes the notifier's request body from tmpl, made with ParseTemplate, in place of its default.
+func WithTemplate(tmpl *template.Template) HTTPOption {
+	return func(n *HTTPNotifier) {
+		n.tmpl = tmpl
+	}
+}
+
+// HTTPNotifier is a Notifier that posts events to a URL, as a webhook or to ntfy.
+type HTTPNotifier struct {
+	url    string
+	client *http.Client
+	header http.Header
+	tmpl   *template.Template
+	// body is the request body for e without a template
+	body func(e Event) (string, error)
+	// eventHeader sets the headers that depend on the event
+	eventHeader func(h http.Header, e Event)
+}
+
+// NewWebhook returns a notifier that posts each event to rawURL as JSON, or as whatever WithTemplate makes of it.
+func NewWebhook(rawURL string, opts ...HTTPOption) (*HTTPNotifier, error) {
+	return newHTTPNotifier(rawURL, "application/json", func(e Event) (string, error) {
+		b, err := json.Marshal(e)
+		return string(b), err
+	}, nil, opts)
+}
+
+// NewNtfy returns a notifier that publishes each event to an ntfy topic, at topicURL such as https://ntfy.sh/mytopic,
+// with the event's summary, or whatever WithTemplate makes of it, as the message.
+func NewNtfy(topicURL string, opts ...HTTPOption) (*HTTPNotifier, error) {
+	return newHTTPNotifier(topicURL, "text/plain; charset=utf-8", func(e Event) (string, error) {
+		return e.Summary(), nil
+	}, func(h http.Header, e Event) {
+		h.Set("Title", "rTorrent "+string(e.Type))
+		if tags, ok := ntfyTags[e.Type]; ok {
+			h.Set("Tags", tags)
+		}
+	}, opts)
+}
+
+func newHTTPNotifier(
+	rawURL, contentType string, body func(Event) (string, error), eventHeader func(http.Header, Event), opts []HTTPOption,
+) (*HTTPNotifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%s is not an http or https URL", rawURL)
+	}
+	n := &HTTPNotifier{
+		url:         rawURL,
+		client:      http.DefaultClient,
+		header:      http.Header{"Content-Type": {contentType}},
+		body:        body,
+		eventHeader: eventHeader,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n, nil
+}
+
+// Notify posts e. A response the server says is the request's fault, other than a timeout or rate limit, wraps
+// ErrPermanent.
+func (n *HTTPNotifier) Notify(ctx context.Context, e Event) error {
+	fallback, err := n.body(e)
+	if err != nil {
+		return err
+	}
+	body, err := render(n.tmpl, e, fallback)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = n.header.Clone()
+	if n.eventHeader != nil {
+		n.eventHeader(req.Header, e)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch code := resp.StatusCode; {
+	case code >= http.StatusOK && code < http.StatusMultipleChoices:
+		return nil
+	case permanentStatus(code):
+		return fmt.Errorf("%w: %s returned %s", ErrPermanent, n.url, resp.Status)
+	default:
+		return fmt.Errorf("%s returned %s", n.url, resp.Status)
+	}
+}
+
+// permanentStatus reports whether a response with status code means the request will never work, being the client's
+// fault but not a timeout or rate limit that might pass
+func permanentStatus(code int) bool {
+	return code >= http.StatusBadRequest && code < http.StatusInternalServerError &&
+		code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
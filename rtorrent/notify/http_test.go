@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedRequest is what a test receiver was sent
+type receivedRequest struct {
+	header http.Header
+	body   string
+}
+
+// receiver starts a server that answers with each of statuses in turn, and then 200, and sends on the requests it gets
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan receivedRequest) {
+	t.Helper()
+
+	received := make(chan receivedRequest, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedRequest{header: r.Header, body: string(body)}
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+var testEvent = Event{
+	Type:        EventDownloadCompleted,
+	Time:        time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	InfoHash:    hashA,
+	Name:        `a "quoted" name`,
+	SizeBytes:   1024,
+	TrackerHost: "a.example",
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	srv, received := receiver(t, http.StatusServiceUnavailable)
+	hook, err := NewWebhook(srv.URL, WithHeader("Authorization", "Bearer secret"))
+	require.NoError(t, err)
+
+	// The receiver is down at first, which retrying gets past
+	d := NewDispatcher([]Notifier{hook}, WithRetries(1, time.Millisecond))
+	require.NoError(t, d.Dispatch(t.Context(), testEvent))
+	<-received
+	req := <-received
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", req.header.Get("Authorization"))
+	var got Event
+	require.NoError(t, json.Unmarshal([]byte(req.body), &got))
+	assert.Equal(t, testEvent, got)
+
+	// A request the receiver rejects won't work however often it is sent
+	srv, _ = receiver(t, http.StatusNotFound)
+	hook, err = NewWebhook(srv.URL)
+	require.NoError(t, err)
+	require.ErrorIs(t, hook.Notify(t.Context(), testEvent), ErrPermanent)
+
+	_, err = NewWebhook("ftp://example.com")
+	require.Error(t, err)
+}
+
+func TestWebhook_Template(t *testing.T) {
+	t.Parallel()
+
+	srv, received := receiver(t)
+	tmpl, err := ParseTemplate(`{"text": {{json .Summary}}, "size": {{.SizeBytes}}, "tracker": {{json .TrackerHost}}}`)
+	require.NoError(t, err)
+	hook, err := NewWebhook(srv.URL, WithTemplate(tmpl))
+	require.NoError(t, err)
+
+	require.NoError(t, hook.Notify(t.Context(), testEvent))
+	assert.JSONEq(t, `{"text": "Download completed: a \"quoted\" name", "size": 1024, "tracker": "a.example"}`, (<-received).body)
+
+	tmpl, err = ParseTemplate(`{{.NoSuchField}}`)
+	require.NoError(t, err)
+	hook, err = NewWebhook(srv.URL, WithTemplate(tmpl))
+	require.NoError(t, err)
+	require.ErrorIs(t, hook.Notify(t.Context(), testEvent), ErrPermanent, "a broken template won't fix itself")
+}
+
+func TestNtfy(t *testing.T) {
+	t.Parallel()
+
+	srv, received := receiver(t)
+	ntfy, err := NewNtfy(srv.URL + "/torrents")
+	require.NoError(t, err)
+
+	failing := testEvent
+	failing.Type = EventTrackerFailing
+	failing.Message = "Unregistered torrent"
+	require.NoError(t, ntfy.Notify(t.Context(), failing))
+	req := <-received
+	assert.Equal(t, "Tracker a.example failing for a \"quoted\" name: Unregistered torrent", req.body)
+	assert.Equal(t, "rTorrent tracker.failing", req.header.Get("Title"))
+	assert.Equal(t, "warning", req.header.Get("Tags"))
+}
@@ -0,0 +1,110 @@
+// Package notify tells people and other programs about what happens to an rTorrent instance's downloads, such as one
+// completing or its tracker starting to fail.
+//
+// A Watcher polls rTorrent, works out events from what changed since its last poll, and hands them to a Dispatcher,
+// which delivers each to every Notifier, retrying those that fail and dropping events it has just delivered. Webhook
+// posts events as JSON, or any payload a template makes of them, Ntfy publishes them to an ntfy topic and SMTP emails
+// them:
+//
+//	hook, err := notify.NewWebhook("https://example.com/hooks/rtorrent")
+//	ntfy, err := notify.NewNtfy("https://ntfy.sh/my-torrents")
+//	d := notify.NewDispatcher([]notify.Notifier{hook, ntfy}, notify.WithRetries(3, time.Second))
+//	go notify.NewWatcher(client, d).Run(ctx, time.Minute)
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Events
+const (
+	EventDownloadCompleted EventType = "download.completed"
+	EventTrackerFailing    EventType = "tracker.failing"
+)
+
+// ErrPermanent is wrapped by errors from a Notifier that retrying won't fix, such as a webhook rejecting the request.
+var ErrPermanent = errors.New("permanent failure")
+
+// EventType is what kind of thing an Event reports.
+type EventType string
+
+// Event is something that happened to a download.
+type Event struct {
+	Type     EventType `json:"type"`
+	Time     time.Time `json:"time"`
+	InfoHash string    `json:"info_hash"`
+	// Name is the download's base filename, as rTorrent's UI shows it
+	Name      string `json:"name"`
+	SizeBytes int    `json:"size_bytes"`
+	// TrackerHost is the host of the tracker that started failing, or the download's first tracker for other events
+	TrackerHost string `json:"tracker_host,omitempty"`
+	// TrackerURL is the URL of the tracker that started failing
+	TrackerURL string `json:"tracker_url,omitempty"`
+	// Message is the download's d.message, which is usually why its tracker failed
+	Message string `json:"message,omitempty"`
+}
+
+// Key identifies the event for deduplication: the same thing happening to the same download, or tracker, has the same
+// key.
+func (e Event) Key() string {
+	return strings.Join([]string{string(e.Type), e.InfoHash, e.TrackerURL}, " ")
+}
+
+// Summary describes the event in a line, for notifications meant for people.
+func (e Event) Summary() string {
+	switch e.Type {
+	case EventDownloadCompleted:
+		return fmt.Sprintf("Download completed: %s", e.Name)
+	case EventTrackerFailing:
+		if e.Message != "" {
+			return fmt.Sprintf("Tracker %s failing for %s: %s", e.TrackerHost, e.Name, e.Message)
+		}
+		return fmt.Sprintf("Tracker %s failing for %s", e.TrackerHost, e.Name)
+	default:
+		return fmt.Sprintf("%s: %s", e.Type, e.Name)
+	}
+}
+
+// Notifier delivers events somewhere.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// NotifierFunc lets an ordinary function be used as a Notifier.
+type NotifierFunc func(ctx context.Context, e Event) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// ParseTemplate parses a template for a notifier's payload, which is executed with the Event. Besides the built in
+// functions it has json, which writes a value as JSON, so values can be put in a JSON payload safely:
+//
+//	{"text": {{json .Summary}}, "size": {{.SizeBytes}}}
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("notify").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// render executes tmpl with e, or returns fallback if there is no template
+func render(tmpl *template.Template, e Event, fallback string) (string, error) {
+	if tmpl == nil {
+		return fallback, nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, e); err != nil {
+		return "", fmt.Errorf("rendering %s: %w", e.Type, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SMTPConfig says how an SMTP notifier sends its emails.
+type SMTPConfig struct {
+	// Addr is the mail server's host:port
+	Addr string
+	// Auth authenticates with the server, if it needs it, such as smtp.PlainAuth
+	Auth smtp.Auth
+	From string
+	To   []string
+	// Subject and Body make the email from the event, in place of its summary and a list of its details
+	Subject *template.Template
+	Body    *template.Template
+}
+
+// SMTP is a Notifier that emails events.
+type SMTP struct {
+	cfg  SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP returns a notifier that emails each event as cfg says.
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if len(cfg.To) == 0 {
+		return nil, errors.New("an SMTP notifier needs someone to send to")
+	}
+	for _, addr := range append([]string{cfg.From}, cfg.To...) {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return nil, fmt.Errorf("%q: %w", addr, err)
+		}
+	}
+	return &SMTP{cfg: cfg, send: smtp.SendMail}, nil
+}
+
+// Notify emails e. net/smtp has no way to cancel a send, so ctx is only checked before it starts.
+func (s *SMTP) Notify(ctx context.Context, e Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	subject, err := render(s.cfg.Subject, e, e.Summary())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+	body, err := render(s.cfg.Body, e, defaultEmailBody(e))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+
+	// A line break in the subject would let an event's name add headers of its own, and names that aren't ASCII need
+	// encoding
+	subject = strings.Join(strings.Fields(subject), " ")
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", e.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return s.send(s.cfg.Addr, s.cfg.Auth, s.cfg.From, s.cfg.To, []byte(msg.String()))
+}
+
+// defaultEmailBody lists an event's details
+func defaultEmailBody(e Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", e.Summary())
+	fmt.Fprintf(&b, "Name: %s\nInfo-hash: %s\nSize: %d bytes\n", e.Name, e.InfoHash, e.SizeBytes)
+	if e.TrackerHost != "" {
+		fmt.Fprintf(&b, "Tracker: %s\n", e.TrackerHost)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, "Message: %s\n", e.Message)
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"net/smtp"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTP(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewSMTP(SMTPConfig{
+		Addr:    "mail.example:25",
+		From:    "rtorrent@example.com",
+		To:      []string{"me@example.com", "you@example.com"},
+		Subject: template.Must(ParseTemplate("Done:\r\nBcc: evil@example.com {{.Name}}")),
+	})
+	require.NoError(t, err)
+	var sent string
+	s.send = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Equal(t, "mail.example:25", addr)
+		assert.Equal(t, "rtorrent@example.com", from)
+		assert.Equal(t, []string{"me@example.com", "you@example.com"}, to)
+		sent = string(msg)
+		return nil
+	}
+
+	e := testEvent
+	e.Name = "Café"
+	require.NoError(t, s.Notify(t.Context(), e))
+	assert.Equal(t, "From: rtorrent@example.com\r\n"+
+		"To: me@example.com, you@example.com\r\n"+
+		"Subject: =?utf-8?q?Done:_Bcc:_evil@example.com_Caf=C3=A9?=\r\n"+
+		"Date: Fri, 02 Jan 2026 03:04:05 +0000\r\n"+
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n"+
+		"Download completed: Café\r\n\r\n"+
+		"Name: Café\r\nInfo-hash: "+hashA+"\r\nSize: 1024 bytes\r\nTracker: a.example\r\n", sent)
+
+	_, err = NewSMTP(SMTPConfig{From: "rtorrent@example.com"})
+	require.Error(t, err)
+	_, err = NewSMTP(SMTPConfig{From: "not an address", To: []string{"me@example.com"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+// watchFields are the download fields a Watcher takes, for working out events and filling them in
+var watchFields = []rtorrent.DownloadField{
+	rtorrent.DownloadFieldHash,
+	rtorrent.DownloadFieldName,
+	rtorrent.DownloadFieldBaseFilename,
+	rtorrent.DownloadFieldComplete,
+	rtorrent.DownloadFieldSizeBytes,
+	rtorrent.DownloadFieldMessage,
+}
+
+// WatcherOption configures a Watcher.
+type WatcherOption func(*Watcher)
+
+// WithView limits the watcher to the downloads in the given rTorrent view, rather than every download.
+func WithView(view string) WatcherOption {
+	return func(w *Watcher) {
+		w.view = view
+	}
+}
+
+// WithErrorHandler is given the error from each of Run's polls that has one. Without it, Run carries on past them
+// silently.
+func WithErrorHandler(fn func(error)) WatcherOption {
+	return func(w *Watcher) {
+		w.onError = fn
+	}
+}
+
+// Watcher works out events from how rTorrent's downloads change, and dispatches them.
+type Watcher struct {
+	snaps      *rtorrent.SnapshotService
+	dispatcher *Dispatcher
+	view       string
+	onError    func(error)
+
+	mu   sync.Mutex
+	last *rtorrent.Snapshot
+}
+
+// NewWatcher returns a Watcher that watches the rTorrent instance behind c and dispatches its events to d.
+func NewWatcher(c rtorrent.Client, d *Dispatcher, opts ...WatcherOption) *Watcher {
+	w := &Watcher{
+		snaps:      &rtorrent.SnapshotService{C: c},
+		dispatcher: d,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Poll snapshots rTorrent, dispatches the events since the last poll, and returns them. The first poll has nothing to
+// compare with, so it only takes the snapshot the next compares with. A download completing is an event, as is a
+// tracker starting to fail, which is one that was working, or hadn't been announced to, now being down or saying the
+// torrent is unregistered. Events that couldn't be dispatched are still returned, along with the error.
+func (w *Watcher) Poll(ctx context.Context) ([]Event, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snap, err := w.snaps.Snapshot(ctx, rtorrent.WithSnapshotView(w.view), rtorrent.WithSnapshotFields(watchFields...),
+		rtorrent.WithSnapshotTrackerFields(rtorrent.TrackerHealthFields()...))
+	if err != nil {
+		return nil, err
+	}
+	last := w.last
+	w.last = snap
+	if last == nil {
+		return nil, nil
+	}
+
+	var events []Event
+	for _, change := range rtorrent.Diff(last, snap).Changed {
+		d := snap.Downloads[change.InfoHash]
+		for _, fc := range change.Fields {
+			was, _ := fc.Old.(bool)
+			is, _ := fc.New.(bool)
+			if fc.Field == rtorrent.DownloadFieldComplete && !was && is {
+				e := event(EventDownloadCompleted, snap, d)
+				e.TrackerHost = firstTrackerHost(snap.Trackers[change.InfoHash])
+				events = append(events, e)
+			}
+		}
+		for _, t := range startedFailing(last, snap, change.InfoHash) {
+			e := event(EventTrackerFailing, snap, d)
+			e.TrackerURL, _ = t.URL()
+			e.TrackerHost = hostOf(e.TrackerURL)
+			events = append(events, e)
+		}
+	}
+
+	var errs []error
+	for _, e := range events {
+		if err := w.dispatcher.Dispatch(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return events, errors.Join(errs...)
+}
+
+// Run polls straight away and then every interval, until ctx is done, and returns ctx's error.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Poll(ctx); err != nil && w.onError != nil && ctx.Err() == nil {
+			w.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// event fills in an event about d from the snapshot, named by its base filename, or its name if it has none
+func event(typ EventType, snap *rtorrent.Snapshot, d *rtorrent.Download) Event {
+	e := Event{Type: typ, Time: snap.Time}
+	e.InfoHash, _ = d.Hash()
+	e.SizeBytes, _ = d.SizeBytes()
+	e.Message, _ = d.Message()
+	name, err := d.BaseFilename()
+	if err != nil || name == "" {
+		name, _ = d.Name()
+	}
+	e.Name = name
+	return e
+}
+
+// startedFailing finds the trackers of a download that are failing in snapshot b but weren't in a, by URL
+func startedFailing(a, b *rtorrent.Snapshot, hash string) []*rtorrent.Tracker {
+	oldMessage, _ := a.Downloads[hash].Message()
+	newMessage, _ := b.Downloads[hash].Message()
+	wasFailing := make(map[string]bool)
+	for _, t := range a.Trackers[hash] {
+		if u, err := t.URL(); err == nil {
+			wasFailing[u] = failing(t, oldMessage)
+		}
+	}
+
+	var started []*rtorrent.Tracker
+	for _, t := range b.Trackers[hash] {
+		u, err := t.URL()
+		if err != nil {
+			continue
+		}
+		if was, ok := wasFailing[u]; ok && !was && failing(t, newMessage) {
+			started = append(started, t)
+		}
+	}
+	return started
+}
+
+// failing reports whether t, which DHT never is, is down or has said the torrent is unregistered
+func failing(t *rtorrent.Tracker, message string) bool {
+	if typ, err := t.Type(); err == nil && typ == rtorrent.TypeDHT {
+		return false
+	}
+	h, err := rtorrent.ClassifyTracker(t, message)
+	return err == nil && (h == rtorrent.HealthDown || h == rtorrent.HealthUnregistered)
+}
+
+// firstTrackerHost is the host of the first of trackers that isn't DHT, or "" if there isn't one
+func firstTrackerHost(trackers []*rtorrent.Tracker) string {
+	for _, t := range trackers {
+		if typ, err := t.Type(); err == nil && typ == rtorrent.TypeDHT {
+			continue
+		}
+		if u, err := t.URL(); err == nil {
+			return hostOf(u)
+		}
+	}
+	return ""
+}
+
+// hostOf is the host of a tracker URL, or the whole URL if it doesn't have one
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return u.Hostname()
+}
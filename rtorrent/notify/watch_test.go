@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	hashA = strings.Repeat("A", 40)
+	hashB = strings.Repeat("B", 40)
+)
+
+// fakeRTorrent answers the downloads and trackers multicalls with downloads and trackers, rows in the order of
+// watchFields and rTorrent's tracker health fields, and the globals with 0. Anything else fails, as the watcher should
+// get all it needs from its snapshots.
+type fakeRTorrent struct {
+	mu        sync.Mutex
+	downloads [][]any
+	trackers  [][]any
+}
+
+func (f *fakeRTorrent) client(t *testing.T) rtorrent.Client {
+	t.Helper()
+
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil, rtorrent.WithInterceptors(
+		func(_ context.Context, method string, args []any, reply any, _ rtorrent.Invoker) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			switch method {
+			case "d.multicall2":
+				*reply.(*[][]any) = f.downloads
+				for _, arg := range args {
+					if s, _ := arg.(string); strings.HasPrefix(s, "t.multicall=") {
+						*reply.(*[][]any) = f.trackers
+					}
+				}
+			case "down.total", "up.total", "down.rate", "up.rate":
+				*reply.(*int) = 0
+			default:
+				return errors.New("unexpected call to " + method)
+			}
+			return nil
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func (f *fakeRTorrent) set(downloads, trackers [][]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.downloads, f.trackers = downloads, trackers
+}
+
+// downloadRow is a download that has a base filename if baseFilename isn't ""
+func downloadRow(hash, baseFilename string, complete bool, message string) []any {
+	c := int64(0)
+	if complete {
+		c = 1
+	}
+	return []any{hash, "name " + hash[:1], baseFilename, c, int64(1024), message}
+}
+
+// trackersRow is the trackers of a download, with announces to the tracker at u having failed the given number of
+// times in a row after having worked before, along with the download's DHT
+func trackersRow(hash, u string, failed int) []any {
+	return []any{hash, []any{
+		[]any{"dht://", int64(rtorrent.TypeDHT), int64(1), int64(9), int64(0)},
+		[]any{u, int64(rtorrent.TypeHTTP), int64(1), int64(failed), int64(5)},
+	}}
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{}
+	var (
+		mu        sync.Mutex
+		delivered []Event
+	)
+	d := NewDispatcher([]Notifier{NotifierFunc(func(_ context.Context, e Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, e)
+		return nil
+	})})
+	w := NewWatcher(fake.client(t), d)
+
+	fake.set(
+		[][]any{downloadRow(hashA, "a.iso", false, ""), downloadRow(hashB, "", true, "")},
+		[][]any{trackersRow(hashA, "http://a.example/announce", 0), trackersRow(hashB, "https://b.example:8443/announce", 1)},
+	)
+	events, err := w.Poll(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, events, "the first poll only takes a snapshot")
+
+	fake.set(
+		[][]any{downloadRow(hashA, "a.iso", true, ""), downloadRow(hashB, "", true, "Tracker: [Failure reason \"Unregistered torrent\"]")},
+		[][]any{trackersRow(hashA, "http://a.example/announce", 0), trackersRow(hashB, "https://b.example:8443/announce", 2)},
+	)
+	events, err = w.Poll(t.Context())
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, EventDownloadCompleted, events[0].Type)
+	assert.Equal(t, hashA, events[0].InfoHash)
+	assert.Equal(t, "a.iso", events[0].Name, "named by its base filename")
+	assert.Equal(t, 1024, events[0].SizeBytes)
+	assert.Equal(t, "a.example", events[0].TrackerHost)
+
+	assert.Equal(t, EventTrackerFailing, events[1].Type)
+	assert.Equal(t, "name B", events[1].Name, "named by its name when there is no base filename")
+	assert.Equal(t, "b.example", events[1].TrackerHost)
+	assert.Equal(t, "https://b.example:8443/announce", events[1].TrackerURL)
+	assert.Contains(t, events[1].Summary(), "Unregistered torrent")
+
+	assert.Equal(t, events, delivered)
+
+	// Nothing changed, so nothing happened
+	events, err = w.Poll(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestWatcher_Run(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{}
+	w := NewWatcher(fake.client(t), NewDispatcher(nil), WithView("main"))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, w.Run(ctx, time.Hour), context.Canceled)
+}
@@ -0,0 +1,177 @@
+// Package otelrtorrent instruments an rtorrent client with OpenTelemetry. It hooks in through the client's interceptor
+// chain, so the core rtorrent package stays free of any OpenTelemetry dependency:
+//
+//	interceptor, err := otelrtorrent.Interceptor()
+//	if err != nil {
+//		return err
+//	}
+//	c, err := rtorrent.New(addr, nil, rtorrent.WithInterceptors(interceptor))
+package otelrtorrent
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/kolo/xmlrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope that spans and metrics are reported under.
+const ScopeName = "github.com/aauren/rtorrent/rtorrent/otelrtorrent"
+
+// Attribute keys set on spans and metrics. Only the method and error keys go on metrics, since an info-hash per data
+// point would blow up the series count.
+const (
+	AttrRPCSystem = attribute.Key("rpc.system")
+	AttrRPCMethod = attribute.Key("rpc.method")
+	AttrErrorType = attribute.Key("error.type")
+	AttrInfoHash  = attribute.Key("rtorrent.info_hash")
+	AttrView      = attribute.Key("rtorrent.view")
+	AttrFaultCode = attribute.Key("rtorrent.fault_code")
+)
+
+// Metric names for the instruments Interceptor creates
+const (
+	MetricCallDuration   = "rtorrent.client.call.duration"
+	MetricResponseValues = "rtorrent.client.response.values"
+)
+
+const (
+	// infoHashHexLen is the length of a hex encoded v1 info-hash, which is how rTorrent identifies downloads
+	infoHashHexLen = 40
+
+	// rpcSystem is reported as the rpc.system attribute on every span
+	rpcSystem = "xmlrpc"
+
+	// errorTypeFault and errorTypeOther tell rTorrent's own faults apart from transport failures in error.type
+	errorTypeFault = "fault"
+	errorTypeOther = "_OTHER"
+)
+
+// config collects the providers the instruments are built from, defaulting to the global ones
+type config struct {
+	tp trace.TracerProvider
+	mp metric.MeterProvider
+}
+
+// An Option configures Interceptor.
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider spans are created from, in place of the global one.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tp = tp
+	}
+}
+
+// WithMeterProvider sets the MeterProvider the histograms are created from, in place of the global one.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.mp = mp
+	}
+}
+
+// Interceptor returns an rtorrent.Interceptor that wraps every XML-RPC call in a client span named after the rTorrent
+// method, such as d.multicall2 or t.multicall, and records the call's latency and how many values it gave back. Spans
+// carry the info-hash and view the call targets when its arguments name them. Values are counted the way
+// rtorrent.ReplySize counts them, in rows or values rather than bytes, since the decoded reply is all an interceptor
+// sees, which is why the histogram isn't a response size in bytes.
+func Interceptor(opts ...Option) (rtorrent.Interceptor, error) {
+	cfg := config{tp: otel.GetTracerProvider(), mp: otel.GetMeterProvider()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	tracer := cfg.tp.Tracer(ScopeName)
+	meter := cfg.mp.Meter(ScopeName)
+
+	duration, err := meter.Float64Histogram(MetricCallDuration,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of XML-RPC calls to rTorrent."))
+	if err != nil {
+		return nil, fmt.Errorf("creating %s histogram: %w", MetricCallDuration, err)
+	}
+	size, err := meter.Int64Histogram(MetricResponseValues,
+		metric.WithUnit("{value}"),
+		metric.WithDescription("Number of rows or values in XML-RPC responses from rTorrent."))
+	if err != nil {
+		return nil, fmt.Errorf("creating %s histogram: %w", MetricResponseValues, err)
+	}
+
+	return func(ctx context.Context, method string, args []any, reply any, next rtorrent.Invoker) error {
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(spanAttributes(method, args)...))
+		defer span.End()
+
+		start := time.Now()
+		err := next(ctx, method, args, reply)
+		elapsed := time.Since(start)
+
+		metricAttrs := []attribute.KeyValue{AttrRPCMethod.String(method)}
+		if err != nil {
+			errType := errorTypeOther
+			var fault xmlrpc.FaultError
+			if errors.As(err, &fault) {
+				errType = errorTypeFault
+				span.SetAttributes(AttrFaultCode.Int(fault.Code))
+			}
+			metricAttrs = append(metricAttrs, AttrErrorType.String(errType))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		set := metric.WithAttributes(metricAttrs...)
+		duration.Record(ctx, elapsed.Seconds(), set)
+		if err == nil {
+			size.Record(ctx, int64(rtorrent.ReplySize(reply)), set)
+		}
+
+		return err
+	}, nil
+}
+
+// spanAttributes picks out what a call targets from its arguments. Download and tracker commands lead with an
+// info-hash, optionally suffixed with a tracker index, while download_list, d.multicall2 and d.multicall.filtered lead
+// with an empty target followed by the view.
+func spanAttributes(method string, args []any) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{AttrRPCSystem.String(rpcSystem), AttrRPCMethod.String(method)}
+	if len(args) == 0 {
+		return attrs
+	}
+
+	if target, ok := args[0].(string); ok {
+		hash, _, _ := strings.Cut(target, ":")
+		if isInfoHash(hash) {
+			attrs = append(attrs, AttrInfoHash.String(strings.ToUpper(hash)))
+		}
+	}
+
+	if method == "download_list" || method == "d.multicall2" || method == "d.multicall.filtered" {
+		if len(args) > 1 {
+			if view, ok := args[1].(string); ok && view != "" {
+				attrs = append(attrs, AttrView.String(view))
+			}
+		}
+	}
+
+	return attrs
+}
+
+// isInfoHash reports whether s looks like a hex encoded v1 info-hash
+func isInfoHash(s string) bool {
+	if len(s) != infoHashHexLen {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,174 @@
+package otelrtorrent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/kolo/xmlrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var testInfoHash = strings.Repeat("A", 40)
+
+// testProviders wires in-memory span and metric readers into an Interceptor
+func testProviders(t *testing.T) (*tracetest.SpanRecorder, *sdkmetric.ManualReader, rtorrent.Interceptor) {
+	t.Helper()
+
+	spans := tracetest.NewSpanRecorder()
+	metrics := sdkmetric.NewManualReader()
+
+	interceptor, err := Interceptor(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metrics))))
+	require.NoError(t, err)
+
+	return spans, metrics, interceptor
+}
+
+// fakeRTorrent returns an interceptor standing in for rTorrent itself, so calls never leave the process
+func fakeRTorrent(reply [][]any, err error) rtorrent.Interceptor {
+	return func(_ context.Context, _ string, _ []any, out any, _ rtorrent.Invoker) error {
+		if err != nil {
+			return err
+		}
+		*out.(*[][]any) = reply
+		return nil
+	}
+}
+
+func attrMap(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(kvs))
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestInterceptorThroughClient(t *testing.T) {
+	t.Parallel()
+
+	spans, metrics, interceptor := testProviders(t)
+
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil,
+		rtorrent.WithInterceptors(interceptor, fakeRTorrent([][]any{{"a"}, {"b"}}, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	ds := &rtorrent.DownloadService{C: c}
+	_, err = ds.DownloadWithDetails([]string{"d.name="})
+	require.NoError(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, "d.multicall2", ended[0].Name())
+	assert.Equal(t, codes.Unset, ended[0].Status().Code)
+
+	attrs := attrMap(ended[0].Attributes())
+	assert.Equal(t, "default", attrs[AttrView].AsString())
+	assert.Equal(t, "xmlrpc", attrs[AttrRPCSystem].AsString())
+	assert.NotContains(t, attrs, AttrInfoHash, "a multicall over a view targets no single download")
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, metrics.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	byName := make(map[string]metricdata.Metrics)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		byName[m.Name] = m
+	}
+
+	durations, ok := byName[MetricCallDuration].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, durations.DataPoints, 1)
+	assert.Equal(t, uint64(1), durations.DataPoints[0].Count)
+
+	sizes, ok := byName[MetricResponseValues].Data.(metricdata.Histogram[int64])
+	require.True(t, ok)
+	require.Len(t, sizes.DataPoints, 1)
+	assert.Equal(t, int64(2), sizes.DataPoints[0].Sum, "one value per multicall row")
+}
+
+func TestInterceptorTagsInfoHash(t *testing.T) {
+	t.Parallel()
+
+	spans, _, interceptor := testProviders(t)
+
+	var reply [][]any
+	err := interceptor(t.Context(), "t.multicall", []any{strings.ToLower(testInfoHash) + ":1", "", "t.url="}, &reply,
+		func(context.Context, string, []any, any) error { return nil })
+	require.NoError(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, "t.multicall", ended[0].Name())
+	assert.Equal(t, testInfoHash, attrMap(ended[0].Attributes())[AttrInfoHash].AsString(),
+		"the tracker index is stripped and the hash upper-cased to match download_list")
+}
+
+func TestInterceptorTagsFilteredView(t *testing.T) {
+	t.Parallel()
+
+	spans, _, interceptor := testProviders(t)
+
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil,
+		rtorrent.WithInterceptors(interceptor, fakeRTorrent([][]any{{testInfoHash}}, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	_, err = (&rtorrent.DownloadService{C: c}).Download(t.Context(), testInfoHash, nil)
+	require.NoError(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, "d.multicall.filtered", ended[0].Name())
+	assert.Equal(t, "default", attrMap(ended[0].Attributes())[AttrView].AsString())
+}
+
+func TestInterceptorRecordsFaults(t *testing.T) {
+	t.Parallel()
+
+	spans, metrics, interceptor := testProviders(t)
+
+	var reply string
+	err := interceptor(t.Context(), "d.name", []any{testInfoHash}, &reply,
+		func(context.Context, string, []any, any) error {
+			return xmlrpc.FaultError{Code: -501, String: "Could not find info-hash."}
+		})
+	require.Error(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	attrs := attrMap(ended[0].Attributes())
+	assert.Equal(t, int64(-501), attrs[AttrFaultCode].AsInt64())
+	assert.Equal(t, testInfoHash, attrs[AttrInfoHash].AsString())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, metrics.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch m.Name {
+		case MetricCallDuration:
+			durations, ok := m.Data.(metricdata.Histogram[float64])
+			require.True(t, ok)
+			require.Len(t, durations.DataPoints, 1)
+			errType, ok := durations.DataPoints[0].Attributes.Value(AttrErrorType)
+			require.True(t, ok)
+			assert.Equal(t, errorTypeFault, errType.AsString())
+		case MetricResponseValues:
+			sizes, ok := m.Data.(metricdata.Histogram[int64])
+			require.True(t, ok)
+			assert.Empty(t, sizes.DataPoints, "failed calls have no response to measure")
+		}
+	}
+}
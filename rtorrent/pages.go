@@ -0,0 +1,292 @@
+package rtorrent
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// downloadFilteredMultiCall is d.multicall2 for only the downloads of a view that meet a filter, which rTorrent has
+// had since 0.9.7
+const downloadFilteredMultiCall = "d.multicall.filtered"
+
+// DefaultPageLimit is how many downloads a page holds when a PageRequest doesn't say.
+const DefaultPageLimit = 50
+
+// ErrBadCursor is returned by Page for a cursor it didn't make, or made for a different sort.
+var ErrBadCursor = errors.New("bad cursor")
+
+// PageRequest says which page of downloads Page should retrieve.
+type PageRequest struct {
+	// View is the view to page through, or the default view if empty
+	View string
+	// SortBy is the field to sort by, or the info-hash if empty. Downloads that sort the same are ordered by info-hash,
+	// so the order is the same from one page to the next.
+	SortBy     DownloadField
+	Descending bool
+	// Offset is how many downloads to skip, unless After is given
+	Offset int
+	// After is the Next cursor of the previous page. Unlike an offset, it carries on from the same download however
+	// many downloads have been added or removed since.
+	After string
+	// Limit is how many downloads the page holds, or DefaultPageLimit if it is 0
+	Limit int
+}
+
+// DownloadPage is a page of downloads retrieved by Page.
+type DownloadPage struct {
+	Downloads []*Download
+	// Total is how many downloads there were to page through
+	Total int
+	// Next is the cursor for the next page, or "" if this is the last
+	Next string
+}
+
+// sortKey is what a download is sorted by: its value of the sort field, nil if that couldn't be read, and its hash
+type sortKey struct {
+	hash  string
+	raw   any
+	value any
+}
+
+// cursor is what a Next cursor holds, base64 encoded JSON of the last download on the page and the sort it came from
+type cursor struct {
+	SortBy     DownloadField `json:"s"`
+	Descending bool          `json:"d,omitempty"`
+	Hash       string        `json:"h"`
+	Value      any           `json:"v"`
+}
+
+// Page retrieves a page of the downloads in a view, sorted by any download field, with the requested fields. It
+// takes two requests however many downloads there are: one for every download's hash and sort field, which is
+// quick even with tens of thousands of downloads, and one for the rest of the fields of just the downloads on the
+// page, which rTorrent picks out with d.multicall.filtered. A download removed between the two is left off the page.
+func (s *DownloadService) Page(ctx context.Context, req PageRequest, fields []DownloadField) (*DownloadPage, error) {
+	sortBy := cmp.Or(req.SortBy, DownloadFieldHash)
+	field, ok := downloadFields.Lookup(sortBy)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownField, sortBy)
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		return nil, fmt.Errorf("%w: negative page offset or limit", ErrBadData)
+	}
+	limit := cmp.Or(req.Limit, DefaultPageLimit)
+	args, err := downloadFields.Arguments(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := s.Downloads(ctx, req.View, []DownloadField{sortBy})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]sortKey, 0, len(all))
+	for _, d := range all {
+		hash, err := d.Hash()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, newSortKey(field, hash, d.dData[sortBy]))
+	}
+	compare := sortKeyCompare(req.Descending)
+	slices.SortFunc(keys, compare)
+
+	start := req.Offset
+	if req.After != "" {
+		after, err := decodeCursor(req.After, sortBy, req.Descending)
+		if err != nil {
+			return nil, err
+		}
+		start, _ = slices.BinarySearchFunc(keys, newSortKey(field, after.Hash, after.Value), compare)
+		if start < len(keys) && keys[start].hash == after.Hash {
+			start++
+		}
+	}
+	start = min(start, len(keys))
+	end := min(start+limit, len(keys))
+	onPage := keys[start:end]
+
+	page := &DownloadPage{Total: len(keys)}
+	if end < len(keys) {
+		page.Next = encodeCursor(cursor{SortBy: sortBy, Descending: req.Descending, Hash: onPage[len(onPage)-1].hash,
+			Value: onPage[len(onPage)-1].raw})
+	}
+	if len(onPage) == 0 {
+		return page, nil
+	}
+	page.Downloads, err = s.pageDownloads(ctx, req.View, onPage, fields, args)
+	return page, err
+}
+
+// pageDownloads retrieves the fields of the downloads on a page, in the page's order
+func (s *DownloadService) pageDownloads(
+	ctx context.Context, view string, onPage []sortKey, fields []DownloadField, args []string,
+) ([]*Download, error) {
+	if view == "" {
+		view = "default"
+	}
+	if !slices.Contains(fields, DownloadFieldHash) {
+		fields = slices.Concat([]DownloadField{DownloadFieldHash}, fields)
+		args = slices.Concat([]string{DownloadFieldHash.AsXMLRPCArgument()}, args)
+	}
+	matches := make([]string, 0, len(onPage))
+	for _, k := range onPage {
+		// The hash goes into the filter as is, so anything but an info-hash could close the brace and add commands of
+		// its own for rTorrent to run
+		if !isInfoHash(k.hash) {
+			return nil, fmt.Errorf("%w: %q is not an info-hash", ErrBadData, k.hash)
+		}
+		matches = append(matches, "equal={"+DownloadFieldHash.AsXMLRPCArgument()+",cat="+k.hash+"}")
+	}
+	filter := matches[0]
+	if len(matches) > 1 {
+		filter = "or={" + strings.Join(matches, ",") + "}"
+	}
+
+	rows, err := s.C.getSliceSlice(ctx, downloadFilteredMultiCall, slices.Concat([]string{view, filter}, args)...)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := DecodeMulticall(downloadFields, fields, rows, func(_ int, r Record[DownloadField]) *Download {
+		return &Download{dData: r}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// rTorrent gives them back in the view's order, not ours
+	byHash := make(map[string]*Download, len(downloads))
+	for _, d := range downloads {
+		if hash, err := d.Hash(); err == nil {
+			byHash[hash] = d
+		}
+	}
+	ordered := make([]*Download, 0, len(onPage))
+	for _, k := range onPage {
+		if d, ok := byHash[k.hash]; ok {
+			ordered = append(ordered, d)
+		}
+	}
+	return ordered, nil
+}
+
+// isInfoHash reports whether hash is a v1 info-hash in hex, which is all rTorrent addresses downloads by
+func isInfoHash(hash string) bool {
+	if len(hash) != infoHashHexLen {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// SortDownloads sorts downloads by field, as Page does, for downloads retrieved some other way, which need field and
+// the hash. A download whose field can't be read sorts after the rest, or before them if descending.
+func SortDownloads(downloads []*Download, field DownloadField, descending bool) error {
+	f, ok := downloadFields.Lookup(field)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownField, field)
+	}
+	keys := make(map[*Download]sortKey, len(downloads))
+	for _, d := range downloads {
+		hash, err := d.Hash()
+		if err != nil {
+			return err
+		}
+		keys[d] = newSortKey(f, hash, d.dData[field])
+	}
+	compare := sortKeyCompare(descending)
+	slices.SortFunc(downloads, func(a, b *Download) int {
+		return compare(keys[a], keys[b])
+	})
+	return nil
+}
+
+func newSortKey(field Field[DownloadField], hash string, raw any) sortKey {
+	value, err := field.Value(Record[DownloadField]{field.Name(): raw})
+	if err != nil {
+		value = nil
+	}
+	return sortKey{hash: hash, raw: raw, value: value}
+}
+
+// sortKeyCompare orders sort keys by value and then hash, which is always ascending so pages of downloads that sort
+// the same come in a fixed order
+func sortKeyCompare(descending bool) func(a, b sortKey) int {
+	return func(a, b sortKey) int {
+		c := compareValues(a.value, b.value)
+		if descending {
+			c = -c
+		}
+		return cmp.Or(c, strings.Compare(a.hash, b.hash))
+	}
+}
+
+// compareValues compares two converted values of the same field, with nil after anything else
+func compareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch a := a.(type) {
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	case int:
+		b, _ := b.(int)
+		return cmp.Compare(a, b)
+	case float64:
+		b, _ := b.(float64)
+		return cmp.Compare(a, b)
+	case bool:
+		b, _ := b.(bool)
+		return compareBools(a, b)
+	case time.Time:
+		b, _ := b.(time.Time)
+		return a.Compare(b)
+	default:
+		return 0
+	}
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func encodeCursor(c cursor) string {
+	// A cursor only holds a hash and a value rTorrent sent us, which always marshal
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, sortBy DownloadField, descending bool) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: %w", ErrBadCursor, err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%w: %w", ErrBadCursor, err)
+	}
+	if c.SortBy != sortBy || c.Descending != descending {
+		return c, fmt.Errorf("%w: it is for a different sort", ErrBadCursor)
+	}
+	return c, nil
+}
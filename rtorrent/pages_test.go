@@ -0,0 +1,174 @@
+package rtorrent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var (
+	pageHashA = strings.Repeat("A", 40)
+	pageHashB = strings.Repeat("B", 40)
+	pageHashC = strings.Repeat("C", 40)
+	pageHashD = strings.Repeat("D", 40)
+)
+
+// expectPageKeys expects Page's first request, for every download's hash and size
+func expectPageKeys(m *MockClient) {
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.size_bytes=").Return([][]any{
+		{pageHashA, int64(300)},
+		{pageHashB, int64(100)},
+		{pageHashC, int64(300)},
+		{pageHashD, "unreadable"},
+	}, nil)
+}
+
+func hashesOf(t *testing.T, downloads []*Download) []string {
+	t.Helper()
+
+	hashes := make([]string, 0, len(downloads))
+	for _, d := range downloads {
+		hash, err := d.Hash()
+		require.NoError(t, err)
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+func TestDownloadService_Page(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	ds := &DownloadService{C: m}
+	req := PageRequest{SortBy: DownloadFieldSizeBytes, Limit: 2}
+
+	expectPageKeys(m)
+	// rTorrent hands the page back in its own order, which Page puts right
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadFilteredMultiCall, "default",
+		"or={equal={d.hash=,cat="+pageHashB+"},equal={d.hash=,cat="+pageHashA+"}}", "d.hash=", "d.name=").
+		Return([][]any{{pageHashA, "a"}, {pageHashB, "b"}}, nil)
+
+	page, err := ds.Page(t.Context(), req, []DownloadField{DownloadFieldName})
+	require.NoError(t, err)
+	assert.Equal(t, 4, page.Total)
+	assert.Equal(t, []string{pageHashB, pageHashA}, hashesOf(t, page.Downloads), "ties are broken by hash")
+	require.NotEmpty(t, page.Next)
+
+	// The second page carries on after A even though it has gone, and an unreadable size sorts last
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.size_bytes=").Return([][]any{
+		{pageHashB, int64(100)},
+		{pageHashC, int64(300)},
+		{pageHashD, "unreadable"},
+	}, nil)
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadFilteredMultiCall, "default",
+		"or={equal={d.hash=,cat="+pageHashC+"},equal={d.hash=,cat="+pageHashD+"}}", "d.hash=", "d.name=").
+		Return([][]any{{pageHashC, "c"}}, nil)
+
+	req.After = page.Next
+	page, err = ds.Page(t.Context(), req, []DownloadField{DownloadFieldName})
+	require.NoError(t, err)
+	assert.Equal(t, []string{pageHashC}, hashesOf(t, page.Downloads), "D was removed before its fields were fetched")
+	assert.Empty(t, page.Next)
+
+	// A cursor only works for the sort it was made for
+	expectPageKeys(m)
+	req.Descending = true
+	_, err = ds.Page(t.Context(), req, nil)
+	require.ErrorIs(t, err, ErrBadCursor)
+}
+
+func TestDownloadService_PageOffset(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	ds := &DownloadService{C: m}
+
+	expectPageKeys(m)
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadFilteredMultiCall, "default", "equal={d.hash=,cat="+pageHashC+"}", "d.hash=").
+		Return([][]any{{pageHashC}}, nil)
+
+	page, err := ds.Page(t.Context(), PageRequest{SortBy: DownloadFieldSizeBytes, Descending: true, Offset: 2, Limit: 1}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pageHashC}, hashesOf(t, page.Downloads), "descending, an unreadable size sorts first")
+	assert.NotEmpty(t, page.Next)
+
+	// Paging past the end makes no second request
+	expectPageKeys(m)
+	page, err = ds.Page(t.Context(), PageRequest{SortBy: DownloadFieldSizeBytes, Offset: 10}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, page.Downloads)
+	assert.Equal(t, 4, page.Total)
+}
+
+func TestDownloadService_PageErrors(t *testing.T) {
+	t.Parallel()
+
+	// None of these reach rTorrent
+	ds := &DownloadService{C: NewMockClient(gomock.NewController(t))}
+
+	_, err := ds.Page(t.Context(), PageRequest{SortBy: "bogus"}, nil)
+	require.ErrorIs(t, err, ErrUnknownField)
+	_, err = ds.Page(t.Context(), PageRequest{}, []DownloadField{"bogus"})
+	require.ErrorIs(t, err, ErrUnknownField)
+	_, err = ds.Page(t.Context(), PageRequest{Limit: -1}, nil)
+	require.ErrorIs(t, err, ErrBadData)
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=").Return([][]any{{pageHashA}}, nil)
+	_, err = (&DownloadService{C: m}).Page(t.Context(), PageRequest{After: "not a cursor"}, nil)
+	require.ErrorIs(t, err, ErrBadCursor)
+}
+
+func TestDownloadService_Download(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	ds := &DownloadService{C: m}
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadFilteredMultiCall, "default", "equal={d.hash=,cat="+pageHashA+"}",
+		"d.hash=", "d.name=").Return([][]any{{pageHashA, "a"}}, nil)
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadFilteredMultiCall, "default", "equal={d.hash=,cat="+pageHashB+"}",
+		"d.hash=", "d.name=").Return(nil, nil)
+
+	d, err := ds.Download(t.Context(), pageHashA, []DownloadField{DownloadFieldName})
+	require.NoError(t, err)
+	name, err := d.Name()
+	require.NoError(t, err)
+	assert.Equal(t, "a", name)
+
+	// A hash given in lower case is asked for in the upper case rTorrent has it in
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadFilteredMultiCall, "default", "equal={d.hash=,cat="+pageHashA+"}",
+		"d.hash=").Return([][]any{{pageHashA}}, nil)
+	_, err = ds.Download(t.Context(), strings.ToLower(pageHashA), nil)
+	require.NoError(t, err)
+
+	_, err = ds.Download(t.Context(), pageHashB, []DownloadField{DownloadFieldName})
+	require.ErrorIs(t, err, ErrDownloadNotFound)
+
+	// A hash that would close the filter's brace and add a command of its own never reaches rTorrent
+	_, err = ds.Download(t.Context(), "X},execute.nothrow={sh,-c,touch /tmp/owned", []DownloadField{DownloadFieldName})
+	require.ErrorIs(t, err, ErrBadData)
+	_, err = ds.Download(t.Context(), strings.Repeat("G", 40), nil)
+	require.ErrorIs(t, err, ErrBadData)
+}
+
+func TestSortDownloads(t *testing.T) {
+	t.Parallel()
+
+	downloads := []*Download{
+		NewDownload(map[DownloadField]any{DownloadFieldHash: pageHashC, DownloadFieldName: "b", DownloadFieldComplete: int64(1)}),
+		NewDownload(map[DownloadField]any{DownloadFieldHash: pageHashA, DownloadFieldName: "b", DownloadFieldComplete: int64(0)}),
+		NewDownload(map[DownloadField]any{DownloadFieldHash: pageHashB, DownloadFieldName: "a", DownloadFieldComplete: int64(1)}),
+	}
+
+	require.NoError(t, SortDownloads(downloads, DownloadFieldName, false))
+	assert.Equal(t, []string{pageHashB, pageHashA, pageHashC}, hashesOf(t, downloads))
+
+	require.NoError(t, SortDownloads(downloads, DownloadFieldComplete, true))
+	assert.Equal(t, []string{pageHashB, pageHashC, pageHashA}, hashesOf(t, downloads))
+
+	require.ErrorIs(t, SortDownloads(downloads, "bogus", false), ErrUnknownField)
+	require.ErrorIs(t, SortDownloads([]*Download{NewDownload(nil)}, DownloadFieldName, false), ErrNoField)
+}
@@ -0,0 +1,156 @@
+package rtorrent
+
+import (
+	"context"
+	"slices"
+	"strconv"
+)
+
+// peerListMultiCall retrieves fields of each of a download's connected peers
+const peerListMultiCall = "p.multicall"
+
+// XMLRPC Peer Fields
+const (
+	PeerFieldID               = PeerField("id")
+	PeerFieldAddress          = PeerField("address")
+	PeerFieldPort             = PeerField("port")
+	PeerFieldClientVersion    = PeerField("client_version")
+	PeerFieldCompletedPercent = PeerField("completed_percent")
+	PeerFieldDownRate         = PeerField("down_rate")
+	PeerFieldUpRate           = PeerField("up_rate")
+	PeerFieldDownTotal        = PeerField("down_total")
+	PeerFieldUpTotal          = PeerField("up_total")
+	PeerFieldIsEncrypted      = PeerField("is_encrypted")
+	PeerFieldIsIncoming       = PeerField("is_incoming")
+)
+
+// peerFields does for peers what downloadFields does for downloads
+var peerFields = NewFieldSet(
+	NewField(PrefixPeer, PeerFieldID, stringFromAny, identity),
+	NewField(PrefixPeer, PeerFieldAddress, stringFromAny, identity),
+	NewField(PrefixPeer, PeerFieldPort, intFromAny, strconv.Itoa),
+	NewField(PrefixPeer, PeerFieldClientVersion, stringFromAny, identity),
+	NewField(PrefixPeer, PeerFieldCompletedPercent, intFromAny, strconv.Itoa),
+	NewField(PrefixPeer, PeerFieldDownRate, intFromAny, strconv.Itoa),
+	NewField(PrefixPeer, PeerFieldUpRate, intFromAny, strconv.Itoa),
+	NewField(PrefixPeer, PeerFieldDownTotal, intFromAny, strconv.Itoa),
+	NewField(PrefixPeer, PeerFieldUpTotal, intFromAny, strconv.Itoa),
+	NewField(PrefixPeer, PeerFieldIsEncrypted, boolFromAny, strconv.FormatBool),
+	NewField(PrefixPeer, PeerFieldIsIncoming, boolFromAny, strconv.FormatBool),
+)
+
+// AllPeerFields returns every retrievable peer field, sorted, in a fresh slice each call.
+func AllPeerFields() []PeerField {
+	return peerFields.Names()
+}
+
+// PeerFields returns the peer fields as a FieldSet, as TrackerFields does for trackers.
+func PeerFields() *FieldSet[PeerField] {
+	return peerFields
+}
+
+// PeerField is used to specify peer related fields that can be retrieved from rTorrent
+type PeerField string
+
+func (pf PeerField) AsXMLRPCArgument() string {
+	return PrefixPeer + string(pf) + "="
+}
+
+func (pf PeerField) String() string {
+	return string(pf)
+}
+
+// Peer is a peer a download is connected to. Like a Download, it only holds the fields it was retrieved with.
+type Peer struct {
+	infoHash string
+	pData    Record[PeerField]
+}
+
+// NewPeer builds a Peer from data already gathered, for a peer of the download with the given info-hash.
+func NewPeer(infoHash string, data map[PeerField]any) *Peer {
+	return &Peer{infoHash: infoHash, pData: data}
+}
+
+// InfoHash Returns the info-hash of the download the peer is connected for.
+func (p *Peer) InfoHash() string {
+	return p.infoHash
+}
+
+// ID Returns the peer's ID, in hex.
+func (p *Peer) ID() (string, error) {
+	return RecordValue(p.pData, PeerFieldID, stringFromAny)
+}
+
+// Address Returns the peer's IP address.
+func (p *Peer) Address() (string, error) {
+	return RecordValue(p.pData, PeerFieldAddress, stringFromAny)
+}
+
+// Port Returns the peer's port.
+func (p *Peer) Port() (int, error) {
+	return RecordValue(p.pData, PeerFieldPort, intFromAny)
+}
+
+// ClientVersion Returns the name and version of the peer's BitTorrent client, as worked out from its ID.
+func (p *Peer) ClientVersion() (string, error) {
+	return RecordValue(p.pData, PeerFieldClientVersion, stringFromAny)
+}
+
+// CompletedPercent Returns how much of the download the peer has, from 0 to 100.
+func (p *Peer) CompletedPercent() (int, error) {
+	return RecordValue(p.pData, PeerFieldCompletedPercent, intFromAny)
+}
+
+// DownRate Returns how fast we are downloading from the peer, in bytes per second.
+func (p *Peer) DownRate() (int, error) {
+	return RecordValue(p.pData, PeerFieldDownRate, intFromAny)
+}
+
+// UpRate Returns how fast we are uploading to the peer, in bytes per second.
+func (p *Peer) UpRate() (int, error) {
+	return RecordValue(p.pData, PeerFieldUpRate, intFromAny)
+}
+
+// DownTotal Returns how many bytes we have downloaded from the peer.
+func (p *Peer) DownTotal() (int, error) {
+	return RecordValue(p.pData, PeerFieldDownTotal, intFromAny)
+}
+
+// UpTotal Returns how many bytes we have uploaded to the peer.
+func (p *Peer) UpTotal() (int, error) {
+	return RecordValue(p.pData, PeerFieldUpTotal, intFromAny)
+}
+
+// IsEncrypted Returns true if the connection to the peer is encrypted.
+func (p *Peer) IsEncrypted() (bool, error) {
+	return RecordValue(p.pData, PeerFieldIsEncrypted, boolFromAny)
+}
+
+// IsIncoming Returns true if the peer connected to us, rather than us to it.
+func (p *Peer) IsIncoming() (bool, error) {
+	return RecordValue(p.pData, PeerFieldIsIncoming, boolFromAny)
+}
+
+// A PeerService is a wrapper for Client methods which operate on a download's peers.
+type PeerService struct {
+	C Client
+}
+
+// Peers retrieves the peers a download is connected to along with the requested fields, or every peer field if fields
+// is empty, in a single request.
+func (s *PeerService) Peers(ctx context.Context, infoHash string, fields []PeerField) ([]*Peer, error) {
+	if len(fields) == 0 {
+		fields = AllPeerFields()
+	}
+	args, err := peerFields.Arguments(fields)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.C.getSliceSliceByHash(ctx, peerListMultiCall, slices.Concat([]string{infoHash}, args)...)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMulticall(peerFields, fields, rows, func(_ int, r Record[PeerField]) *Peer {
+		return &Peer{infoHash: infoHash, pData: r}
+	})
+}
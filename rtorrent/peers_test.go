@@ -0,0 +1,50 @@
+package rtorrent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPeerServicePeers(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSliceByHash(gomock.Any(), peerListMultiCall, testInfoHash, "p.address=", "p.port=", "p.is_incoming=").
+		Return([][]any{{"192.0.2.1", int64(51413), int64(1)}}, nil)
+
+	peers, err := (&PeerService{C: m}).Peers(t.Context(), testInfoHash,
+		[]PeerField{PeerFieldAddress, PeerFieldPort, PeerFieldIsIncoming})
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	p := peers[0]
+	assert.Equal(t, testInfoHash, p.InfoHash())
+	addr, err := p.Address()
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", addr)
+	port, err := p.Port()
+	require.NoError(t, err)
+	assert.Equal(t, 51413, port)
+	incoming, err := p.IsIncoming()
+	require.NoError(t, err)
+	assert.True(t, incoming)
+
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal(b, &rec))
+	assert.Equal(t, "192.0.2.1", rec["address"])
+	assert.Equal(t, testInfoHash, rec["info_hash"])
+	assert.Contains(t, rec, "client_version")
+	assert.Nil(t, rec["client_version"])
+}
+
+func TestAllPeerFields(t *testing.T) {
+	t.Parallel()
+
+	assert.Len(t, AllPeerFields(), 11)
+	assert.Equal(t, "p.down_rate=", PeerFieldDownRate.AsXMLRPCArgument())
+}
@@ -0,0 +1,204 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// A Pool groups the Clients for a fleet of rTorrent instances under names of the caller's choosing, so a question can
+// be put to all of them at once. Every query fans out concurrently, one goroutine per instance.
+type Pool struct {
+	clients map[string]Client
+	names   []string
+}
+
+// Tagged pairs a value with the name of the Pool instance it came from.
+type Tagged[T any] struct {
+	Instance string
+	Value    T
+}
+
+// A PoolError reports the instances that failed during a Pool query, keyed by name. Results from the instances that
+// succeeded are still returned alongside it, and errors.Is and errors.As see through to every instance's error.
+type PoolError struct {
+	Errors map[string]error
+}
+
+func (e *PoolError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d of the pool's instances failed", len(e.Errors))
+	for _, name := range slices.Sorted(maps.Keys(e.Errors)) {
+		fmt.Fprintf(&sb, "; %s: %v", name, e.Errors[name])
+	}
+	return sb.String()
+}
+
+// Unwrap returns every instance's error, ordered by instance name.
+func (e *PoolError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, name := range slices.Sorted(maps.Keys(e.Errors)) {
+		errs = append(errs, e.Errors[name])
+	}
+	return errs
+}
+
+// NewPool creates a Pool from Clients keyed by instance name. The map is copied, so later changes to it have no effect.
+func NewPool(clients map[string]Client) *Pool {
+	return &Pool{clients: maps.Clone(clients), names: slices.Sorted(maps.Keys(clients))}
+}
+
+// Names returns the pool's instance names, sorted.
+func (p *Pool) Names() []string {
+	return slices.Clone(p.names)
+}
+
+// Client returns the Client for the named instance.
+func (p *Pool) Client(name string) (Client, bool) {
+	c, ok := p.clients[name]
+	return c, ok
+}
+
+// Close closes every Client in the pool, joining any errors.
+func (p *Pool) Close() error {
+	errs := make([]error, 0, len(p.names))
+	for _, name := range p.names {
+		if err := p.clients[name].Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FanOut runs fn against every instance in p concurrently, returning the results tagged with their instance and sorted
+// by instance name. If any instance fails, the others' results are returned along with a *PoolError. If ctx is done
+// before every instance has answered, FanOut returns straight away with ctx's error. fn is given ctx, but the client
+// only heeds it while a call waits on a limiter, so calls already sent to rTorrent finish in the background.
+func FanOut[T any](
+	ctx context.Context, p *Pool, fn func(ctx context.Context, instance string, c Client) (T, error),
+) ([]Tagged[T], error) {
+	type result struct {
+		name  string
+		value T
+		err   error
+	}
+
+	// Buffered so that every goroutine can hand off its result and exit, even once we've bailed out on ctx.Done()
+	results := make(chan result, len(p.names))
+	for _, name := range p.names {
+		go func() {
+			v, err := fn(ctx, name, p.clients[name])
+			results <- result{name, v, err}
+		}()
+	}
+
+	tagged := make([]Tagged[T], 0, len(p.names))
+	failed := make(map[string]error)
+	for range p.names {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case r := <-results:
+			if r.err != nil {
+				failed[r.name] = r.err
+				continue
+			}
+			tagged = append(tagged, Tagged[T]{Instance: r.name, Value: r.value})
+		}
+	}
+
+	slices.SortFunc(tagged, func(a, b Tagged[T]) int { return strings.Compare(a.Instance, b.Instance) })
+	if len(failed) > 0 {
+		return tagged, &PoolError{Errors: failed}
+	}
+	return tagged, nil
+}
+
+// FanOutDownloads runs fn against a DownloadService for every instance in p, as FanOut does.
+func FanOutDownloads[T any](
+	ctx context.Context, p *Pool, fn func(ctx context.Context, ds *DownloadService) (T, error),
+) ([]Tagged[T], error) {
+	return FanOut(ctx, p, func(ctx context.Context, _ string, c Client) (T, error) {
+		return fn(ctx, &DownloadService{C: c})
+	})
+}
+
+// FanOutTrackers runs fn against a TrackerService for every instance in p, as FanOut does.
+func FanOutTrackers[T any](
+	ctx context.Context, p *Pool, fn func(ctx context.Context, ts *TrackerService) (T, error),
+) ([]Tagged[T], error) {
+	return FanOut(ctx, p, func(ctx context.Context, _ string, c Client) (T, error) {
+		return fn(ctx, &TrackerService{C: c})
+	})
+}
+
+// Downloads lists every download across the pool, each tagged with the instance it's loaded on.
+func (p *Pool) Downloads(ctx context.Context) ([]Tagged[string], error) {
+	lists, err := FanOutDownloads(ctx, p, func(ctx context.Context, ds *DownloadService) ([]string, error) {
+		return ds.List(ctx, "")
+	})
+
+	var downloads []Tagged[string]
+	for _, list := range lists {
+		for _, hash := range list.Value {
+			downloads = append(downloads, Tagged[string]{Instance: list.Instance, Value: hash})
+		}
+	}
+	return downloads, err
+}
+
+// Locate returns the names of the instances that have the download with the given info-hash loaded, sorted. It
+// returns ErrDownloadNotFound if none of them do, unless some instances failed to answer, in which case the download
+// may well be on one of those and the *PoolError is returned instead.
+func (p *Pool) Locate(ctx context.Context, infoHash string) ([]string, error) {
+	downloads, err := p.Downloads(ctx)
+
+	var found []string
+	for _, d := range downloads {
+		if strings.EqualFold(d.Value, infoHash) {
+			found = append(found, d.Instance)
+		}
+	}
+	if len(found) == 0 && err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDownloadNotFound, infoHash)
+	}
+	return found, err
+}
+
+// DownloadRate sums the current download rate in bytes across the pool. If some instances fail, the sum of the rest is
+// returned along with a *PoolError.
+func (p *Pool) DownloadRate(ctx context.Context) (int, error) {
+	return p.sum(ctx, "down.rate")
+}
+
+// UploadRate sums the current upload rate in bytes across the pool, as DownloadRate does.
+func (p *Pool) UploadRate(ctx context.Context) (int, error) {
+	return p.sum(ctx, "up.rate")
+}
+
+// DownloadTotal sums the bytes downloaded since each instance's startup across the pool, as DownloadRate does.
+func (p *Pool) DownloadTotal(ctx context.Context) (int, error) {
+	return p.sum(ctx, "down.total")
+}
+
+// UploadTotal sums the bytes uploaded since each instance's startup across the pool, as DownloadRate does.
+func (p *Pool) UploadTotal(ctx context.Context) (int, error) {
+	return p.sum(ctx, "up.total")
+}
+
+// sum adds up a global counter across the pool, reading it with the fan-out's context so that cancelling it stops the
+// calls still waiting on a limiter, though not those already sent
+func (p *Pool) sum(ctx context.Context, method string) (int, error) {
+	values, err := FanOut(ctx, p, func(ctx context.Context, _ string, c Client) (int, error) {
+		return c.getInt(ctx, method, "")
+	})
+
+	total := 0
+	for _, v := range values {
+		total += v.Value
+	}
+	return total, err
+}
@@ -0,0 +1,171 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var errInstanceDown = errors.New("instance down")
+
+// testPool builds a Pool of mock clients, one per name
+func testPool(t *testing.T, names ...string) (*Pool, map[string]*MockClient) {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	mocks := make(map[string]*MockClient, len(names))
+	clients := make(map[string]Client, len(names))
+	for _, name := range names {
+		mocks[name] = NewMockClient(ctrl)
+		clients[name] = mocks[name]
+	}
+	return NewPool(clients), mocks
+}
+
+func TestPoolNames(t *testing.T) {
+	t.Parallel()
+
+	p, _ := testPool(t, "seedbox", "attic", "nas")
+	assert.Equal(t, []string{"attic", "nas", "seedbox"}, p.Names())
+
+	c, ok := p.Client("nas")
+	assert.True(t, ok)
+	assert.NotNil(t, c)
+	_, ok = p.Client("nope")
+	assert.False(t, ok)
+}
+
+func TestPoolDownloadsAreTagged(t *testing.T) {
+	t.Parallel()
+
+	// The calls are made with the caller's context, or one derived from it, so cancelling it reaches them
+	type ctxKey struct{}
+	ctx := context.WithValue(t.Context(), ctxKey{}, "caller")
+	fromCaller := gomock.Cond(func(ctx context.Context) bool { return ctx.Value(ctxKey{}) == "caller" })
+
+	p, mocks := testPool(t, "b", "a")
+	mocks["a"].EXPECT().getStringSlice(fromCaller, downloadList).Return(testDownloads[:2], nil)
+	mocks["b"].EXPECT().getStringSlice(fromCaller, downloadList).Return(testDownloads[2:], nil)
+
+	got, err := p.Downloads(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Tagged[string]{
+		{Instance: "a", Value: testDownloads[0]},
+		{Instance: "a", Value: testDownloads[1]},
+		{Instance: "b", Value: testDownloads[2]},
+	}, got)
+}
+
+func TestPoolPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	p, mocks := testPool(t, "a", "b", "c")
+	mocks["a"].EXPECT().getInt(gomock.Any(), "down.rate", "").Return(100, nil)
+	mocks["b"].EXPECT().getInt(gomock.Any(), "down.rate", "").Return(0, errInstanceDown)
+	mocks["c"].EXPECT().getInt(gomock.Any(), "down.rate", "").Return(20, nil)
+
+	total, err := p.DownloadRate(t.Context())
+	assert.Equal(t, 120, total, "the instances that answered are still summed")
+
+	var poolErr *PoolError
+	require.ErrorAs(t, err, &poolErr)
+	assert.Equal(t, map[string]error{"b": errInstanceDown}, poolErr.Errors)
+	require.ErrorIs(t, err, errInstanceDown)
+	assert.Contains(t, err.Error(), "b: instance down")
+}
+
+func TestPoolLocate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("found on several instances", func(t *testing.T) {
+		t.Parallel()
+
+		p, mocks := testPool(t, "a", "b", "c")
+		mocks["a"].EXPECT().getStringSlice(gomock.Any(), downloadList).Return(testDownloads, nil)
+		mocks["b"].EXPECT().getStringSlice(gomock.Any(), downloadList).Return(testDownloads[1:], nil)
+		mocks["c"].EXPECT().getStringSlice(gomock.Any(), downloadList).Return(testDownloads, nil)
+
+		got, err := p.Locate(t.Context(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "c"}, got)
+	})
+
+	t.Run("not found anywhere", func(t *testing.T) {
+		t.Parallel()
+
+		p, mocks := testPool(t, "a")
+		mocks["a"].EXPECT().getStringSlice(gomock.Any(), downloadList).Return(testDownloads[1:], nil)
+
+		got, err := p.Locate(t.Context(), testInfoHash)
+		require.ErrorIs(t, err, ErrDownloadNotFound)
+		assert.Empty(t, got)
+	})
+
+	t.Run("not found but an instance failed", func(t *testing.T) {
+		t.Parallel()
+
+		p, mocks := testPool(t, "a", "b")
+		mocks["a"].EXPECT().getStringSlice(gomock.Any(), downloadList).Return(testDownloads[1:], nil)
+		mocks["b"].EXPECT().getStringSlice(gomock.Any(), downloadList).Return(nil, errInstanceDown)
+
+		got, err := p.Locate(t.Context(), testInfoHash)
+		require.ErrorIs(t, err, errInstanceDown)
+		require.NotErrorIs(t, err, ErrDownloadNotFound, "it may be on the instance that didn't answer")
+		assert.Empty(t, got)
+	})
+}
+
+func TestFanOutTrackers(t *testing.T) {
+	t.Parallel()
+
+	p, mocks := testPool(t, "a", "b")
+	for _, m := range mocks {
+		m.EXPECT().getSliceSliceByHash(gomock.Any(), trackerListMultiCall, testInfoHash, FieldURL.AsXMLRPCArgument()).
+			Return([][]any{{testURL}}, nil)
+	}
+
+	got, err := FanOutTrackers(t.Context(), p, func(ctx context.Context, ts *TrackerService) (int, error) {
+		trackers, err := ts.TrackerWithDetails(ctx, NewTrackerNoIndex(testInfoHash), []TrackerField{FieldURL})
+		return len(trackers), err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Tagged[int]{{Instance: "a", Value: 1}, {Instance: "b", Value: 1}}, got)
+}
+
+func TestFanOutStopsWaitingOnContext(t *testing.T) {
+	t.Parallel()
+
+	p, _ := testPool(t, "a")
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	got, err := FanOut(ctx, p, func(context.Context, string, Client) (int, error) {
+		defer close(done)
+		<-release
+		return 1, nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, got)
+
+	close(release)
+	<-done
+}
+
+func TestPoolClose(t *testing.T) {
+	t.Parallel()
+
+	p, mocks := testPool(t, "a", "b")
+	mocks["a"].EXPECT().Close().Return(nil)
+	mocks["b"].EXPECT().Close().Return(errInstanceDown)
+
+	err := p.Close()
+	require.ErrorIs(t, err, errInstanceDown)
+	assert.Contains(t, err.Error(), "closing b")
+}
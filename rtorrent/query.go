@@ -0,0 +1,324 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBadQuery is matched by every QueryError.
+	ErrBadQuery = errors.New("bad query")
+	// ErrNoViewFilter is returned by FilterView for a query rTorrent's view.filter can't express.
+	ErrNoViewFilter = errors.New("query has no view filter")
+)
+
+// filterValueRejects are the characters a string can't contain and still be passed to rTorrent inside a filter
+const filterValueRejects = `,{}"\;`
+
+// queryNode is a parsed query, or a part of one
+type queryNode interface {
+	match(d *Download) (bool, error)
+	// filter gives the node as a view.filter expression, if it has one
+	filter() (string, bool)
+	fields(add func(DownloadField))
+}
+
+// Query is a parsed condition on downloads, made by ParseQuery. It can be evaluated against downloads we already
+// have, or, when it only uses what rTorrent can check itself, handed to rTorrent as a view filter.
+type Query struct {
+	expr   string
+	root   queryNode
+	needed []DownloadField
+}
+
+// ParseQuery parses and type checks a query such as:
+//
+//	ratio > 1.5 and label = "tv" and not is_complete
+//
+// Conditions compare a download field, named as in AllDownloadFields or by its d. command, with a value. Text fields
+// take quoted text and =, != or contains, numbers and timestamps take any of =, !=, <, <=, > and >= with a number,
+// timestamps being in Unix seconds, and fields that are true or false take = or != with true or false, or can stand on
+// their own. A few fields can be given by friendlier names: label for custom1, is_complete, is_started, size and
+// finished. Conditions are combined with not, and and or, in that order of precedence, and grouped with parentheses.
+//
+// A query that doesn't parse, or compares a field with the wrong kind of value, gives a *QueryError saying where.
+func ParseQuery(expr string) (*Query, error) {
+	toks, err := lexQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 1 {
+		return nil, &QueryError{Query: expr, Msg: "the query is empty"}
+	}
+	p := &queryParser{expr: expr, toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t.pos, "unexpected %s after a complete condition, join conditions with and or or", t.describe())
+	}
+
+	q := &Query{expr: expr, root: root}
+	root.fields(func(f DownloadField) {
+		if !slices.Contains(q.needed, f) {
+			q.needed = append(q.needed, f)
+		}
+	})
+	return q, nil
+}
+
+// String returns the query as it was written.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Fields returns the download fields the query needs to be matched against a download, in the order it uses them.
+func (q *Query) Fields() []DownloadField {
+	return slices.Clone(q.needed)
+}
+
+// Match Returns true if d, which needs the fields Fields lists, meets the query.
+func (q *Query) Match(d *Download) (bool, error) {
+	return q.root.match(d)
+}
+
+// ViewFilter Returns the query as an expression for rTorrent's view.filter, and false if it can't be written as one,
+// as when it searches text with contains or compares with a fraction of anything but a ratio.
+func (q *Query) ViewFilter() (string, bool) {
+	return q.root.filter()
+}
+
+// Query retrieves the downloads in the default view that meet the query expr (see ParseQuery), with the requested
+// fields along with the ones the query needs. The query is evaluated here rather than by rTorrent, so it can use
+// anything ParseQuery accepts; FilterView has rTorrent do the filtering instead.
+func (s *DownloadService) Query(ctx context.Context, expr string, fields []DownloadField) ([]*Download, error) {
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	all := slices.Clone(fields)
+	for _, f := range q.needed {
+		if !slices.Contains(all, f) {
+			all = append(all, f)
+		}
+	}
+
+	downloads, err := s.Downloads(ctx, "", all)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*Download
+	for _, d := range downloads {
+		ok, err := q.Match(d)
+		if err != nil {
+			hash, _ := d.Hash()
+			return matched, fmt.Errorf("%s: %w", hash, err)
+		}
+		if ok {
+			matched = append(matched, d)
+		}
+	}
+	return matched, nil
+}
+
+// FilterView sets the filter of view, which must already exist, to q, so that rTorrent only lists the downloads that
+// meet it there. rTorrent applies the filter as downloads change, as well as whenever view.filter is called. It gives
+// ErrNoViewFilter for a query ViewFilter can't express.
+func (s *DownloadService) FilterView(ctx context.Context, view string, q *Query) error {
+	filter, ok := q.ViewFilter()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoViewFilter, q)
+	}
+	return s.C.execute(ctx, "view.filter", "", view, filter)
+}
+
+type andNode struct {
+	left, right queryNode
+}
+
+func (n *andNode) match(d *Download) (bool, error) {
+	ok, err := n.left.match(d)
+	if err != nil || !ok {
+		return false, err
+	}
+	return n.right.match(d)
+}
+
+func (n *andNode) filter() (string, bool) {
+	return joinFilters(kwAnd, n.left, n.right)
+}
+
+func (n *andNode) fields(add func(DownloadField)) {
+	n.left.fields(add)
+	n.right.fields(add)
+}
+
+type orNode struct {
+	left, right queryNode
+}
+
+func (n *orNode) match(d *Download) (bool, error) {
+	ok, err := n.left.match(d)
+	if err != nil || ok {
+		return ok, err
+	}
+	return n.right.match(d)
+}
+
+func (n *orNode) filter() (string, bool) {
+	return joinFilters(kwOr, n.left, n.right)
+}
+
+func (n *orNode) fields(add func(DownloadField)) {
+	n.left.fields(add)
+	n.right.fields(add)
+}
+
+type notNode struct {
+	x queryNode
+}
+
+func (n *notNode) match(d *Download) (bool, error) {
+	ok, err := n.x.match(d)
+	return !ok && err == nil, err
+}
+
+func (n *notNode) filter() (string, bool) {
+	f, ok := n.x.filter()
+	if !ok {
+		return "", false
+	}
+	return "not={" + f + "}", true
+}
+
+func (n *notNode) fields(add func(DownloadField)) {
+	n.x.fields(add)
+}
+
+// joinFilters joins the filters of two nodes with one of rTorrent's and and or commands
+func joinFilters(cmd string, left, right queryNode) (string, bool) {
+	l, ok := left.filter()
+	if !ok {
+		return "", false
+	}
+	r, ok := right.filter()
+	if !ok {
+		return "", false
+	}
+	return cmd + "={" + l + "," + r + "}", true
+}
+
+// compareNode compares a field with a value, which is in whichever of text, b or num suits the field's type
+type compareNode struct {
+	field Field[DownloadField]
+	typ   queryType
+	op    string
+	text  string
+	b     bool
+	num   float64
+}
+
+func (n *compareNode) match(d *Download) (bool, error) {
+	v, err := n.field.Value(d.dData)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", n.field.Name(), err)
+	}
+	switch v := v.(type) {
+	case string:
+		switch n.op {
+		case kwContains:
+			return strings.Contains(v, n.text), nil
+		case "!=":
+			return v != n.text, nil
+		default:
+			return v == n.text, nil
+		}
+	case bool:
+		return (v == n.b) == (n.op == "="), nil
+	case int:
+		return compareNumbers(float64(v), n.op, n.num), nil
+	case float64:
+		return compareNumbers(v, n.op, n.num), nil
+	case time.Time:
+		return compareNumbers(float64(v.Unix()), n.op, n.num), nil
+	default:
+		return false, fmt.Errorf("%w: cannot compare %T", ErrBadData, v)
+	}
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	default:
+		return a == b
+	}
+}
+
+// filter writes the comparison in rTorrent's own terms, which only has equal, greater and less, compares whole
+// numbers, and reports ratios multiplied by ratioScale
+func (n *compareNode) filter() (string, bool) {
+	cmd := n.field.AsXMLRPCArgument()
+	switch n.typ {
+	case queryBool:
+		if n.b == (n.op == "=") {
+			return cmd, true
+		}
+		return "not={" + cmd + "}", true
+	case queryText:
+		if n.op == kwContains || strings.ContainsAny(n.text, filterValueRejects) {
+			return "", false
+		}
+		return filterComparison(cmd, n.op, "cat="+n.text), true
+	case queryNumber, queryTime:
+		num := n.num
+		if n.field.Name() == DownloadFieldRatio {
+			num *= ratioScale
+		}
+		// rTorrent would compare a fraction against a whole number, so those are left for Match
+		if num != math.Trunc(num) {
+			return "", false
+		}
+		return filterComparison(cmd, n.op, "value="+strconv.FormatFloat(num, 'f', -1, 64)), true
+	default:
+		return "", false
+	}
+}
+
+// filterComparison writes the comparison of the command cmd with value using rTorrent's equal, greater and less
+func filterComparison(cmd, op, value string) string {
+	args := "{" + cmd + "," + value + "}"
+	switch op {
+	case "!=":
+		return "not={equal=" + args + "}"
+	case ">":
+		return "greater=" + args
+	case ">=":
+		return "not={less=" + args + "}"
+	case "<":
+		return "less=" + args
+	case "<=":
+		return "not={greater=" + args + "}"
+	default:
+		return "equal=" + args
+	}
+}
+
+func (n *compareNode) fields(add func(DownloadField)) {
+	add(n.field.Name())
+}
@@ -0,0 +1,378 @@
+package rtorrent
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query tokens
+const (
+	tokEOF queryTokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+// Query keywords, which are matched without regard to case
+const (
+	kwAnd      = "and"
+	kwOr       = "or"
+	kwNot      = "not"
+	kwContains = "contains"
+	kwTrue     = "true"
+	kwFalse    = "false"
+)
+
+// Types of value a query can compare a field with
+const (
+	queryText queryType = iota
+	queryBool
+	queryNumber
+	queryTime
+)
+
+// queryAliases are the names a query may use for download fields besides their own
+var queryAliases = map[string]DownloadField{
+	"label":       DownloadFieldCustom1,
+	"is_complete": DownloadFieldComplete,
+	"is_started":  DownloadFieldState,
+	"size":        DownloadFieldSizeBytes,
+	"finished":    DownloadFieldFinished,
+}
+
+// queryOps are the comparison operators, with the spellings each one may be written with
+var queryOps = map[string]string{
+	"=": "=", "==": "=", "!=": "!=", ">": ">", ">=": ">=", "<": "<", "<=": "<=",
+}
+
+type queryTokenKind int
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+// describe names the token in an error message
+func (t queryToken) describe() string {
+	if t.kind == tokEOF {
+		return "the end of the query"
+	}
+	return strconv.Quote(t.text)
+}
+
+// keyword Returns the token's text as a keyword, which are matched without regard to case
+func (t queryToken) keyword() string {
+	if t.kind != tokIdent {
+		return ""
+	}
+	return strings.ToLower(t.text)
+}
+
+type queryType int
+
+// String names the type in an error message
+func (qt queryType) String() string {
+	switch qt {
+	case queryText:
+		return "text"
+	case queryBool:
+		return "true or false"
+	case queryNumber:
+		return "a number"
+	case queryTime:
+		return "a timestamp"
+	default:
+		return unknownStr
+	}
+}
+
+// queryTypeOf works out how a query compares values of a field converted to typ
+func queryTypeOf(typ reflect.Type) (queryType, bool) {
+	if typ == reflect.TypeFor[time.Time]() {
+		return queryTime, true
+	}
+	switch typ.Kind() { //nolint:exhaustive // every other kind of value has no comparisons
+	case reflect.String:
+		return queryText, true
+	case reflect.Bool:
+		return queryBool, true
+	case reflect.Int, reflect.Float64:
+		return queryNumber, true
+	default:
+		return 0, false
+	}
+}
+
+// QueryError is a query that couldn't be parsed, along with where in it the problem was. It matches ErrBadQuery.
+type QueryError struct {
+	Query string
+	// Pos is the byte offset in Query of the problem
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d: %s", ErrBadQuery, e.Pos+1, e.Msg)
+}
+
+func (e *QueryError) Unwrap() error {
+	return ErrBadQuery
+}
+
+// lexQuery splits a query into tokens
+func lexQuery(expr string) ([]queryToken, error) {
+	var toks []queryToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, queryToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			toks = append(toks, queryToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, &QueryError{Query: expr, Pos: i, Msg: "text is missing its closing quote"}
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, &QueryError{Query: expr, Pos: i, Msg: "text has a bad escape in it"}
+			}
+			toks = append(toks, queryToken{kind: tokString, text: s, pos: i})
+			i = end + 1
+		case isQueryDigit(c) || (c == '-' && i+1 < len(expr) && isQueryDigit(expr[i+1])):
+			end := i + 1
+			for end < len(expr) && (isQueryDigit(expr[end]) || expr[end] == '.') {
+				end++
+			}
+			toks = append(toks, queryToken{kind: tokNumber, text: expr[i:end], pos: i})
+			i = end
+		case isQueryLetter(c):
+			end := i + 1
+			for end < len(expr) && (isQueryLetter(expr[end]) || isQueryDigit(expr[end]) || expr[end] == '.') {
+				end++
+			}
+			toks = append(toks, queryToken{kind: tokIdent, text: expr[i:end], pos: i})
+			i = end
+		case strings.ContainsRune("=!<>&|", rune(c)):
+			end := i + 1
+			if end < len(expr) && strings.ContainsRune("=&|", rune(expr[end])) {
+				end++
+			}
+			toks = append(toks, queryToken{kind: tokOp, text: expr[i:end], pos: i})
+			i = end
+		default:
+			return nil, &QueryError{Query: expr, Pos: i, Msg: fmt.Sprintf("unexpected %q", c)}
+		}
+	}
+	return append(toks, queryToken{kind: tokEOF, pos: len(expr)}), nil
+}
+
+func isQueryDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isQueryLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// queryParser is a recursive descent parser over a query's tokens, which are always ended by a tokEOF
+type queryParser struct {
+	expr string
+	toks []queryToken
+	i    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.toks[p.i]
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *queryParser) errorf(pos int, format string, args ...any) error {
+	return &QueryError{Query: p.expr, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses conditions joined by or, which binds more loosely than and
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.keyword() == kwOr || (t.kind == tokOp && t.text == "||"); t = p.peek() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.keyword() == kwAnd || (t.kind == tokOp && t.text == "&&"); t = p.peek() {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if t := p.peek(); t.keyword() == kwNot || (t.kind == tokOp && t.text == "!") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing.pos, "expected ) to close the ( at position %d, found %s", t.pos+1, closing.describe())
+		}
+		return x, nil
+	case t.kind == tokIdent && !isQueryKeyword(t.keyword()):
+		return p.parseCondition(t)
+	default:
+		return nil, p.errorf(t.pos, "expected a field, not or (, found %s", t.describe())
+	}
+}
+
+// parseCondition parses a comparison of the field named by name, or the field alone if it is true or false
+func (p *queryParser) parseCondition(name queryToken) (queryNode, error) {
+	field, typ, err := p.lookupField(name)
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.peek()
+	op, isOp := queryOps[opTok.text]
+	isOp = isOp && opTok.kind == tokOp
+	if !isOp && opTok.keyword() != kwContains {
+		if typ != queryBool {
+			return nil, p.errorf(opTok.pos, "%s is %s, so it needs comparing with something, such as %s %s",
+				name.text, typ, name.text, exampleComparison(typ))
+		}
+		return &compareNode{field: field, typ: typ, op: "=", b: true}, nil
+	}
+	p.next()
+	if !isOp {
+		op = kwContains
+	}
+
+	value := p.next()
+	c := &compareNode{field: field, typ: typ, op: op}
+	switch typ {
+	case queryText:
+		if value.kind != tokString {
+			return nil, p.errorf(value.pos, "%s is text, so it needs comparing with quoted text, found %s", name.text, value.describe())
+		}
+		if op != "=" && op != "!=" && op != kwContains {
+			return nil, p.errorf(opTok.pos, "%s is text, which can only be compared with =, != or contains", name.text)
+		}
+		c.text = value.text
+	case queryBool:
+		kw := value.keyword()
+		if kw != kwTrue && kw != kwFalse {
+			return nil, p.errorf(value.pos, "%s is true or false, found %s", name.text, value.describe())
+		}
+		if op != "=" && op != "!=" {
+			return nil, p.errorf(opTok.pos, "%s is true or false, which can only be compared with = or !=", name.text)
+		}
+		c.b = kw == kwTrue
+	case queryNumber, queryTime:
+		if value.kind != tokNumber {
+			return nil, p.errorf(value.pos, "%s is %s, so it needs comparing with a number, found %s", name.text, typ, value.describe())
+		}
+		if op == kwContains {
+			return nil, p.errorf(opTok.pos, "%s is %s, and only text can be searched with contains", name.text, typ)
+		}
+		n, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return nil, p.errorf(value.pos, "%s is not a number", value.describe())
+		}
+		c.num = n
+	default:
+	}
+	return c, nil
+}
+
+// lookupField finds the download field a query names, by its own name, its command or an alias
+func (p *queryParser) lookupField(name queryToken) (Field[DownloadField], queryType, error) {
+	key := DownloadField(strings.TrimPrefix(name.text, PrefixDownload))
+	if alias, ok := queryAliases[name.text]; ok {
+		key = alias
+	}
+	field, ok := downloadFields.Lookup(key)
+	if !ok {
+		return field, 0, p.errorf(name.pos, "%q is not a download field", name.text)
+	}
+	typ, ok := queryTypeOf(field.Type())
+	if !ok {
+		return field, 0, p.errorf(name.pos, "%s can't be used in a query", name.text)
+	}
+	return field, typ, nil
+}
+
+func isQueryKeyword(s string) bool {
+	switch s {
+	case kwAnd, kwOr, kwNot, kwContains, kwTrue, kwFalse:
+		return true
+	default:
+		return false
+	}
+}
+
+// exampleComparison gives a sensible comparison for a field of type typ in an error message
+func exampleComparison(typ queryType) string {
+	switch typ {
+	case queryText:
+		return `= "something"`
+	case queryNumber, queryTime:
+		return "> 0"
+	default:
+		return "= true"
+	}
+}
@@ -0,0 +1,182 @@
+package rtorrent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func queryDownload() *Download {
+	return NewDownload(map[DownloadField]any{
+		DownloadFieldHash:      testInfoHash,
+		DownloadFieldName:      "Some.Show.S01E01.1080p",
+		DownloadFieldRatio:     int64(1800),
+		DownloadFieldCustom1:   "tv",
+		DownloadFieldComplete:  int64(0),
+		DownloadFieldSizeBytes: int64(4096),
+		DownloadFieldFinished:  int64(0),
+	})
+}
+
+func TestParseQuery_Match(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`ratio > 1.5 and label = "tv" and not is_complete`, true},
+		{`ratio > 1.5 and label = "movies"`, false},
+		{`ratio >= 1.8 && ratio <= 1.8`, true},
+		{`ratio < 1 or size == 4096`, true},
+		{`!(ratio < 1 || size != 4096)`, true},
+		{`name contains "S01" AND d.custom1 != "movies"`, true},
+		{`is_complete = false`, true},
+		{`complete != false`, false},
+		{`finished > 0`, false},
+		{`not not is_complete`, false},
+		{`ratio > -1 and (label = "x" or label = "tv")`, true},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.expr)
+		require.NoError(t, err, tt.expr)
+		got, err := q.Match(queryDownload())
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.expected, got, tt.expr)
+		assert.Equal(t, tt.expr, q.String())
+	}
+
+	q, err := ParseQuery(`ratio > 1.5 and label = "tv" or ratio > 3`)
+	require.NoError(t, err)
+	assert.Equal(t, []DownloadField{DownloadFieldRatio, DownloadFieldCustom1}, q.Fields())
+
+	_, err = q.Match(NewDownload(nil))
+	require.ErrorIs(t, err, ErrNoField)
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{``, 0, "the query is empty"},
+		{`ratio >`, 7, `ratio is a number, so it needs comparing with a number, found the end of the query`},
+		{`ratio > 1.5 label = "tv"`, 12, `unexpected "label" after a complete condition, join conditions with and or or`},
+		{`lable = "tv"`, 0, `"lable" is not a download field`},
+		{`label > "tv"`, 6, `label is text, which can only be compared with =, != or contains`},
+		{`label = tv`, 8, `label is text, so it needs comparing with quoted text, found "tv"`},
+		{`ratio = "high"`, 8, `ratio is a number, so it needs comparing with a number, found "high"`},
+		{`ratio contains 1`, 6, `ratio is a number, and only text can be searched with contains`},
+		{`ratio`, 5, `ratio is a number, so it needs comparing with something, such as ratio > 0`},
+		{`is_complete = 1`, 14, `is_complete is true or false, found "1"`},
+		{`(ratio > 1`, 10, `expected ) to close the ( at position 1, found the end of the query`},
+		{`ratio > 1 and`, 13, `expected a field, not or (, found the end of the query`},
+		{`label = "tv`, 8, `text is missing its closing quote`},
+		{`ratio > 1.2.3`, 8, `"1.2.3" is not a number`},
+		{`ratio # 1`, 6, `unexpected '#'`},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.expr)
+		require.ErrorIs(t, err, ErrBadQuery, tt.expr)
+		var qe *QueryError
+		require.ErrorAs(t, err, &qe, tt.expr)
+		assert.Equal(t, tt.pos, qe.Pos, tt.expr)
+		assert.Equal(t, tt.msg, qe.Msg, tt.expr)
+	}
+
+	_, err := ParseQuery(`ratio >`)
+	assert.EqualError(t, err, "bad query at position 8: ratio is a number, so it needs comparing with a number, found the end of the query")
+}
+
+func TestQuery_ViewFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr     string
+		expected string
+		ok       bool
+	}{
+		{`ratio > 1.5 and label = "tv" and not is_complete`,
+			"and={and={greater={d.ratio=,value=1500},equal={d.custom1=,cat=tv}},not={d.complete=}}", true},
+		{`size <= 4096 or is_started = false`, "or={not={greater={d.size_bytes=,value=4096}},not={d.state=}}", true},
+		{`label != "tv" and size >= 1`, "and={not={equal={d.custom1=,cat=tv}},not={less={d.size_bytes=,value=1}}}", true},
+		{`finished < 1700000000`, "less={d.timestamp.finished=,value=1700000000}", true},
+		{`name contains "S01"`, "", false},
+		{`label = "a,b"`, "", false},
+		{`size > 1.5`, "", false},
+		{`ratio > 1.0005`, "", false},
+		{`is_complete or name contains "x"`, "", false},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.expr)
+		require.NoError(t, err, tt.expr)
+		got, ok := q.ViewFilter()
+		assert.Equal(t, tt.ok, ok, tt.expr)
+		if tt.ok {
+			assert.Equal(t, tt.expected, got, tt.expr)
+		}
+	}
+}
+
+func TestQuery_FractionalRatio(t *testing.T) {
+	t.Parallel()
+
+	// rTorrent has ratios in thousandths, so a finer one can't go in a filter without it and Match disagreeing on
+	// a download such as this one
+	q, err := ParseQuery(`ratio > 1.0005`)
+	require.NoError(t, err)
+	ok, err := q.Match(NewDownload(map[DownloadField]any{DownloadFieldRatio: int64(1001)}))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, ok = q.ViewFilter()
+	assert.False(t, ok, "the query is left to Match")
+
+	q, err = ParseQuery(`ratio > 1.25`)
+	require.NoError(t, err)
+	filter, ok := q.ViewFilter()
+	require.True(t, ok)
+	assert.Equal(t, "greater={d.ratio=,value=1250}", filter)
+}
+
+func TestDownloadService_Query(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.name=", "d.ratio=", "d.custom1=").
+		Return([][]any{
+			{testDownloads[0], "a", int64(2000), "tv"},
+			{testDownloads[1], "b", int64(500), "tv"},
+			{testDownloads[2], "c", int64(3000), "movies"},
+		}, nil)
+
+	got, err := (&DownloadService{C: m}).Query(t.Context(), `ratio > 1.5 and label = "tv"`, []DownloadField{DownloadFieldName})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	name, err := got[0].Name()
+	require.NoError(t, err)
+	assert.Equal(t, "a", name)
+
+	_, err = (&DownloadService{C: m}).Query(t.Context(), `ratio >`, nil)
+	require.ErrorIs(t, err, ErrBadQuery)
+}
+
+func TestDownloadService_FilterView(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().execute(gomock.Any(), "view.filter", "", "tv", "equal={d.custom1=,cat=tv}").Return(nil)
+	ds := &DownloadService{C: m}
+
+	q, err := ParseQuery(`label = "tv"`)
+	require.NoError(t, err)
+	require.NoError(t, ds.FilterView(t.Context(), "tv", q))
+
+	q, err = ParseQuery(`name contains "x"`)
+	require.NoError(t, err)
+	require.ErrorIs(t, ds.FilterView(t.Context(), "tv", q), ErrNoViewFilter)
+}
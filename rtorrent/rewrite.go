@@ -0,0 +1,189 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// rewriteFields are the tracker fields RewriteTrackers needs to work out what to change
+var rewriteFields = []TrackerField{FieldURL, FieldIsEnabled, FieldGroup}
+
+// TrackerRewrite is a tracker URL RewriteTrackers replaced, or would replace in a dry run.
+type TrackerRewrite struct {
+	InfoHash string
+	Group    int
+	OldURL   string
+	NewURL   string
+}
+
+// RewriteOption configures RewriteTrackers.
+type RewriteOption func(*rewriteConfig)
+
+type rewriteConfig struct {
+	dryRun bool
+}
+
+// WithRewriteDryRun makes RewriteTrackers report the rewrites it would make without making them.
+func WithRewriteDryRun() RewriteOption {
+	return func(c *rewriteConfig) {
+		c.dryRun = true
+	}
+}
+
+// RewriteTrackers replaces the enabled trackers, of every download in view or every download if view is "", whose
+// URL matches pattern with the URL pattern.ReplaceAllString makes of it using replacement, such as when a private
+// tracker changes its announce domain or passkey. rTorrent can't change a tracker's URL, so the new URL is inserted
+// with d.tracker.insert into the same group and the old tracker disabled, then the download's session is saved so the
+// change survives a restart. A download already announcing to the new URL only has the old one disabled.
+//
+// It returns the rewrites it made. A download that can't be rewritten doesn't stop the others, and comes back in the
+// error along with its info-hash, but an old tracker is only ever disabled once its replacement is in place.
+func (ts *TrackerService) RewriteTrackers(
+	ctx context.Context, view string, pattern *regexp.Regexp, replacement string, opts ...RewriteOption,
+) ([]TrackerRewrite, error) {
+	var cfg rewriteConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	byDownload, err := ts.TrackersByDownload(ctx, view, rewriteFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rewrites []TrackerRewrite
+		errs     []error
+	)
+	// We go through the downloads in a fixed order so that the rewrites, and the errors, come back the same each time
+	for _, hash := range slices.Sorted(maps.Keys(byDownload)) {
+		planned, err := planRewrites(hash, byDownload[hash], pattern, replacement)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hash, err))
+			continue
+		}
+		if len(planned) == 0 {
+			continue
+		}
+		if cfg.dryRun {
+			rewrites = append(rewrites, planned...)
+			continue
+		}
+
+		done, err := ts.rewriteDownload(ctx, hash, byDownload[hash], planned)
+		rewrites = append(rewrites, done...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hash, err))
+		}
+	}
+	return rewrites, errors.Join(errs...)
+}
+
+// planRewrites works out the rewrites a download's trackers need
+func planRewrites(hash string, trackers []*Tracker, pattern *regexp.Regexp, replacement string) ([]TrackerRewrite, error) {
+	var planned []TrackerRewrite
+	for _, t := range trackers {
+		u, err := t.URL()
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := t.IsEnabled()
+		if err != nil {
+			return nil, err
+		}
+		if !enabled || !pattern.MatchString(u) {
+			continue
+		}
+		newURL := pattern.ReplaceAllString(u, replacement)
+		if newURL == u {
+			continue
+		}
+		group, err := t.Group()
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, TrackerRewrite{InfoHash: hash, Group: group, OldURL: u, NewURL: newURL})
+	}
+	return planned, nil
+}
+
+// rewriteDownload makes the planned rewrites to a single download, returning those it completed
+func (ts *TrackerService) rewriteDownload(
+	ctx context.Context, hash string, trackers []*Tracker, planned []TrackerRewrite,
+) ([]TrackerRewrite, error) {
+	existing := make(map[string]bool, len(trackers))
+	for _, t := range trackers {
+		u, err := t.URL()
+		if enabled, enabledErr := t.IsEnabled(); err == nil && enabledErr == nil && enabled {
+			existing[u] = true
+		}
+	}
+
+	// Inserting a tracker moves the ones after it along, so we insert every new URL before disabling anything, and then
+	// find the old trackers again by URL rather than trusting the indexes we started with
+	var (
+		inserted []TrackerRewrite
+		errs     []error
+	)
+	for _, r := range planned {
+		if !existing[r.NewURL] {
+			if err := ts.C.execute(ctx, "d.tracker.insert", hash, strconv.Itoa(r.Group), r.NewURL); err != nil {
+				errs = append(errs, fmt.Errorf("inserting %s: %w", r.NewURL, err))
+				continue
+			}
+			existing[r.NewURL] = true
+		}
+		inserted = append(inserted, r)
+	}
+	if len(inserted) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	current, err := ts.TrackerWithDetails(ctx, NewTrackerNoIndex(hash), []TrackerField{FieldURL, FieldIsEnabled})
+	if err != nil {
+		return nil, errors.Join(append(errs, err)...)
+	}
+	// TrackerWithDetails leaves a lone tracker without an index, and disabling needs one
+	for i, t := range current {
+		current[i] = t.CloneWithTrackerIndex(NewTrackerWithIndex(hash, i))
+	}
+	var done []TrackerRewrite
+	for _, r := range inserted {
+		if err := ts.disableURL(ctx, current, r.OldURL); err != nil {
+			errs = append(errs, fmt.Errorf("disabling %s: %w", r.OldURL, err))
+			continue
+		}
+		done = append(done, r)
+	}
+
+	if err := ts.C.execute(ctx, "d.save_full_session", hash); err != nil {
+		errs = append(errs, fmt.Errorf("saving session: %w", err))
+	}
+	return done, errors.Join(errs...)
+}
+
+// disableURL disables every enabled tracker among trackers announcing to u
+func (ts *TrackerService) disableURL(ctx context.Context, trackers []*Tracker, u string) error {
+	for _, t := range trackers {
+		tu, err := t.URL()
+		if err != nil {
+			return err
+		}
+		enabled, err := t.IsEnabled()
+		if err != nil {
+			return err
+		}
+		if tu != u || !enabled {
+			continue
+		}
+		if err := ts.SetEnabled(ctx, t.TrackerIndex(), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
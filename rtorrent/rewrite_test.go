@@ -0,0 +1,101 @@
+package rtorrent
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var passkeyPattern = regexp.MustCompile(`^https://old\.example/(\w+)/announce$`)
+
+func expectRewriteTrackers(m *MockClient, rows ...[]any) {
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "t.multicall=,t.url=,t.is_enabled=,t.group=").
+		Return(rows, nil)
+}
+
+func TestTrackerService_RewriteTrackers(t *testing.T) {
+	t.Parallel()
+
+	hashB := strings.Repeat("B", 40)
+	m := NewMockClient(gomock.NewController(t))
+	expectRewriteTrackers(m,
+		[]any{testInfoHash, []any{
+			[]any{"udp://other.example:1337", int64(1), int64(0)},
+			[]any{"https://old.example/key/announce", int64(1), int64(1)},
+		}},
+		[]any{hashB, []any{
+			[]any{"https://old.example/key/announce", int64(0), int64(0)},
+			[]any{"udp://other.example:1337", int64(1), int64(0)},
+		}},
+	)
+	gomock.InOrder(
+		m.EXPECT().execute(gomock.Any(), "d.tracker.insert", testInfoHash, "1", "https://new.example/key/announce").Return(nil),
+		// The new tracker has gone in after the old one, which keeps its index here
+		m.EXPECT().getSliceSliceByHash(gomock.Any(), trackerListMultiCall, []string{testInfoHash, "t.url=", "t.is_enabled="}).
+			Return([][]any{
+				{"udp://other.example:1337", int64(1)},
+				{"https://old.example/key/announce", int64(1)},
+				{"https://new.example/key/announce", int64(1)},
+			}, nil),
+		m.EXPECT().execute(gomock.Any(), "t.is_enabled.set", testInfoHash+":t1", "0").Return(nil),
+		m.EXPECT().execute(gomock.Any(), "d.save_full_session", testInfoHash).Return(nil),
+	)
+
+	rewrites, err := (&TrackerService{C: m}).RewriteTrackers(t.Context(), "", passkeyPattern, "https://new.example/$1/announce")
+	require.NoError(t, err)
+	assert.Equal(t, []TrackerRewrite{{
+		InfoHash: testInfoHash, Group: 1, OldURL: "https://old.example/key/announce", NewURL: "https://new.example/key/announce",
+	}}, rewrites, "disabled trackers are left alone")
+}
+
+func TestTrackerService_RewriteTrackersDryRun(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	expectRewriteTrackers(m, []any{testInfoHash, []any{[]any{"https://old.example/key/announce", int64(1), int64(0)}}})
+
+	rewrites, err := (&TrackerService{C: m}).RewriteTrackers(t.Context(), "", passkeyPattern, "https://new.example/$1/announce",
+		WithRewriteDryRun())
+	require.NoError(t, err)
+	require.Len(t, rewrites, 1)
+	assert.Equal(t, "https://new.example/key/announce", rewrites[0].NewURL)
+}
+
+func TestTrackerService_RewriteTrackersErrors(t *testing.T) {
+	t.Parallel()
+
+	var (
+		hashB       = strings.Repeat("B", 40)
+		hashC       = strings.Repeat("C", 40)
+		errRejected = errors.New("rejected")
+	)
+	m := NewMockClient(gomock.NewController(t))
+	expectRewriteTrackers(m,
+		[]any{testInfoHash, []any{[]any{"https://old.example/key/announce", int64(1), int64(0)}}},
+		[]any{hashB, []any{
+			[]any{"https://old.example/key/announce", int64(1), int64(0)},
+			[]any{"https://new.example/key/announce", int64(1), int64(0)},
+		}},
+		[]any{hashC, []any{[]any{"https://old.example/key/announce", int64(1), "first"}}},
+	)
+	// The first download's old tracker stays enabled, since its replacement never went in
+	m.EXPECT().execute(gomock.Any(), "d.tracker.insert", testInfoHash, "0", "https://new.example/key/announce").Return(errRejected)
+	// The second already has the new URL, so only needs the old one disabling
+	m.EXPECT().getSliceSliceByHash(gomock.Any(), trackerListMultiCall, []string{hashB, "t.url=", "t.is_enabled="}).
+		Return([][]any{{"https://old.example/key/announce", int64(1)}, {"https://new.example/key/announce", int64(1)}}, nil)
+	m.EXPECT().execute(gomock.Any(), "t.is_enabled.set", hashB+":t0", "0").Return(nil)
+	m.EXPECT().execute(gomock.Any(), "d.save_full_session", hashB).Return(nil)
+
+	rewrites, err := (&TrackerService{C: m}).RewriteTrackers(t.Context(), "", passkeyPattern, "https://new.example/$1/announce")
+	require.ErrorIs(t, err, errRejected)
+	assert.Contains(t, err.Error(), testInfoHash)
+	require.ErrorIs(t, err, ErrBadData, "the third download's tracker has an unreadable group")
+	assert.Contains(t, err.Error(), hashC)
+	require.Len(t, rewrites, 1)
+	assert.Equal(t, hashB, rewrites[0].InfoHash)
+}
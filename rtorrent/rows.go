@@ -0,0 +1,211 @@
+package rtorrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// rowTag is the struct tag DownloadRows reads each field's command from
+const rowTag = "rt"
+
+// ErrBadRowType is returned by DownloadRows for a type it can't decode rows into.
+var ErrBadRowType = errors.New("bad row type")
+
+// rowColumn is a struct field DownloadRows fills, along with the command that fills it
+type rowColumn struct {
+	index   int
+	name    string
+	command string
+	set     func(reflect.Value, any) error
+}
+
+// argument is the column as an argument to a multicall. A command that already has its own arguments, such as
+// d.custom=progress, is passed on as it is.
+func (col rowColumn) argument() string {
+	if strings.Contains(col.command, "=") {
+		return col.command
+	}
+	return col.command + "="
+}
+
+// DownloadRows retrieves every download in view, or the default view if view is "", as a slice of T, a struct whose
+// fields are tagged with the command rTorrent should fill them from, such as:
+//
+//	type Row struct {
+//		Hash  string    `rt:"d.hash"`
+//		Ratio int       `rt:"d.ratio"`
+//		Done  time.Time `rt:"d.timestamp.finished"`
+//	}
+//
+// It takes the place of DownloadWithDetails for ad-hoc reports, building the d.multicall2 request from the tags and
+// converting each value as the Download getters do. Fields may be strings, bools, integers, floats, time.Time for
+// timestamps, or any to be handed the raw value, and fields without a tag, or tagged "-", are left alone. A type it
+// can't fill gives ErrBadRowType before anything reaches rTorrent, while a value that doesn't convert to its field's
+// type gives ErrBadData naming the row and field, along with the rows decoded before it.
+func DownloadRows[T any](ctx context.Context, s *DownloadService, view string) ([]T, error) {
+	typ := reflect.TypeFor[T]()
+	columns, err := rowColumns(typ)
+	if err != nil {
+		return nil, err
+	}
+	if view == "" {
+		view = "default"
+	}
+	args := []string{view}
+	for _, col := range columns {
+		args = append(args, col.argument())
+	}
+
+	sliceOfSlices, err := s.C.getSliceSlice(ctx, downloadListMultiCall, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]T, 0, len(sliceOfSlices))
+	for i, slice := range sliceOfSlices {
+		if len(slice) < len(columns) {
+			return rows, fmt.Errorf("%w: row %d: got %d values for %d columns", ErrBadData, i, len(slice), len(columns))
+		}
+		var row T
+		v := reflect.ValueOf(&row).Elem()
+		for j, col := range columns {
+			if err := col.set(v.Field(col.index), slice[j]); err != nil {
+				f := typ.Field(col.index)
+				return rows, fmt.Errorf("row %d: %s.%s (%s) from %s: %w", i, typ.Name(), col.name, f.Type, col.command, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// rowColumns works out the columns of a row type from its tags
+func rowColumns(typ reflect.Type) ([]rowColumn, error) {
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s is not a struct", ErrBadRowType, typ)
+	}
+	var columns []rowColumn
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		command, ok := f.Tag.Lookup(rowTag)
+		if !ok || command == "-" {
+			continue
+		}
+		if command == "" {
+			return nil, fmt.Errorf("%w: %s.%s has an empty %s tag", ErrBadRowType, typ.Name(), f.Name, rowTag)
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("%w: %s.%s is tagged but not exported", ErrBadRowType, typ.Name(), f.Name)
+		}
+		set, err := rowSetter(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", typ.Name(), f.Name, err)
+		}
+		columns = append(columns, rowColumn{index: i, name: f.Name, command: command, set: set})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: %s has no fields tagged %s", ErrNoField, typ, rowTag)
+	}
+	return slices.Clip(columns), nil
+}
+
+// rowSetters set a field of each kind DownloadRows can fill from a raw value, using the converters in data.go
+var rowSetters = map[reflect.Kind]func(reflect.Value, any) error{
+	reflect.String:  setString,
+	reflect.Bool:    setBool,
+	reflect.Int:     setInt,
+	reflect.Int8:    setInt,
+	reflect.Int16:   setInt,
+	reflect.Int32:   setInt,
+	reflect.Int64:   setInt,
+	reflect.Uint:    setUint,
+	reflect.Uint8:   setUint,
+	reflect.Uint16:  setUint,
+	reflect.Uint32:  setUint,
+	reflect.Uint64:  setUint,
+	reflect.Float32: setFloat,
+	reflect.Float64: setFloat,
+}
+
+// rowSetter picks the setter for a field of type typ
+func rowSetter(typ reflect.Type) (func(reflect.Value, any) error, error) {
+	if typ == reflect.TypeFor[time.Time]() {
+		return setTime, nil
+	}
+	if typ.Kind() == reflect.Interface && typ.NumMethod() == 0 {
+		return setRaw, nil
+	}
+	if set, ok := rowSetters[typ.Kind()]; ok {
+		return set, nil
+	}
+	return nil, fmt.Errorf("%w: cannot decode into %s", ErrBadRowType, typ)
+}
+
+func setString(v reflect.Value, raw any) error {
+	s, err := stringFromAny(raw)
+	if err == nil {
+		v.SetString(s)
+	}
+	return err
+}
+
+func setBool(v reflect.Value, raw any) error {
+	b, err := boolFromAny(raw)
+	if err == nil {
+		v.SetBool(b)
+	}
+	return err
+}
+
+func setInt(v reflect.Value, raw any) error {
+	i, err := intFromAny(raw)
+	if err != nil {
+		return err
+	}
+	if v.OverflowInt(int64(i)) {
+		return fmt.Errorf("%w: %d overflows %s", ErrBadData, i, v.Type())
+	}
+	v.SetInt(int64(i))
+	return nil
+}
+
+func setUint(v reflect.Value, raw any) error {
+	i, err := intFromAny(raw)
+	if err != nil {
+		return err
+	}
+	if i < 0 || v.OverflowUint(uint64(i)) {
+		return fmt.Errorf("%w: %d overflows %s", ErrBadData, i, v.Type())
+	}
+	v.SetUint(uint64(i))
+	return nil
+}
+
+func setFloat(v reflect.Value, raw any) error {
+	f, err := floatFromAny(raw)
+	if err == nil {
+		v.SetFloat(f)
+	}
+	return err
+}
+
+func setTime(v reflect.Value, raw any) error {
+	t, err := timeFromAny(raw)
+	if err == nil {
+		v.Set(reflect.ValueOf(t))
+	}
+	return err
+}
+
+// setRaw hands an any field the value just as rTorrent sent it
+func setRaw(v reflect.Value, raw any) error {
+	if raw != nil {
+		v.Set(reflect.ValueOf(raw))
+	}
+	return nil
+}
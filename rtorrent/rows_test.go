@@ -0,0 +1,121 @@
+package rtorrent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type reportRow struct {
+	Hash     string    `rt:"d.hash"`
+	Ratio    int       `rt:"d.ratio"`
+	Done     time.Time `rt:"d.timestamp.finished"`
+	Started  bool      `rt:"d.state"`
+	Rate     uint16    `rt:"d.down.rate="`
+	Progress float64   `rt:"d.custom=progress"`
+	Raw      any       `rt:"d.custom1"`
+	Note     string
+	Skipped  string `rt:"-"`
+}
+
+func TestDownloadRows(t *testing.T) {
+	t.Parallel()
+
+	t.Run("decodes tagged fields", func(t *testing.T) {
+		t.Parallel()
+
+		m := NewMockClient(gomock.NewController(t))
+		m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "seeding", "d.hash=", "d.ratio=",
+			"d.timestamp.finished=", "d.state=", "d.down.rate=", "d.custom=progress", "d.custom1=").
+			Return([][]any{
+				{testDownloads[0], int64(1500), int64(1700000000), int64(1), int64(512), "0.5", "linux"},
+				{testDownloads[1], int64(0), int64(0), int64(0), int64(0), "1", ""},
+			}, nil)
+
+		rows, err := DownloadRows[reportRow](t.Context(), &DownloadService{C: m}, "seeding")
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, reportRow{
+			Hash: testDownloads[0], Ratio: 1500, Done: time.Unix(1700000000, 0), Started: true, Rate: 512,
+			Progress: 0.5, Raw: "linux",
+		}, rows[0])
+		assert.False(t, rows[1].Started)
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		type row struct {
+			Hash  string `rt:"d.hash"`
+			Ratio int    `rt:"d.ratio"`
+		}
+		m := NewMockClient(gomock.NewController(t))
+		m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.ratio=").
+			Return([][]any{{testDownloads[0], int64(1)}, {testDownloads[1], []any{}}}, nil)
+
+		rows, err := DownloadRows[row](t.Context(), &DownloadService{C: m}, "")
+		require.ErrorIs(t, err, ErrBadData)
+		assert.Contains(t, err.Error(), "row 1: row.Ratio (int) from d.ratio: bad data")
+		assert.Len(t, rows, 1, "the rows before the bad one are still returned")
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		t.Parallel()
+
+		type row struct {
+			Rate uint8 `rt:"d.down.rate"`
+		}
+		m := NewMockClient(gomock.NewController(t))
+		m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.down.rate=").
+			Return([][]any{{int64(-1)}}, nil)
+
+		_, err := DownloadRows[row](t.Context(), &DownloadService{C: m}, "")
+		require.ErrorIs(t, err, ErrBadData)
+	})
+
+	t.Run("short row", func(t *testing.T) {
+		t.Parallel()
+
+		type row struct {
+			Hash string `rt:"d.hash"`
+			Name string `rt:"d.name"`
+		}
+		m := NewMockClient(gomock.NewController(t))
+		m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=", "d.name=").
+			Return([][]any{{testDownloads[0]}}, nil)
+
+		_, err := DownloadRows[row](t.Context(), &DownloadService{C: m}, "")
+		require.ErrorIs(t, err, ErrBadData)
+	})
+}
+
+func TestDownloadRows_BadTypes(t *testing.T) {
+	t.Parallel()
+
+	// No call is expected, a type that can't be filled is rejected before anything reaches rTorrent
+	ds := &DownloadService{C: NewMockClient(gomock.NewController(t))}
+
+	_, err := DownloadRows[string](t.Context(), ds, "")
+	require.ErrorIs(t, err, ErrBadRowType)
+
+	_, err = DownloadRows[struct {
+		Files []string `rt:"d.files"`
+	}](t.Context(), ds, "")
+	require.ErrorIs(t, err, ErrBadRowType)
+
+	_, err = DownloadRows[struct {
+		hash string `rt:"d.hash"`
+	}](t.Context(), ds, "")
+	require.ErrorIs(t, err, ErrBadRowType)
+
+	_, err = DownloadRows[struct {
+		Hash string `rt:""`
+	}](t.Context(), ds, "")
+	require.ErrorIs(t, err, ErrBadRowType)
+
+	_, err = DownloadRows[struct{ Hash string }](t.Context(), ds, "")
+	require.ErrorIs(t, err, ErrNoField)
+}
@@ -2,8 +2,11 @@
 package rtorrent
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
 
 	"github.com/kolo/xmlrpc"
 )
@@ -17,29 +20,57 @@ type Client interface {
 	DownloadRate() (int, error)
 	UploadRate() (int, error)
 
-	getSliceSlice(method string, args ...string) ([][]any, error)
-	getSliceSliceByHash(method string, args ...string) ([][]any, error)
-	getStringSlice(method string, args ...string) ([]string, error)
-	getInt(method string, arg string) (int, error)
-	getString(method string, arg string) (string, error)
+	getSliceSlice(ctx context.Context, method string, args ...string) ([][]any, error)
+	getSliceSliceByHash(ctx context.Context, method string, args ...string) ([][]any, error)
+	getStringSlice(ctx context.Context, method string, args ...string) ([]string, error)
+	getInt(ctx context.Context, method string, arg string) (int, error)
+	getString(ctx context.Context, method string, arg string) (string, error)
+	execute(ctx context.Context, method string, args ...string) error
 }
 
-// A XMLRPCClient is an rTorrent client.  It can be used to retrieve a variety of statistics from rTorrent.
+// A XMLRPCClient is an rTorrent client.  It can be used to retrieve a variety of statistics from rTorrent.  It is safe for
+// concurrent use, and WithMaxInFlight and WithRateLimit keep a busy set of goroutines from overwhelming rTorrent.
 type XMLRPCClient struct {
 	xrc *xmlrpc.Client
+
+	// interceptors wrap every call, outermost first, and invoke is the chain built from them by New
+	interceptors []Interceptor
+	invoke       Invoker
+
+	// limiters sit innermost in the chain, so calls that an interceptor answers itself never wait on them
+	limiters []Interceptor
+
+	// logger is optional, and endpoint is the address with any credentials redacted so it is safe to log
+	logger    *slog.Logger
+	logLevels LogLevels
+	endpoint  string
 }
 
+// An Option configures optional behaviour of a XMLRPCClient when it is passed to New.
+type Option func(*XMLRPCClient)
+
 // New creates a new Client using the input XML-RPC address and an optional transport.  If transport is nil, a default one will be used.
-func New(addr string, transport http.RoundTripper) (Client, error) {
+func New(addr string, transport http.RoundTripper, opts ...Option) (Client, error) {
 	xrc, err := xmlrpc.NewClient(addr, transport)
 	if err != nil {
 		return nil, fmt.Errorf("creating xml-rpc client for %q: %w", addr, err)
 	}
 
 	c := &XMLRPCClient{
-		xrc: xrc,
+		xrc:       xrc,
+		logLevels: DefaultLogLevels,
+		endpoint:  redactEndpoint(addr),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 
+	interceptors := slices.Concat(c.interceptors, c.limiters)
+	if c.logger != nil {
+		interceptors = slices.Insert(interceptors, 0, Interceptor(c.logInterceptor))
+	}
+	c.invoke = chainInterceptors(interceptors, c.send)
+
 	return c, nil
 }
 
@@ -48,35 +79,56 @@ func (c *XMLRPCClient) Close() error {
 	return c.xrc.Close()
 }
 
-// DownloadTotal retrieves the total number of downloaded bytes since rTorrent startup.
+// DownloadTotal retrieves the total number of downloaded bytes since rTorrent startup. This and the other globals below
+// take no context, so can't be given up on; SnapshotService.Globals reads all four with one.
 func (c *XMLRPCClient) DownloadTotal() (int, error) {
-	return c.getInt("down.total", "")
+	return c.getInt(context.Background(), "down.total", "")
 }
 
 // UploadTotal retrieves the total number of uploaded bytes since rTorrent startup.
 func (c *XMLRPCClient) UploadTotal() (int, error) {
-	return c.getInt("up.total", "")
+	return c.getInt(context.Background(), "up.total", "")
 }
 
 // DownloadRate retrieves the current download rate in bytes from rTorrent.
 func (c *XMLRPCClient) DownloadRate() (int, error) {
-	return c.getInt("down.rate", "")
+	return c.getInt(context.Background(), "down.rate", "")
 }
 
 // UploadRate retrieves the current upload rate in bytes from rTorrent.
 func (c *XMLRPCClient) UploadRate() (int, error) {
-	return c.getInt("up.rate", "")
+	return c.getInt(context.Background(), "up.rate", "")
 }
 
-// call runs the XML-RPC method and decodes into out, tagging failures with the method name because transport errors
-// on their own give no clue as to which call went wrong
-func (c *XMLRPCClient) call(method string, send any, out any) error {
-	if err := c.xrc.Call(method, send, out); err != nil {
+// call runs the XML-RPC method through the interceptor chain and decodes into out, tagging failures with the method
+// name because transport errors on their own give no clue as to which call went wrong
+func (c *XMLRPCClient) call(ctx context.Context, method string, args []any, out any) error {
+	invoke := c.invoke
+	if invoke == nil {
+		// A XMLRPCClient built by hand rather than through New has no chain, so it talks to rTorrent directly
+		invoke = c.send
+	}
+	if err := invoke(ctx, method, args, out); err != nil {
 		return fmt.Errorf("xml-rpc call %q: %w", method, err)
 	}
 	return nil
 }
 
+// send is the innermost Invoker, the one that actually puts the request on the wire. The XML-RPC codec has no notion
+// of a context, so ctx only reaches as far as the interceptors.
+func (c *XMLRPCClient) send(_ context.Context, method string, args []any, reply any) error {
+	return c.xrc.Call(method, args, reply)
+}
+
+// singleArg wraps an optional lone argument, leaving it out entirely when empty so methods that take no target are
+// sent without params
+func singleArg(arg string) []any {
+	if arg == "" {
+		return nil
+	}
+	return []any{arg}
+}
+
 // argsToAny widens the string args into the []any the XML-RPC codec expects, prefixed by the lead arguments
 func argsToAny(lead []any, args []string) []any {
 	send := make([]any, 0, len(lead)+len(args))
@@ -88,45 +140,41 @@ func argsToAny(lead []any, args []string) []any {
 }
 
 // getInt retrieves an integer value from the specified XML-RPC method.
-func (c *XMLRPCClient) getInt(method string, arg string) (int, error) {
-	var send any
-	if arg != "" {
-		send = arg
-	}
-
+func (c *XMLRPCClient) getInt(ctx context.Context, method string, arg string) (int, error) {
 	var v int
-	return v, c.call(method, send, &v)
+	return v, c.call(ctx, method, singleArg(arg), &v)
 }
 
 // getString retrieves a string value from the specified XML-RPC method.
-func (c *XMLRPCClient) getString(method string, arg string) (string, error) {
-	var send any
-	if arg != "" {
-		send = arg
-	}
-
+func (c *XMLRPCClient) getString(ctx context.Context, method string, arg string) (string, error) {
 	var v string
-	return v, c.call(method, send, &v)
+	return v, c.call(ctx, method, singleArg(arg), &v)
 }
 
 // getStringSlice retrieves a slice of string values from the specified XML-RPC method.
-func (c *XMLRPCClient) getStringSlice(method string, args ...string) ([]string, error) {
+func (c *XMLRPCClient) getStringSlice(ctx context.Context, method string, args ...string) ([]string, error) {
 	var v []string
-	return v, c.call(method, argsToAny([]any{""}, args), &v)
+	return v, c.call(ctx, method, argsToAny([]any{""}, args), &v)
+}
+
+// execute calls the specified XML-RPC method for its effect, discarding whatever it returns.
+func (c *XMLRPCClient) execute(ctx context.Context, method string, args ...string) error {
+	var v any
+	return c.call(ctx, method, argsToAny(nil, args), &v)
 }
 
 // getSliceSlice retrieves a slice of slice values from the specified XML-RPC method.
-func (c *XMLRPCClient) getSliceSlice(method string, args ...string) ([][]any, error) {
+func (c *XMLRPCClient) getSliceSlice(ctx context.Context, method string, args ...string) ([][]any, error) {
 	var v [][]any
-	return v, c.call(method, argsToAny([]any{""}, args), &v)
+	return v, c.call(ctx, method, argsToAny([]any{""}, args), &v)
 }
 
 // getSliceSliceByHash retrieves a slice of slice values scoped to the info-hash that must be passed as the first argument.
-func (c *XMLRPCClient) getSliceSliceByHash(method string, args ...string) ([][]any, error) {
+func (c *XMLRPCClient) getSliceSliceByHash(ctx context.Context, method string, args ...string) ([][]any, error) {
 	if len(args) == 0 {
 		return nil, fmt.Errorf("%w: %s requires an info-hash as its first argument", ErrBadData, method)
 	}
 
 	var v [][]any
-	return v, c.call(method, argsToAny([]any{args[0], ""}, args[1:]), &v)
+	return v, c.call(ctx, method, argsToAny([]any{args[0], ""}, args[1:]), &v)
 }
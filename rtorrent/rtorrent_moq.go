@@ -10,6 +10,7 @@
 package rtorrent
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -233,19 +234,62 @@ func (c *MockClientUploadTotalCall) DoAndReturn(f func() (int, error)) *MockClie
 	return c
 }
 
+// execute mocks base method.
+func (m *MockClient) execute(ctx context.Context, method string, args ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, method}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "execute", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// execute indicates an expected call of execute.
+func (mr *MockClientMockRecorder) execute(ctx, method any, args ...any) *MockClientexecuteCall {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, method}, args...)
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "execute", reflect.TypeOf((*MockClient)(nil).execute), varargs...)
+	return &MockClientexecuteCall{Call: call}
+}
+
+// MockClientexecuteCall wrap *gomock.Call
+type MockClientexecuteCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockClientexecuteCall) Return(arg0 error) *MockClientexecuteCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockClientexecuteCall) Do(f func(context.Context, string, ...string) error) *MockClientexecuteCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockClientexecuteCall) DoAndReturn(f func(context.Context, string, ...string) error) *MockClientexecuteCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // getInt mocks base method.
-func (m *MockClient) getInt(method, arg string) (int, error) {
+func (m *MockClient) getInt(ctx context.Context, method, arg string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "getInt", method, arg)
+	ret := m.ctrl.Call(m, "getInt", ctx, method, arg)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // getInt indicates an expected call of getInt.
-func (mr *MockClientMockRecorder) getInt(method, arg any) *MockClientgetIntCall {
+func (mr *MockClientMockRecorder) getInt(ctx, method, arg any) *MockClientgetIntCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getInt", reflect.TypeOf((*MockClient)(nil).getInt), method, arg)
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getInt", reflect.TypeOf((*MockClient)(nil).getInt), ctx, method, arg)
 	return &MockClientgetIntCall{Call: call}
 }
 
@@ -261,21 +305,21 @@ func (c *MockClientgetIntCall) Return(arg0 int, arg1 error) *MockClientgetIntCal
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockClientgetIntCall) Do(f func(string, string) (int, error)) *MockClientgetIntCall {
+func (c *MockClientgetIntCall) Do(f func(context.Context, string, string) (int, error)) *MockClientgetIntCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockClientgetIntCall) DoAndReturn(f func(string, string) (int, error)) *MockClientgetIntCall {
+func (c *MockClientgetIntCall) DoAndReturn(f func(context.Context, string, string) (int, error)) *MockClientgetIntCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
 // getSliceSlice mocks base method.
-func (m *MockClient) getSliceSlice(method string, args ...string) ([][]any, error) {
+func (m *MockClient) getSliceSlice(ctx context.Context, method string, args ...string) ([][]any, error) {
 	m.ctrl.T.Helper()
-	varargs := []any{method}
+	varargs := []any{ctx, method}
 	for _, a := range args {
 		varargs = append(varargs, a)
 	}
@@ -286,9 +330,9 @@ func (m *MockClient) getSliceSlice(method string, args ...string) ([][]any, erro
 }
 
 // getSliceSlice indicates an expected call of getSliceSlice.
-func (mr *MockClientMockRecorder) getSliceSlice(method any, args ...any) *MockClientgetSliceSliceCall {
+func (mr *MockClientMockRecorder) getSliceSlice(ctx, method any, args ...any) *MockClientgetSliceSliceCall {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{method}, args...)
+	varargs := append([]any{ctx, method}, args...)
 	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getSliceSlice", reflect.TypeOf((*MockClient)(nil).getSliceSlice), varargs...)
 	return &MockClientgetSliceSliceCall{Call: call}
 }
@@ -305,21 +349,21 @@ func (c *MockClientgetSliceSliceCall) Return(arg0 [][]any, arg1 error) *MockClie
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockClientgetSliceSliceCall) Do(f func(string, ...string) ([][]any, error)) *MockClientgetSliceSliceCall {
+func (c *MockClientgetSliceSliceCall) Do(f func(context.Context, string, ...string) ([][]any, error)) *MockClientgetSliceSliceCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockClientgetSliceSliceCall) DoAndReturn(f func(string, ...string) ([][]any, error)) *MockClientgetSliceSliceCall {
+func (c *MockClientgetSliceSliceCall) DoAndReturn(f func(context.Context, string, ...string) ([][]any, error)) *MockClientgetSliceSliceCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
 // getSliceSliceByHash mocks base method.
-func (m *MockClient) getSliceSliceByHash(method string, args ...string) ([][]any, error) {
+func (m *MockClient) getSliceSliceByHash(ctx context.Context, method string, args ...string) ([][]any, error) {
 	m.ctrl.T.Helper()
-	varargs := []any{method}
+	varargs := []any{ctx, method}
 	for _, a := range args {
 		varargs = append(varargs, a)
 	}
@@ -330,9 +374,9 @@ func (m *MockClient) getSliceSliceByHash(method string, args ...string) ([][]any
 }
 
 // getSliceSliceByHash indicates an expected call of getSliceSliceByHash.
-func (mr *MockClientMockRecorder) getSliceSliceByHash(method any, args ...any) *MockClientgetSliceSliceByHashCall {
+func (mr *MockClientMockRecorder) getSliceSliceByHash(ctx, method any, args ...any) *MockClientgetSliceSliceByHashCall {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{method}, args...)
+	varargs := append([]any{ctx, method}, args...)
 	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getSliceSliceByHash", reflect.TypeOf((*MockClient)(nil).getSliceSliceByHash), varargs...)
 	return &MockClientgetSliceSliceByHashCall{Call: call}
 }
@@ -349,30 +393,30 @@ func (c *MockClientgetSliceSliceByHashCall) Return(arg0 [][]any, arg1 error) *Mo
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockClientgetSliceSliceByHashCall) Do(f func(string, ...string) ([][]any, error)) *MockClientgetSliceSliceByHashCall {
+func (c *MockClientgetSliceSliceByHashCall) Do(f func(context.Context, string, ...string) ([][]any, error)) *MockClientgetSliceSliceByHashCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockClientgetSliceSliceByHashCall) DoAndReturn(f func(string, ...string) ([][]any, error)) *MockClientgetSliceSliceByHashCall {
+func (c *MockClientgetSliceSliceByHashCall) DoAndReturn(f func(context.Context, string, ...string) ([][]any, error)) *MockClientgetSliceSliceByHashCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
 // getString mocks base method.
-func (m *MockClient) getString(method, arg string) (string, error) {
+func (m *MockClient) getString(ctx context.Context, method, arg string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "getString", method, arg)
+	ret := m.ctrl.Call(m, "getString", ctx, method, arg)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // getString indicates an expected call of getString.
-func (mr *MockClientMockRecorder) getString(method, arg any) *MockClientgetStringCall {
+func (mr *MockClientMockRecorder) getString(ctx, method, arg any) *MockClientgetStringCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getString", reflect.TypeOf((*MockClient)(nil).getString), method, arg)
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getString", reflect.TypeOf((*MockClient)(nil).getString), ctx, method, arg)
 	return &MockClientgetStringCall{Call: call}
 }
 
@@ -388,21 +432,21 @@ func (c *MockClientgetStringCall) Return(arg0 string, arg1 error) *MockClientget
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockClientgetStringCall) Do(f func(string, string) (string, error)) *MockClientgetStringCall {
+func (c *MockClientgetStringCall) Do(f func(context.Context, string, string) (string, error)) *MockClientgetStringCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockClientgetStringCall) DoAndReturn(f func(string, string) (string, error)) *MockClientgetStringCall {
+func (c *MockClientgetStringCall) DoAndReturn(f func(context.Context, string, string) (string, error)) *MockClientgetStringCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
 // getStringSlice mocks base method.
-func (m *MockClient) getStringSlice(method string, args ...string) ([]string, error) {
+func (m *MockClient) getStringSlice(ctx context.Context, method string, args ...string) ([]string, error) {
 	m.ctrl.T.Helper()
-	varargs := []any{method}
+	varargs := []any{ctx, method}
 	for _, a := range args {
 		varargs = append(varargs, a)
 	}
@@ -413,9 +457,9 @@ func (m *MockClient) getStringSlice(method string, args ...string) ([]string, er
 }
 
 // getStringSlice indicates an expected call of getStringSlice.
-func (mr *MockClientMockRecorder) getStringSlice(method any, args ...any) *MockClientgetStringSliceCall {
+func (mr *MockClientMockRecorder) getStringSlice(ctx, method any, args ...any) *MockClientgetStringSliceCall {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{method}, args...)
+	varargs := append([]any{ctx, method}, args...)
 	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getStringSlice", reflect.TypeOf((*MockClient)(nil).getStringSlice), varargs...)
 	return &MockClientgetStringSliceCall{Call: call}
 }
@@ -432,13 +476,13 @@ func (c *MockClientgetStringSliceCall) Return(arg0 []string, arg1 error) *MockCl
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockClientgetStringSliceCall) Do(f func(string, ...string) ([]string, error)) *MockClientgetStringSliceCall {
+func (c *MockClientgetStringSliceCall) Do(f func(context.Context, string, ...string) ([]string, error)) *MockClientgetStringSliceCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockClientgetStringSliceCall) DoAndReturn(f func(string, ...string) ([]string, error)) *MockClientgetStringSliceCall {
+func (c *MockClientgetStringSliceCall) DoAndReturn(f func(context.Context, string, ...string) ([]string, error)) *MockClientgetStringSliceCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
@@ -45,13 +45,13 @@ func TestGetSliceSliceByHashRequiresInfoHash(t *testing.T) {
 	t.Parallel()
 
 	c := &XMLRPCClient{}
-	_, err := c.getSliceSliceByHash(trackerListMultiCall)
+	_, err := c.getSliceSliceByHash(t.Context(), trackerListMultiCall)
 	require.ErrorIs(t, err, ErrBadData)
 }
 
 // testClient stands up an XML-RPC server that asserts the request matches method and wantParams, then replies with out.
-// The returned Client is closed along with the server when the test finishes.
-func testClient(t *testing.T, method string, wantParams []string, out any) Client {
+// The returned Client, built with opts, is closed along with the server when the test finishes.
+func testClient(t *testing.T, method string, wantParams []string, out any, opts ...Option) Client {
 	t.Helper()
 
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -83,7 +83,7 @@ func testClient(t *testing.T, method string, wantParams []string, out any) Clien
 		}
 	}))
 
-	c, err := New(s.URL, nil)
+	c, err := New(s.URL, nil, opts...)
 	require.NoError(t, err, "failed to create Client")
 
 	t.Cleanup(func() {
@@ -0,0 +1,226 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+// subjectFields are the fields an Engine fetches for every download, which cover each of the built in conditions
+var subjectFields = []rtorrent.DownloadField{
+	rtorrent.DownloadFieldHash,
+	rtorrent.DownloadFieldName,
+	rtorrent.DownloadFieldState,
+	rtorrent.DownloadFieldComplete,
+	rtorrent.DownloadFieldDirectory,
+	rtorrent.DownloadFieldCustom1,
+	rtorrent.DownloadFieldSizeBytes,
+	rtorrent.DownloadFieldMessage,
+	rtorrent.DownloadFieldRatio,
+	rtorrent.DownloadFieldFinished,
+}
+
+// subjectTrackerFields are the fields fetched for a download's trackers when a condition asks for them
+var subjectTrackerFields = []rtorrent.TrackerField{
+	rtorrent.FieldURL,
+	rtorrent.FieldType,
+	rtorrent.FieldIsEnabled,
+	rtorrent.FieldFailedCounter,
+	rtorrent.FieldSuccessCounter,
+}
+
+// AuditEntry records an action a rule took, or would have taken in a dry run.
+type AuditEntry struct {
+	Time     time.Time
+	Rule     string
+	Action   string
+	InfoHash string
+	Name     string
+	DryRun   bool
+
+	// Err is why the action failed, if it did
+	Err error
+}
+
+// MarshalJSON writes the entry as a JSON object, with Err as its message.
+func (e AuditEntry) MarshalJSON() ([]byte, error) {
+	var msg string
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		Time     time.Time `json:"time"`
+		Rule     string    `json:"rule"`
+		Action   string    `json:"action"`
+		InfoHash string    `json:"info_hash"`
+		Name     string    `json:"name"`
+		DryRun   bool      `json:"dry_run,omitempty"`
+		Error    string    `json:"error,omitempty"`
+	}{e.Time, e.Rule, e.Action, e.InfoHash, e.Name, e.DryRun, msg})
+}
+
+// AuditLog keeps a record of what an Engine does.
+type AuditLog interface {
+	Record(e AuditEntry) error
+}
+
+// jsonAuditLog writes each entry as a line of JSON
+type jsonAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditLog returns an AuditLog that writes each entry to w as a line of JSON.
+func NewJSONAuditLog(w io.Writer) AuditLog {
+	return &jsonAuditLog{enc: json.NewEncoder(w)}
+}
+
+func (l *jsonAuditLog) Record(e AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(e)
+}
+
+// Option configures an Engine.
+type Option func(*Engine)
+
+// WithDryRun makes the engine record the actions its rules would take without taking them.
+func WithDryRun() Option {
+	return func(e *Engine) {
+		e.dryRun = true
+	}
+}
+
+// WithAuditLog records every action the engine takes, or would take in a dry run, to log.
+func WithAuditLog(log AuditLog) Option {
+	return func(e *Engine) {
+		e.audit = log
+	}
+}
+
+// WithView limits the engine to the downloads in the given rTorrent view, rather than every download.
+func WithView(view string) Option {
+	return func(e *Engine) {
+		e.view = view
+	}
+}
+
+// WithErrorHandler is given the error from each of Run's passes that has one. Without it, Run carries on past them
+// silently, leaving them to the audit log.
+func WithErrorHandler(fn func(error)) Option {
+	return func(e *Engine) {
+		e.onError = fn
+	}
+}
+
+// Engine evaluates rules against the downloads of an rTorrent instance and takes their actions.
+type Engine struct {
+	ds      *rtorrent.DownloadService
+	ts      *rtorrent.TrackerService
+	rules   []Rule
+	view    string
+	dryRun  bool
+	audit   AuditLog
+	onError func(error)
+	now     func() time.Time
+}
+
+// NewEngine returns an Engine that applies rules, in order, to the downloads of the rTorrent instance behind c.
+func NewEngine(c rtorrent.Client, rules []Rule, opts ...Option) *Engine {
+	e := &Engine{
+		ds:    &rtorrent.DownloadService{C: c},
+		ts:    &rtorrent.TrackerService{C: c},
+		rules: rules,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Evaluate makes a single pass over the downloads, taking the actions of every rule whose condition they meet, and
+// returns what it did. A download whose rule fails doesn't stop the others, and neither does a failed action, though it
+// does stop the rest of that rule's actions on that download. Those errors come back joined.
+func (e *Engine) Evaluate(ctx context.Context) ([]AuditEntry, error) {
+	downloads, err := e.ds.Downloads(ctx, e.view, subjectFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		now     = e.now()
+		entries []AuditEntry
+		errs    []error
+	)
+	for _, d := range downloads {
+		hash, err := d.Hash()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		name, _ := d.Name()
+		s := &Subject{
+			Download: d,
+			Now:      now,
+			loadTrackers: func(ctx context.Context) ([]*rtorrent.Tracker, error) {
+				return e.ts.TrackerWithDetails(ctx, rtorrent.NewTrackerNoIndex(hash), subjectTrackerFields)
+			},
+		}
+
+	rules:
+		for _, r := range e.rules {
+			ok, err := r.When.Match(ctx, s)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %q on %s: %w", r.Name, hash, err))
+				continue
+			}
+			if !ok {
+				continue
+			}
+			for _, a := range r.Then {
+				entry := AuditEntry{Time: now, Rule: r.Name, Action: a.String(), InfoHash: hash, Name: name, DryRun: e.dryRun}
+				if !e.dryRun {
+					entry.Err = a.Apply(ctx, e.ds, s)
+				}
+				entries = append(entries, entry)
+				if e.audit != nil {
+					if err := e.audit.Record(entry); err != nil {
+						errs = append(errs, fmt.Errorf("recording audit entry: %w", err))
+					}
+				}
+				if entry.Err != nil {
+					errs = append(errs, fmt.Errorf("rule %q on %s: %s: %w", r.Name, hash, entry.Action, entry.Err))
+					break
+				}
+				if af, ok := a.(actionFunc); ok && af.removes {
+					break rules
+				}
+			}
+		}
+	}
+	return entries, errors.Join(errs...)
+}
+
+// Run evaluates the rules straight away and then every interval, until ctx is done, and returns ctx's error.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.Evaluate(ctx); err != nil && e.onError != nil && ctx.Err() == nil {
+			e.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
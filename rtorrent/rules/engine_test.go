@@ -0,0 +1,169 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	hashA = strings.Repeat("A", 40)
+	hashB = strings.Repeat("B", 40)
+)
+
+// fakeRTorrent answers d.multicall2 with rows, in the order of subjectFields, and records every other call it is
+// given, failing those fail names
+type fakeRTorrent struct {
+	rows [][]any
+	fail map[string]error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeRTorrent) client(t *testing.T) rtorrent.Client {
+	t.Helper()
+
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil, rtorrent.WithInterceptors(
+		func(_ context.Context, method string, args []any, reply any, _ rtorrent.Invoker) error {
+			if method == "d.multicall2" {
+				*reply.(*[][]any) = f.rows
+				return nil
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.calls = append(f.calls, method+" "+args[0].(string))
+			return f.fail[method]
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// row is a download as the fake reports it, which has seeded for a day at the given ratio
+func row(hash, label string, started bool, ratio float64) []any {
+	state := int64(0)
+	if started {
+		state = 1
+	}
+	return []any{
+		hash, "name " + label, state, int64(1), "/mnt/fast", label, int64(1 << 20), "", int64(ratio * 1000),
+		testNow.Add(-day).Unix(),
+	}
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{rows: [][]any{
+		row(hashA, "linux", true, 3),
+		row(hashB, "keep", true, 3),
+	}}
+	var log bytes.Buffer
+	e := NewEngine(fake.client(t), []Rule{
+		{
+			Name: "stop seeding",
+			When: All(Started(), Ratio(OpGreaterEqual, 2), Not(Label("keep"))),
+			Then: []Action{Stop(), SetLabel("seeded")},
+		},
+	}, WithAuditLog(NewJSONAuditLog(&log)))
+	e.now = func() time.Time { return testNow }
+
+	entries, err := e.Evaluate(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d.stop " + hashA, "d.custom1.set " + hashA}, fake.calls)
+	assert.Equal(t, []AuditEntry{
+		{Time: testNow, Rule: "stop seeding", Action: "stop", InfoHash: hashA, Name: "name linux"},
+		{Time: testNow, Rule: "stop seeding", Action: "label seeded", InfoHash: hashA, Name: "name linux"},
+	}, entries)
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	require.Len(t, lines, 2)
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, map[string]any{
+		"time": testNow.Format(time.RFC3339Nano), "rule": "stop seeding", "action": "stop", "info_hash": hashA,
+		"name": "name linux",
+	}, first)
+}
+
+func TestEngineDryRun(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{rows: [][]any{row(hashA, "", true, 0)}}
+	e := NewEngine(fake.client(t), []Rule{{Name: "stop", When: Started(), Then: []Action{Stop()}}}, WithDryRun())
+
+	entries, err := e.Evaluate(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, fake.calls, "a dry run changes nothing")
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].DryRun)
+}
+
+func TestEngineErrors(t *testing.T) {
+	t.Parallel()
+
+	errRefused := errors.New("refused")
+	fake := &fakeRTorrent{
+		rows: [][]any{row(hashA, "", true, 0), row(hashB, "", true, 0)},
+		fail: map[string]error{"d.stop": errRefused},
+	}
+	e := NewEngine(fake.client(t), []Rule{
+		{Name: "stop", When: Started(), Then: []Action{Stop(), SetLabel("stopped")}},
+		{Name: "unknown", When: Size(">>", 0), Then: []Action{Start()}},
+	})
+
+	entries, err := e.Evaluate(t.Context())
+	require.ErrorIs(t, err, errRefused)
+	require.ErrorIs(t, err, ErrBadOp)
+	assert.Contains(t, err.Error(), hashB, "every download is still evaluated")
+
+	// A failed action stops the rest of its rule
+	assert.Equal(t, []string{"d.stop " + hashA, "d.stop " + hashB}, fake.calls)
+	require.Len(t, entries, 2)
+	require.ErrorIs(t, entries[0].Err, errRefused)
+}
+
+func TestEngineRemoveEndsEvaluation(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{rows: [][]any{row(hashA, "", false, 0)}}
+	e := NewEngine(fake.client(t), []Rule{
+		{Name: "remove", When: Not(Started()), Then: []Action{Remove(), SetLabel("never")}},
+		{Name: "start", When: Not(Started()), Then: []Action{Start()}},
+	})
+
+	_, err := e.Evaluate(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d.erase " + hashA}, fake.calls)
+}
+
+func TestEngineRun(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeRTorrent{
+		rows: [][]any{row(hashA, "", true, 0)},
+		fail: map[string]error{"d.stop": errors.New("refused")},
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	var passes int
+	e := NewEngine(fake.client(t), []Rule{{Name: "stop", When: Started(), Then: []Action{Stop()}}},
+		WithErrorHandler(func(error) {
+			if passes++; passes == 2 {
+				cancel()
+			}
+		}))
+
+	err := e.Run(ctx, time.Millisecond)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2, passes)
+}
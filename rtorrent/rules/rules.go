@@ -0,0 +1,335 @@
+// Package rules manages downloads automatically, by policies declared in code or YAML and evaluated periodically
+// against what rTorrent reports.
+//
+// A Rule pairs a Condition with the Actions to take on every download that meets it:
+//
+//	stopSeeding := rules.Rule{
+//		Name: "stop seeding",
+//		When: rules.All(
+//			rules.Ratio(rules.OpGreaterEqual, 2),
+//			rules.SeedingTime(rules.OpGreater, 7*24*time.Hour),
+//			rules.Not(rules.Label("keep")),
+//		),
+//		Then: []rules.Action{rules.Stop()},
+//	}
+//	engine := rules.NewEngine(c, []rules.Rule{stopSeeding}, rules.WithDryRun())
+//	err := engine.Run(ctx, 10*time.Minute)
+//
+// Conditions are evaluated against every download on each pass, so they should be written to stop matching once
+// their actions have been taken, such as by checking that a download is still Started before stopping it.
+package rules
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+)
+
+// ErrBadOp is returned when a condition is given a comparison operator it doesn't know.
+var ErrBadOp = errors.New("unknown comparison operator")
+
+// Rule is a policy: the Actions to take, in order, on every download meeting the Condition.
+type Rule struct {
+	Name string
+	When Condition
+	Then []Action
+}
+
+// Subject is a download as a rule sees it, as of an engine's latest pass.
+type Subject struct {
+	Download *rtorrent.Download
+	Now      time.Time
+
+	// trackers are only fetched for conditions that ask for them, and then only once
+	loadTrackers func(ctx context.Context) ([]*rtorrent.Tracker, error)
+	once         sync.Once
+	trackers     []*rtorrent.Tracker
+	trackersErr  error
+}
+
+// NewSubject builds a Subject from a download and its trackers, for evaluating conditions against downloads from a
+// source other than an Engine, such as the session package.
+func NewSubject(d *rtorrent.Download, trackers []*rtorrent.Tracker, now time.Time) *Subject {
+	return &Subject{
+		Download:     d,
+		Now:          now,
+		loadTrackers: func(context.Context) ([]*rtorrent.Tracker, error) { return trackers, nil },
+	}
+}
+
+// Trackers returns the download's trackers.
+func (s *Subject) Trackers(ctx context.Context) ([]*rtorrent.Tracker, error) {
+	s.once.Do(func() {
+		s.trackers, s.trackersErr = s.loadTrackers(ctx)
+	})
+	return s.trackers, s.trackersErr
+}
+
+// Op is a comparison operator for conditions on a download's quantities.
+type Op string
+
+// Comparison Operators
+const (
+	OpGreater      Op = ">"
+	OpGreaterEqual Op = ">="
+	OpLess         Op = "<"
+	OpLessEqual    Op = "<="
+	OpEqual        Op = "=="
+	OpNotEqual     Op = "!="
+)
+
+// allOps lists the operators longest first, which is the order a parser has to try them in
+var allOps = []Op{OpGreaterEqual, OpLessEqual, OpEqual, OpNotEqual, OpGreater, OpLess}
+
+func compare[T cmp.Ordered](a T, op Op, b T) (bool, error) {
+	c := cmp.Compare(a, b)
+	switch op {
+	case OpGreater:
+		return c > 0, nil
+	case OpGreaterEqual:
+		return c >= 0, nil
+	case OpLess:
+		return c < 0, nil
+	case OpLessEqual:
+		return c <= 0, nil
+	case OpEqual:
+		return c == 0, nil
+	case OpNotEqual:
+		return c != 0, nil
+	default:
+		return false, fmt.Errorf("%w: %q", ErrBadOp, op)
+	}
+}
+
+// A Condition decides whether a rule applies to a download.
+type Condition interface {
+	Match(ctx context.Context, s *Subject) (bool, error)
+}
+
+// ConditionFunc adapts a function into a Condition, for policies the built in conditions can't express.
+type ConditionFunc func(ctx context.Context, s *Subject) (bool, error)
+
+// Match calls f.
+func (f ConditionFunc) Match(ctx context.Context, s *Subject) (bool, error) {
+	return f(ctx, s)
+}
+
+// All matches downloads that meet every one of conds, checking them in order and stopping at the first that fails.
+func All(conds ...Condition) Condition {
+	return ConditionFunc(func(ctx context.Context, s *Subject) (bool, error) {
+		for _, c := range conds {
+			if ok, err := c.Match(ctx, s); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// Any matches downloads that meet at least one of conds, checking them in order and stopping at the first that does.
+func Any(conds ...Condition) Condition {
+	return ConditionFunc(func(ctx context.Context, s *Subject) (bool, error) {
+		for _, c := range conds {
+			if ok, err := c.Match(ctx, s); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	})
+}
+
+// Not matches downloads that don't meet cond.
+func Not(cond Condition) Condition {
+	return ConditionFunc(func(ctx context.Context, s *Subject) (bool, error) {
+		ok, err := cond.Match(ctx, s)
+		return !ok && err == nil, err
+	})
+}
+
+// Ratio matches downloads whose upload ratio compares to ratio by op.
+func Ratio(op Op, ratio float64) Condition {
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		r, err := s.Download.Ratio()
+		if err != nil {
+			return false, err
+		}
+		return compare(r, op, ratio)
+	})
+}
+
+// SeedingTime matches downloads that have been complete for a time comparing to d by op. Downloads that haven't
+// completed have been seeding for no time at all.
+func SeedingTime(op Op, d time.Duration) Condition {
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		finished, err := s.Download.FinishedTime()
+		if err != nil {
+			return false, err
+		}
+		var seeding time.Duration
+		if finished.Unix() > 0 {
+			seeding = s.Now.Sub(finished)
+		}
+		return compare(seeding, op, d)
+	})
+}
+
+// Size matches downloads whose content's size in bytes compares to bytes by op.
+func Size(op Op, bytes int) Condition {
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		size, err := s.Download.SizeBytes()
+		if err != nil {
+			return false, err
+		}
+		return compare(size, op, bytes)
+	})
+}
+
+// Label matches downloads labelled label, which is to say those with label in d.custom1 as ruTorrent keeps it.
+func Label(label string) Condition {
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		l, err := s.Download.Custom(1)
+		return l == label && err == nil, err
+	})
+}
+
+// Complete matches downloads that are complete.
+func Complete() Condition {
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		return s.Download.IsComplete()
+	})
+}
+
+// Started matches downloads that are started.
+func Started() Condition {
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		return s.Download.IsStarted()
+	})
+}
+
+// Under matches downloads whose directory is dir or somewhere beneath it.
+func Under(dir string) Condition {
+	dir = filepath.Clean(dir)
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		d, err := s.Download.Directory()
+		if err != nil {
+			return false, err
+		}
+		d = filepath.Clean(d)
+		return d == dir || strings.HasPrefix(d, dir+string(filepath.Separator)), nil
+	})
+}
+
+// MessageContains matches downloads whose message, which is where rTorrent reports tracker failures, contains substr
+// regardless of case.
+func MessageContains(substr string) Condition {
+	substr = strings.ToLower(substr)
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		msg, err := s.Download.Message()
+		return strings.Contains(strings.ToLower(msg), substr) && err == nil, err
+	})
+}
+
+// Unregistered matches downloads whose trackers have reported that they don't know the torrent, as trackers do for
+// torrents that have been deleted from them. rTorrent keeps no failure message per tracker, only the download's latest
+// one, so this goes by that, as rtorrent.IsUnregisteredMessage reads it.
+func Unregistered() Condition {
+	return ConditionFunc(func(_ context.Context, s *Subject) (bool, error) {
+		msg, err := s.Download.Message()
+		return rtorrent.IsUnregisteredMessage(msg) && err == nil, err
+	})
+}
+
+// TrackerContains matches downloads with a tracker whose URL contains substr, such as a tracker's host name.
+func TrackerContains(substr string) Condition {
+	return ConditionFunc(func(ctx context.Context, s *Subject) (bool, error) {
+		trackers, err := s.Trackers(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, t := range trackers {
+			u, err := t.URL()
+			if err != nil {
+				return false, err
+			}
+			if strings.Contains(u, substr) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// An Action is something a rule does to a download.
+type Action interface {
+	// Apply takes the action on s's download through ds
+	Apply(ctx context.Context, ds *rtorrent.DownloadService, s *Subject) error
+
+	// String describes the action for the audit log
+	String() string
+}
+
+// actionFunc is the Action behind each of the built in actions
+type actionFunc struct {
+	name string
+
+	// apply takes its arguments in the order of a DownloadService method expression, so those can be used directly
+	apply func(ds *rtorrent.DownloadService, ctx context.Context, hash string) error
+
+	// removes marks an action after which the download is gone, so later rules must leave it be
+	removes bool
+}
+
+func (a actionFunc) Apply(ctx context.Context, ds *rtorrent.DownloadService, s *Subject) error {
+	hash, err := s.Download.Hash()
+	if err != nil {
+		return err
+	}
+	return a.apply(ds, ctx, hash)
+}
+
+func (a actionFunc) String() string {
+	return a.name
+}
+
+// Stop stops the download.
+func Stop() Action {
+	return actionFunc{name: "stop", apply: (*rtorrent.DownloadService).Stop}
+}
+
+// Start starts the download.
+func Start() Action {
+	return actionFunc{name: "start", apply: (*rtorrent.DownloadService).Start}
+}
+
+// Remove removes the download from rTorrent, leaving its data where it is. No later rule sees the download in the
+// same pass.
+func Remove() Action {
+	return actionFunc{name: "remove", apply: (*rtorrent.DownloadService).Erase, removes: true}
+}
+
+// MoveTo moves the download's data to dir with DownloadService.Move.
+func MoveTo(dir string) Action {
+	return actionFunc{
+		name: "move to " + dir,
+		apply: func(ds *rtorrent.DownloadService, ctx context.Context, hash string) error {
+			return ds.Move(ctx, hash, dir)
+		},
+	}
+}
+
+// SetLabel labels the download label, in d.custom1 as ruTorrent keeps it.
+func SetLabel(label string) Action {
+	return actionFunc{
+		name: "label " + label,
+		apply: func(ds *rtorrent.DownloadService, ctx context.Context, hash string) error {
+			return ds.SetCustom(ctx, hash, 1, label)
+		},
+	}
+}
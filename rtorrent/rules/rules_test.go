@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Unix(1700000000, 0)
+
+// seeder is a complete download that has been seeding for eight days at a ratio of 2.5
+func seeder() *rtorrent.Download {
+	return rtorrent.NewDownload(map[rtorrent.DownloadField]any{
+		rtorrent.DownloadFieldHash:      "ABCDEF0123456789ABCDEF0123456789ABCDEF01",
+		rtorrent.DownloadFieldName:      "debian.iso",
+		rtorrent.DownloadFieldState:     int64(1),
+		rtorrent.DownloadFieldComplete:  int64(1),
+		rtorrent.DownloadFieldDirectory: "/mnt/fast/isos",
+		rtorrent.DownloadFieldCustom1:   "linux",
+		rtorrent.DownloadFieldSizeBytes: int64(4 << 30),
+		rtorrent.DownloadFieldMessage:   `Tracker: [Failure reason "Unregistered torrent"]`,
+		rtorrent.DownloadFieldRatio:     int64(2500),
+		rtorrent.DownloadFieldFinished:  testNow.Add(-8 * day).Unix(),
+	})
+}
+
+func TestConditions(t *testing.T) {
+	t.Parallel()
+
+	tracker := rtorrent.NewTracker(rtorrent.NewTrackerWithIndex("", 0), map[rtorrent.TrackerField]any{
+		rtorrent.FieldURL: "https://tracker.example.org/announce",
+	})
+	tests := []struct {
+		name     string
+		cond     Condition
+		expected bool
+	}{
+		{"ratio", Ratio(OpGreaterEqual, 2), true},
+		{"ratio below", Ratio(OpLess, 2), false},
+		{"seeding time", SeedingTime(OpGreater, 7*day), true},
+		{"seeding time below", SeedingTime(OpLessEqual, 7*day), false},
+		{"size", Size(OpGreater, 1<<30), true},
+		{"label", Label("linux"), true},
+		{"other label", Label("keep"), false},
+		{"complete", Complete(), true},
+		{"started", Started(), true},
+		{"under", Under("/mnt/fast"), true},
+		{"under exactly", Under("/mnt/fast/isos/"), true},
+		{"not under a sibling", Under("/mnt/fa"), false},
+		{"message", MessageContains("failure REASON"), true},
+		{"unregistered", Unregistered(), true},
+		{"tracker", TrackerContains("example.org"), true},
+		{"other tracker", TrackerContains("example.com"), false},
+		{"all", All(Complete(), Label("linux")), true},
+		{"all but one", All(Complete(), Label("keep")), false},
+		{"any", Any(Label("keep"), Complete()), true},
+		{"none", Any(Label("keep"), Not(Complete())), false},
+		{"empty all", All(), true},
+		{"empty any", Any(), false},
+	}
+	for _, tt := range tests {
+		s := NewSubject(seeder(), []*rtorrent.Tracker{tracker}, testNow)
+		ok, err := tt.cond.Match(t.Context(), s)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, ok, tt.name)
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	t.Parallel()
+
+	s := NewSubject(rtorrent.NewDownload(map[rtorrent.DownloadField]any{}), nil, testNow)
+
+	_, err := Ratio(OpGreater, 1).Match(t.Context(), s)
+	require.ErrorIs(t, err, rtorrent.ErrNoField)
+
+	// A condition that can't be decided isn't turned into a match by negating it
+	ok, err := Not(Complete()).Match(t.Context(), s)
+	require.ErrorIs(t, err, rtorrent.ErrNoField)
+	assert.False(t, ok)
+
+	_, err = Ratio("=>", 1).Match(t.Context(), NewSubject(seeder(), nil, testNow))
+	require.ErrorIs(t, err, ErrBadOp)
+}
+
+func TestSeedingTimeUnfinished(t *testing.T) {
+	t.Parallel()
+
+	s := NewSubject(rtorrent.NewDownload(map[rtorrent.DownloadField]any{
+		rtorrent.DownloadFieldFinished: int64(0),
+	}), nil, testNow)
+	ok, err := SeedingTime(OpEqual, 0).Match(t.Context(), s)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestSubjectLoadsTrackersOnce(t *testing.T) {
+	t.Parallel()
+
+	errTrackers := errors.New("no trackers")
+	var calls int
+	s := &Subject{
+		Download: seeder(),
+		loadTrackers: func(context.Context) ([]*rtorrent.Tracker, error) {
+			calls++
+			return nil, errTrackers
+		},
+	}
+	cond := Any(TrackerContains("a"), TrackerContains("b"))
+	_, err := cond.Match(t.Context(), s)
+	require.ErrorIs(t, err, errTrackers)
+	_, err = s.Trackers(t.Context())
+	require.ErrorIs(t, err, errTrackers)
+	assert.Equal(t, 1, calls)
+}
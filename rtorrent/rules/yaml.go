@@ -0,0 +1,287 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidRule is returned for rules that can't be loaded.
+var ErrInvalidRule = errors.New("invalid rule")
+
+// day is the unit seeding times are usually thought of in, which time.ParseDuration doesn't have
+const day = 24 * time.Hour
+
+// file is the layout of a rules file
+type file struct {
+	Rules []ruleNode `yaml:"rules"`
+}
+
+type ruleNode struct {
+	Name string      `yaml:"name"`
+	When yaml.Node   `yaml:"when"`
+	Then []yaml.Node `yaml:"then"`
+}
+
+// LoadFile reads rules from the YAML file at path. See ParseYAML for the format.
+func LoadFile(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules, err := ParseYAML(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseYAML reads rules from YAML such as:
+//
+//	rules:
+//	  - name: stop seeding
+//	    when:
+//	      all:
+//	        - started: true
+//	        - ratio: ">= 2"
+//	        - seeding_time: "> 7d"
+//	        - not: {label: keep}
+//	    then: [stop]
+//	  - name: clean up unregistered
+//	    when: {unregistered: true}
+//	    then: [remove]
+//	  - name: archive
+//	    when: {label: done, directory: /mnt/fast}
+//	    then:
+//	      - move: /mnt/archive
+//	      - label: archived
+//
+// Each condition is a map with one key for each of the built in conditions: all and any take a list of conditions,
+// not takes a condition, ratio, seeding_time and size take a comparison such as ">= 2", label, message, tracker and
+// directory take a string, and complete, started and unregistered take true or false. A map with several keys matches
+// downloads meeting all of them. Durations may be given in days, as in 7d, as well as anything time.ParseDuration
+// understands. The actions are stop, start, remove, and maps of move or label to a string.
+func ParseYAML(r io.Reader) ([]Rule, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var f file
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	rules := make([]Rule, 0, len(f.Rules))
+	for i, n := range f.Rules {
+		rule, err := n.rule()
+		if err != nil {
+			if n.Name == "" {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("rule %q: %w", n.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (n *ruleNode) rule() (Rule, error) {
+	if n.Name == "" {
+		return Rule{}, fmt.Errorf("%w: missing name", ErrInvalidRule)
+	}
+	if n.When.IsZero() {
+		return Rule{}, fmt.Errorf("%w: missing when", ErrInvalidRule)
+	}
+	if len(n.Then) == 0 {
+		return Rule{}, fmt.Errorf("%w: missing then", ErrInvalidRule)
+	}
+
+	when, err := parseCondition(&n.When)
+	if err != nil {
+		return Rule{}, err
+	}
+	rule := Rule{Name: n.Name, When: when}
+	for i := range n.Then {
+		a, err := parseAction(&n.Then[i])
+		if err != nil {
+			return Rule{}, err
+		}
+		rule.Then = append(rule.Then, a)
+	}
+	return rule, nil
+}
+
+func parseCondition(n *yaml.Node) (Condition, error) {
+	if n.Kind != yaml.MappingNode || len(n.Content) == 0 {
+		return nil, nodeError(n, "a condition must be a map")
+	}
+
+	conds := make([]Condition, 0, len(n.Content)/2)
+	for i := 0; i < len(n.Content); i += 2 {
+		c, err := parseConditionKey(n.Content[i].Value, n.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+	}
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+	return All(conds...), nil
+}
+
+func parseConditionKey(key string, v *yaml.Node) (Condition, error) {
+	switch key {
+	case "all", "any":
+		if v.Kind != yaml.SequenceNode {
+			return nil, nodeError(v, key+" takes a list of conditions")
+		}
+		conds := make([]Condition, 0, len(v.Content))
+		for _, c := range v.Content {
+			cond, err := parseCondition(c)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, cond)
+		}
+		if key == "all" {
+			return All(conds...), nil
+		}
+		return Any(conds...), nil
+	case "not":
+		cond, err := parseCondition(v)
+		if err != nil {
+			return nil, err
+		}
+		return Not(cond), nil
+	case "ratio":
+		op, value, err := parseComparison(v)
+		if err != nil {
+			return nil, err
+		}
+		r, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, nodeError(v, err.Error())
+		}
+		return Ratio(op, r), nil
+	case "seeding_time":
+		op, value, err := parseComparison(v)
+		if err != nil {
+			return nil, err
+		}
+		d, err := parseDuration(value)
+		if err != nil {
+			return nil, nodeError(v, err.Error())
+		}
+		return SeedingTime(op, d), nil
+	case "size":
+		op, value, err := parseComparison(v)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, nodeError(v, err.Error())
+		}
+		return Size(op, size), nil
+	case "label":
+		return stringCondition(v, Label)
+	case "message":
+		return stringCondition(v, MessageContains)
+	case "tracker":
+		return stringCondition(v, TrackerContains)
+	case "directory":
+		return stringCondition(v, Under)
+	case "complete":
+		return boolCondition(v, Complete())
+	case "started":
+		return boolCondition(v, Started())
+	case "unregistered":
+		return boolCondition(v, Unregistered())
+	default:
+		return nil, nodeError(v, "unknown condition "+strconv.Quote(key))
+	}
+}
+
+func parseAction(n *yaml.Node) (Action, error) {
+	if n.Kind == yaml.ScalarNode {
+		switch n.Value {
+		case "stop":
+			return Stop(), nil
+		case "start":
+			return Start(), nil
+		case "remove":
+			return Remove(), nil
+		default:
+			return nil, nodeError(n, "unknown action "+strconv.Quote(n.Value))
+		}
+	}
+
+	if n.Kind != yaml.MappingNode || len(n.Content) != 2 || n.Content[1].Kind != yaml.ScalarNode {
+		return nil, nodeError(n, "an action is a name, or a map of one name to a string")
+	}
+	key, value := n.Content[0].Value, n.Content[1].Value
+	switch key {
+	case "move":
+		return MoveTo(value), nil
+	case "label":
+		return SetLabel(value), nil
+	default:
+		return nil, nodeError(n, "unknown action "+strconv.Quote(key))
+	}
+}
+
+// parseComparison splits a comparison such as ">= 2" into its operator and value
+func parseComparison(n *yaml.Node) (Op, string, error) {
+	if n.Kind != yaml.ScalarNode {
+		return "", "", nodeError(n, `expected a comparison such as ">= 2"`)
+	}
+	s := strings.TrimSpace(n.Value)
+	for _, op := range allOps {
+		if value, ok := strings.CutPrefix(s, string(op)); ok {
+			return op, strings.TrimSpace(value), nil
+		}
+	}
+	return "", "", nodeError(n, fmt.Sprintf(`%q needs a comparison operator, such as ">= %s"`, s, s))
+}
+
+// parseDuration is time.ParseDuration, plus whole days such as 7d
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * day, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func stringCondition(n *yaml.Node, cond func(string) Condition) (Condition, error) {
+	if n.Kind != yaml.ScalarNode || n.Value == "" {
+		return nil, nodeError(n, "expected a string")
+	}
+	return cond(n.Value), nil
+}
+
+func boolCondition(n *yaml.Node, cond Condition) (Condition, error) {
+	var b bool
+	if err := n.Decode(&b); err != nil {
+		return nil, nodeError(n, "expected true or false")
+	}
+	if !b {
+		return Not(cond), nil
+	}
+	return cond, nil
+}
+
+func nodeError(n *yaml.Node, msg string) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidRule, n.Line, msg)
+}
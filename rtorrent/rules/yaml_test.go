@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+rules:
+  - name: stop seeding
+    when:
+      all:
+        - started: true
+        - ratio: ">= 2"
+        - seeding_time: "> 7d"
+        - not: {label: keep}
+    then: [stop]
+  - name: clean up unregistered
+    when: {unregistered: true, complete: true}
+    then: [remove]
+  - name: archive
+    when:
+      any:
+        - size: "> 1073741824"
+        - directory: /mnt/slow
+    then:
+      - move: /mnt/archive
+      - label: archived
+`
+
+func TestParseYAML(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ParseYAML(strings.NewReader(testRules))
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	var (
+		names   []string
+		actions []string
+	)
+	for _, r := range rules {
+		names = append(names, r.Name)
+		for _, a := range r.Then {
+			actions = append(actions, a.String())
+		}
+		ok, err := r.When.Match(t.Context(), NewSubject(seeder(), nil, testNow))
+		require.NoError(t, err)
+		assert.True(t, ok, r.Name)
+	}
+	assert.Equal(t, []string{"stop seeding", "clean up unregistered", "archive"}, names)
+	assert.Equal(t, []string{"stop", "remove", "move to /mnt/archive", "label archived"}, actions)
+}
+
+func TestParseYAMLBoolConditions(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ParseYAML(strings.NewReader(`
+rules:
+  - name: incomplete
+    when: {complete: false}
+    then: [start]
+`))
+	require.NoError(t, err)
+	ok, err := rules[0].When.Match(t.Context(), NewSubject(seeder(), nil, testNow))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{"no name", "rules: [{when: {started: true}, then: [stop]}]", "rule 1: invalid rule: missing name"},
+		{"no condition", "rules: [{name: a, then: [stop]}]", "missing when"},
+		{"no actions", "rules: [{name: a, when: {started: true}}]", "missing then"},
+		{"unknown field", "rules: [{name: a, whne: {started: true}, then: [stop]}]", "field whne not found"},
+		{"unknown condition", "rules: [{name: a, when: {seeders: 3}, then: [stop]}]", `unknown condition "seeders"`},
+		{"unknown action", "rules: [{name: a, when: {started: true}, then: [pause]}]", `unknown action "pause"`},
+		{"no operator", "rules: [{name: a, when: {ratio: 2}, then: [stop]}]", `"2" needs a comparison operator`},
+		{"bad duration", `rules: [{name: a, when: {seeding_time: "> xd"}, then: [stop]}]`, `invalid duration "xd"`},
+		{"bad bool", "rules: [{name: a, when: {started: maybe}, then: [stop]}]", "expected true or false"},
+		{"list condition", "rules: [{name: a, when: [started], then: [stop]}]", "a condition must be a map"},
+	}
+	for _, tt := range tests {
+		_, err := ParseYAML(strings.NewReader(tt.yaml))
+		require.Error(t, err, tt.name)
+		assert.Contains(t, err.Error(), tt.err, tt.name)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	d, err := parseDuration("7d")
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, d)
+
+	d, err = parseDuration("36h")
+	require.NoError(t, err)
+	assert.Equal(t, 36*time.Hour, d)
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rules: [{name: a, when: {label: x}, then: [start]}]"), 0o600))
+
+	rules, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	require.NoError(t, os.WriteFile(path, []byte("rules: [{name: a}]"), 0o600))
+	_, err = LoadFile(path)
+	require.ErrorIs(t, err, ErrInvalidRule)
+	assert.Contains(t, err.Error(), path)
+
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,319 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/aauren/rtorrent/rtorrent/metainfo"
+)
+
+// BackupExt is appended to a session file's name for the copy of it Apply keeps before rewriting it
+const BackupExt = ".bak"
+
+// numberedCustom are the custom keys rTorrent keeps at the top of a .rtorrent file rather than in its custom dict
+var numberedCustom = []string{"custom1", "custom2", "custom3", "custom4", "custom5"}
+
+// Edit describes the changes Apply makes to a download's session files. Whatever is left nil is left alone.
+type Edit struct {
+	// Directory maps a download's directory, and its directory_base, to a new one. ReplacePrefix covers the usual
+	// case of storage moving wholesale.
+	Directory func(dir string) string
+
+	// Custom sets custom values. The keys custom1 through custom5 set the numbered slots, and any other key sets the
+	// value d.custom.set would.
+	Custom map[string]string
+
+	// Tracker maps each tracker URL, in both the .torrent and the resume data, to a new one. Only the announce URLs
+	// outside the torrent's info dict change, so its info-hash stays the same.
+	Tracker func(url string) string
+}
+
+// ReplacePrefix returns a Directory function that moves any path under oldDir to the same place under newDir, and
+// leaves other paths alone. Prefixes only match whole path elements, so /mnt/old doesn't match /mnt/older.
+func ReplacePrefix(oldDir, newDir string) func(string) string {
+	oldDir, newDir = filepath.Clean(oldDir), filepath.Clean(newDir)
+	return func(dir string) string {
+		rest, ok := strings.CutPrefix(filepath.Clean(dir), oldDir)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, string(filepath.Separator))) {
+			return dir
+		}
+		return newDir + rest
+	}
+}
+
+// Apply makes e to every download in the session directory dir, returning the info-hashes of those it changed. As
+// with Read, a download that can't be rewritten doesn't stop the others.
+//
+// rTorrent overwrites its session files from memory as it runs, so they should only be edited while it is stopped.
+func Apply(dir string, e Edit) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+TorrentExt))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+
+	var (
+		changed []string
+		errs    []error
+	)
+	for _, path := range paths {
+		hash := strings.TrimSuffix(filepath.Base(path), TorrentExt)
+		ok, err := ApplyEntry(dir, hash, e)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			changed = append(changed, hash)
+		}
+	}
+	return changed, errors.Join(errs...)
+}
+
+// ApplyEntry makes e to the download with the given info-hash in the session directory dir, reporting whether any
+// of its files changed. Each file that changes is first copied aside with BackupExt appended to its name, replacing
+// any older backup, and is then replaced atomically, so a crash leaves either the old file or the new one in place.
+func ApplyEntry(dir, infoHash string, e Edit) (bool, error) {
+	base := filepath.Join(dir, strings.ToUpper(infoHash)+TorrentExt)
+
+	var changed bool
+	for _, f := range []struct {
+		path string
+		read func(string) (map[string]any, error)
+		edit func(map[string]any)
+	}{
+		{base + ResumeExt, readDict, e.editResume},
+		{base + RTorrentExt, readDict, e.editState},
+		{base, readTorrent, e.editTorrent},
+	} {
+		ok, err := rewriteFile(f.path, f.read, f.edit)
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || ok
+	}
+	return changed, nil
+}
+
+// ApplyLive is Apply's Directory edit for a running instance. It points every download whose directory the directory
+// function maps somewhere new at that new directory, returning the info-hashes of those it changed. It doesn't move any
+// data. rTorrent won't change the directory of an open download, so any that are open come back in the error rather
+// than being changed, and the rest are changed regardless.
+func ApplyLive(ctx context.Context, ds *rtorrent.DownloadService, directory func(string) string) ([]string, error) {
+	downloads, err := ds.Downloads(ctx, "", []rtorrent.DownloadField{
+		rtorrent.DownloadFieldDirectory, rtorrent.DownloadFieldIsMultiFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		changed []string
+		errs    []error
+	)
+	for _, d := range downloads {
+		hash, dir, target, multi, err := liveTarget(d, directory)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hash, err))
+			continue
+		}
+		if target == "" {
+			continue
+		}
+		// A multi-file download gets its exact new directory, as Move gives it, in case it isn't named after the torrent
+		setDirectory := ds.SetDirectory
+		if multi {
+			setDirectory = ds.SetDirectoryBase
+		}
+		if err := setDirectory(ctx, hash, target); err != nil {
+			errs = append(errs, fmt.Errorf("%s: moving %s to %s: %w", hash, dir, target, err))
+			continue
+		}
+		changed = append(changed, hash)
+	}
+	return changed, errors.Join(errs...)
+}
+
+// liveTarget works out where the directory function says d's directory should be, or "" if that's where it already is,
+// and whether d is a multi-file download, whose directory is its own rather than the one holding its file
+func liveTarget(d *rtorrent.Download, directory func(string) string) (hash, dir, target string, multi bool, err error) {
+	if hash, err = d.Hash(); err != nil {
+		return "", "", "", false, err
+	}
+	if dir, err = d.Directory(); err != nil {
+		return hash, "", "", false, err
+	}
+	if multi, err = d.IsMultiFile(); err != nil {
+		return hash, dir, "", false, err
+	}
+	if target = directory(dir); target == dir {
+		return hash, dir, "", multi, nil
+	}
+	return hash, dir, target, multi, nil
+}
+
+func (e *Edit) editState(state map[string]any) {
+	if e.Directory != nil {
+		for _, key := range []string{"directory", "directory_base"} {
+			if dir, ok := state[key].(string); ok && dir != "" {
+				state[key] = e.Directory(dir)
+			}
+		}
+	}
+
+	for key, value := range e.Custom {
+		if slices.Contains(numberedCustom, key) {
+			state[key] = value
+			continue
+		}
+		custom, ok := state["custom"].(map[string]any)
+		if !ok {
+			custom = make(map[string]any)
+			state["custom"] = custom
+		}
+		custom[key] = value
+	}
+}
+
+func (e *Edit) editResume(resume map[string]any) {
+	if e.Tracker == nil {
+		return
+	}
+	trackers, ok := resume["trackers"].(map[string]any)
+	if !ok {
+		return
+	}
+	rewritten := make(map[string]any, len(trackers))
+	for u, v := range trackers {
+		rewritten[e.Tracker(u)] = v
+	}
+	resume["trackers"] = rewritten
+}
+
+func (e *Edit) editTorrent(torrent map[string]any) {
+	if e.Tracker == nil {
+		return
+	}
+	if announce, ok := torrent["announce"].(string); ok {
+		torrent["announce"] = e.Tracker(announce)
+	}
+	for _, tier := range listOf(torrent["announce-list"]) {
+		tier, ok := tier.([]any)
+		if !ok {
+			continue
+		}
+		for i, u := range tier {
+			if s, ok := u.(string); ok {
+				tier[i] = e.Tracker(s)
+			}
+		}
+	}
+}
+
+// readTorrent decodes a .torrent file's top level dict, keeping the info dict's bytes as they are so re-encoding it
+// can't change the torrent's info-hash
+func readTorrent(path string) (map[string]any, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	torrent := make(map[string]any)
+	err = metainfo.NewDecoder(f).DecodeDict(func(key string, d *metainfo.Decoder) error {
+		var err error
+		if key == "info" {
+			torrent[key], err = d.DecodeRaw()
+		} else {
+			torrent[key], err = d.Decode()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return torrent, nil
+}
+
+// rewriteFile reads the session file at path, edits it and, if that changed it, backs it up and replaces it. Missing
+// files are left missing.
+func rewriteFile(path string, read func(string) (map[string]any, error), edit func(map[string]any)) (bool, error) {
+	original, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	v, err := read(path)
+	if err != nil {
+		return false, err
+	}
+	// We compare against the file re-encoded rather than as it was, since re-encoding alone may reorder a file that
+	// didn't sort its keys as bencode asks, and that isn't a change worth making
+	before, err := metainfo.Marshal(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	edit(v)
+	edited, err := metainfo.Marshal(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if bytes.Equal(before, edited) {
+		return false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if err := writeFileAtomic(path+BackupExt, original, info.Mode()); err != nil {
+		return false, fmt.Errorf("backing up %s: %w", path, err)
+	}
+	if err := writeFileAtomic(path, edited, info.Mode()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeFileAtomic writes data to a temporary file alongside path and renames it into place, so that readers only
+// ever see the old contents or the new
+func writeFileAtomic(path string, data []byte, mode os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func listOf(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
@@ -0,0 +1,178 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplacePrefix(t *testing.T) {
+	t.Parallel()
+
+	replace := ReplacePrefix("/mnt/old/", "/mnt/new")
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"/mnt/old", "/mnt/new"},
+		{"/mnt/old/", "/mnt/new"},
+		{"/mnt/old/tv/show", "/mnt/new/tv/show"},
+		{"/mnt/older/tv", "/mnt/older/tv"},
+		{"/srv/mnt/old", "/srv/mnt/old"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, replace(tt.in), tt.in)
+	}
+}
+
+func TestApplyEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hash := writeTorrent(t, dir, "show")
+	base := filepath.Join(dir, hash+TorrentExt)
+	writeBencode(t, base+RTorrentExt, map[string]any{
+		"directory":      "/mnt/old/tv/show",
+		"directory_base": "/mnt/old/tv/show",
+		"custom1":        "tv",
+		"custom":         map[string]any{"addtime": "1"},
+		"state":          1,
+	})
+	writeBencode(t, base+ResumeExt, map[string]any{
+		"bitfield": 4,
+		"trackers": map[string]any{testAnnounce: map[string]any{"enabled": 1}},
+	})
+	originalState, err := os.ReadFile(base + RTorrentExt)
+	require.NoError(t, err)
+
+	changed, err := ApplyEntry(dir, hash, Edit{
+		Directory: ReplacePrefix("/mnt/old", "/mnt/new"),
+		Custom:    map[string]string{"custom1": "archive", "seedbox": "yes"},
+		Tracker: func(u string) string {
+			return strings.Replace(u, "tracker.example", "tracker.example.org", 1)
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	e, err := ReadEntry(dir, hash)
+	require.NoError(t, err)
+	assert.Equal(t, hash, e.MetaInfo.InfoHash(), "rewriting the announce URLs must not change the info-hash")
+	assert.Equal(t, "/mnt/new/tv/show", must(t, e.Download.Directory))
+	assert.Equal(t, "/mnt/new/tv/show", must(t, e.Download.DirectoryBase))
+	assert.Equal(t, "archive", must(t, func() (string, error) { return e.Download.Custom(1) }))
+	assert.Equal(t, map[string]string{"addtime": "1", "seedbox": "yes"}, e.Custom)
+	assert.True(t, must(t, e.Download.IsStarted), "fields the edit doesn't touch are kept")
+
+	require.Len(t, e.Trackers, 2)
+	assert.Equal(t, "http://tracker.example.org/announce", must(t, e.Trackers[0].URL))
+	assert.True(t, must(t, e.Trackers[0].IsEnabled), "the resume entry followed the rewritten URL")
+	assert.Equal(t, testBackup, must(t, e.Trackers[1].URL))
+
+	backup, err := os.ReadFile(base + RTorrentExt + BackupExt)
+	require.NoError(t, err)
+	assert.Equal(t, originalState, backup)
+
+	files, err := filepath.Glob(filepath.Join(dir, ".*"))
+	require.NoError(t, err)
+	assert.Empty(t, files, "no temporary files are left behind")
+}
+
+func TestApplyEntryUnchanged(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hash := writeTorrent(t, dir, "a")
+	writeBencode(t, filepath.Join(dir, hash+TorrentExt+RTorrentExt), map[string]any{"directory": "/srv/a"})
+
+	changed, err := ApplyEntry(dir, hash, Edit{
+		Directory: ReplacePrefix("/mnt/old", "/mnt/new"),
+		Tracker:   func(u string) string { return u },
+	})
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	backups, err := filepath.Glob(filepath.Join(dir, "*"+BackupExt))
+	require.NoError(t, err)
+	assert.Empty(t, backups, "nothing is backed up when nothing changes")
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	var moved []string
+	for _, name := range []string{"a", "b"} {
+		hash := writeTorrent(t, dir, name)
+		writeBencode(t, filepath.Join(dir, hash+TorrentExt+RTorrentExt), map[string]any{"directory": "/mnt/old/" + name})
+		moved = append(moved, hash)
+	}
+	hashBad := writeTorrent(t, dir, "bad")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hashBad+TorrentExt+RTorrentExt), []byte("garbage"), 0o600))
+
+	changed, err := Apply(dir, Edit{Directory: ReplacePrefix("/mnt/old", "/mnt/new")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), hashBad)
+	assert.ElementsMatch(t, moved, changed, "the unreadable download doesn't stop the others")
+}
+
+func TestApplyLive(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errOpen = errors.New("cannot change the directory of an open download")
+		hashes  = []string{
+			strings.Repeat("A", 40), strings.Repeat("B", 40), strings.Repeat("C", 40), strings.Repeat("D", 40),
+			strings.Repeat("E", 40),
+		}
+
+		mu  sync.Mutex
+		set = make(map[string]string)
+	)
+	c, err := rtorrent.New("http://127.0.0.1:1/RPC2", nil, rtorrent.WithInterceptors(
+		func(_ context.Context, method string, args []any, reply any, _ rtorrent.Invoker) error {
+			switch method {
+			case "d.multicall2":
+				*reply.(*[][]any) = [][]any{
+					{hashes[0], "/mnt/old/isos", int64(0)},
+					{hashes[1], "/mnt/old/tv/show", int64(1)},
+					{hashes[2], "/srv/elsewhere", int64(0)},
+					{hashes[3], "/mnt/old/open", int64(0)},
+					{hashes[4], "/mnt/old/tv/renamed", int64(1)},
+				}
+				return nil
+			case "d.directory.set", "d.directory_base.set":
+				if args[0] == hashes[3] {
+					return errOpen
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				set[args[0].(string)] = method + " " + args[1].(string)
+				return nil
+			default:
+				return errors.New("unexpected method " + method)
+			}
+		}))
+	require.NoError(t, err)
+	defer c.Close()
+
+	changed, err := ApplyLive(t.Context(), &rtorrent.DownloadService{C: c}, ReplacePrefix("/mnt/old", "/mnt/new"))
+	require.ErrorIs(t, err, errOpen)
+	assert.Contains(t, err.Error(), hashes[3])
+	assert.Equal(t, []string{hashes[0], hashes[1], hashes[4]}, changed)
+
+	// Multi-file downloads are given their own directory, so one not named after its torrent keeps its name
+	assert.Equal(t, map[string]string{
+		hashes[0]: "d.directory.set /mnt/new/isos",
+		hashes[1]: "d.directory_base.set /mnt/new/tv/show",
+		hashes[4]: "d.directory_base.set /mnt/new/tv/renamed",
+	}, set)
+}
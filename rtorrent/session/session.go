@@ -0,0 +1,256 @@
+// Package session reads rTorrent's session directory, so what an instance was doing can be known while it is down.
+//
+// rTorrent keeps three files per download in its session directory, each named for the download's info-hash: the
+// .torrent itself, a .torrent.rtorrent file with rTorrent's own state (started or not, where the data lives, custom
+// values) and a .torrent.libtorrent_resume file with libtorrent's (which chunks are done, the tracker list). Read
+// gathers them back up into the same Download and Tracker types the live services return, so code written against a
+// running instance can fall back to its session files:
+//
+//	entries, err := session.Read("/home/me/.session")
+//	for _, e := range entries {
+//		dir, _ := e.Download.Directory()
+//		...
+//	}
+//
+// rTorrent rewrites these files as it runs, so they are best read while it is stopped, or at least treated as a
+// snapshot from its last save.
+package session
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/aauren/rtorrent/rtorrent/metainfo"
+)
+
+// Session file extensions, the latter two being appended to the first
+const (
+	TorrentExt  = ".torrent"
+	RTorrentExt = ".rtorrent"
+	ResumeExt   = ".libtorrent_resume"
+)
+
+// pieceHashLen is the size of each SHA-1 piece hash in a v1 info dict
+const pieceHashLen = 20
+
+// rtorrentFields maps the keys of a .rtorrent file to the download fields they hold
+var rtorrentFields = map[string]rtorrent.DownloadField{
+	"state":            rtorrent.DownloadFieldState,
+	"state_changed":    rtorrent.DownloadFieldStateChanged,
+	"complete":         rtorrent.DownloadFieldComplete,
+	"directory":        rtorrent.DownloadFieldDirectory,
+	"directory_base":   rtorrent.DownloadFieldDirectoryBase,
+	"tied_to_file":     rtorrent.DownloadFieldTiedToFile,
+	"custom1":          rtorrent.DownloadFieldCustom1,
+	"custom2":          rtorrent.DownloadFieldCustom2,
+	"custom3":          rtorrent.DownloadFieldCustom3,
+	"custom4":          rtorrent.DownloadFieldCustom4,
+	"custom5":          rtorrent.DownloadFieldCustom5,
+	"total_uploaded":   rtorrent.DownloadFieldUpTotal,
+	"total_downloaded": rtorrent.DownloadFieldDownTotal,
+	"chunks_done":      rtorrent.DownloadFieldCompletedChunks,
+}
+
+// Entry is everything the session directory holds about one download.
+type Entry struct {
+	// Download carries the fields the session files record: hash, name, state, state_changed, complete, directory,
+	// directory_base, tied_to_file, custom1 through custom5, size_bytes, size_chunks, completed_chunks, up.total and
+	// down.total. Fields rTorrent didn't save, such as a download's message, are absent.
+	Download *rtorrent.Download
+
+	// Trackers lists the torrent's trackers followed by any added to the download since, carrying the url, type,
+	// is_enabled and is_extra_tracker fields
+	Trackers []*rtorrent.Tracker
+
+	// MetaInfo is the parsed .torrent file
+	MetaInfo *metainfo.MetaInfo
+
+	// Custom holds values set with d.custom.set, by key. The numbered slots are on the Download.
+	Custom map[string]string
+}
+
+// Read reads every download in the session directory dir, ordered by info-hash. A download whose files can't be read
+// doesn't stop the others being read, so Read returns what it could along with an error naming each one it couldn't.
+func Read(dir string) ([]*Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+TorrentExt))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+
+	var (
+		entries []*Entry
+		errs    []error
+	)
+	for _, path := range paths {
+		e, err := ReadEntry(dir, strings.TrimSuffix(filepath.Base(path), TorrentExt))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, errors.Join(errs...)
+}
+
+// ReadEntry reads the download with the given info-hash from the session directory dir. Only the .torrent file is
+// required, since rTorrent may not have written the others yet.
+func ReadEntry(dir, infoHash string) (*Entry, error) {
+	base := filepath.Join(dir, strings.ToUpper(infoHash)+TorrentExt)
+
+	mi, err := metainfo.LoadFile(base)
+	if err != nil {
+		return nil, err
+	}
+	state, err := readDict(base + RTorrentExt)
+	if err != nil {
+		return nil, err
+	}
+	resume, err := readDict(base + ResumeExt)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[rtorrent.DownloadField]any{
+		rtorrent.DownloadFieldHash:       strings.ToUpper(infoHash),
+		rtorrent.DownloadFieldName:       mi.Info.Name,
+		rtorrent.DownloadFieldSizeBytes:  mi.TotalLength(),
+		rtorrent.DownloadFieldSizeChunks: sizeChunks(mi),
+	}
+	for key, field := range rtorrentFields {
+		if v, ok := state[key]; ok {
+			data[field] = v
+		}
+	}
+	// libtorrent's bitfield is the authority on which chunks are done, rTorrent's count is only what it last saw
+	if done, ok := completedChunks(resume["bitfield"]); ok {
+		data[rtorrent.DownloadFieldCompletedChunks] = done
+	}
+
+	e := &Entry{
+		Download: rtorrent.NewDownload(data),
+		Trackers: trackers(strings.ToUpper(infoHash), mi, resume),
+		MetaInfo: mi,
+	}
+	if custom, ok := state["custom"].(map[string]any); ok {
+		e.Custom = make(map[string]string, len(custom))
+		for k, v := range custom {
+			if s, ok := v.(string); ok {
+				e.Custom[k] = s
+			}
+		}
+	}
+	return e, nil
+}
+
+// readDict decodes the bencoded dict at path, giving back an empty one if the file doesn't exist
+func readDict(path string) (map[string]any, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := metainfo.Unmarshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dict, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: not a dict", path, metainfo.ErrSyntax)
+	}
+	return dict, nil
+}
+
+// sizeChunks counts a torrent's chunks, from its piece hashes where it has them and its length where it doesn't
+func sizeChunks(mi *metainfo.MetaInfo) int64 {
+	if mi.HasV1() {
+		return int64(len(mi.Info.Pieces) / pieceHashLen)
+	}
+	var total int64
+	for _, f := range mi.Info.Files {
+		total += f.Length
+	}
+	return (total + mi.Info.PieceLength - 1) / mi.Info.PieceLength
+}
+
+// completedChunks counts the chunks done according to a resume bitfield. libtorrent saves a full or empty bitfield
+// as the number of chunks done rather than spelling out every bit.
+func completedChunks(bitfield any) (int64, bool) {
+	switch v := bitfield.(type) {
+	case int64:
+		return v, true
+	case string:
+		var done int64
+		for i := range len(v) {
+			done += int64(bits.OnesCount8(v[i]))
+		}
+		return done, true
+	default:
+		return 0, false
+	}
+}
+
+// trackers lists the torrent's trackers in the order rTorrent does, those from the torrent first and then those
+// added to the download since, with the enabled state from the resume data
+func trackers(infoHash string, mi *metainfo.MetaInfo, resume map[string]any) []*rtorrent.Tracker {
+	saved, _ := resume["trackers"].(map[string]any)
+
+	urls := mi.Trackers()
+	var extra []string
+	for u := range saved {
+		if !slices.Contains(urls, u) && !strings.HasPrefix(u, "dht://") {
+			extra = append(extra, u)
+		}
+	}
+	slices.Sort(extra)
+
+	ts := make([]*rtorrent.Tracker, 0, len(urls)+len(extra))
+	for i, u := range slices.Concat(urls, extra) {
+		data := map[rtorrent.TrackerField]any{
+			rtorrent.FieldURL:            u,
+			rtorrent.FieldIsEnabled:      int64(1),
+			rtorrent.FiledIsExtraTracker: int64(0),
+		}
+		if typ, ok := trackerType(u); ok {
+			data[rtorrent.FieldType] = int64(typ)
+		}
+		if s, ok := saved[u].(map[string]any); ok {
+			if enabled, ok := s["enabled"]; ok {
+				data[rtorrent.FieldIsEnabled] = enabled
+			}
+			if isExtra, ok := s["extra_tracker"]; ok {
+				data[rtorrent.FiledIsExtraTracker] = isExtra
+			}
+		}
+		ts = append(ts, rtorrent.NewTracker(rtorrent.NewTrackerWithIndex(infoHash, i), data))
+	}
+	return ts
+}
+
+// trackerType works out a tracker's type from its URL scheme, as rTorrent does when it loads a torrent
+func trackerType(announce string) (rtorrent.TrackerType, bool) {
+	u, err := url.Parse(announce)
+	if err != nil {
+		return 0, false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return rtorrent.TypeHTTP, true
+	case "udp":
+		return rtorrent.TypeUDP, true
+	case "dht":
+		return rtorrent.TypeDHT, true
+	default:
+		return 0, false
+	}
+}
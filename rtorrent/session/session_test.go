@@ -0,0 +1,177 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aauren/rtorrent/rtorrent"
+	"github.com/aauren/rtorrent/rtorrent/metainfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAnnounce = "http://tracker.example/announce"
+	testBackup   = "udp://backup.example:6969"
+	testExtra    = "https://extra.example/announce"
+)
+
+// writeBencode writes v, bencoded, to path
+func writeBencode(t *testing.T, path string, v any) {
+	t.Helper()
+
+	data, err := metainfo.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+// writeTorrent writes a four chunk torrent into dir, returning its info-hash
+func writeTorrent(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name+".tmp")
+	writeBencode(t, path, map[string]any{
+		"announce":      testAnnounce,
+		"announce-list": []any{[]any{testAnnounce}, []any{testBackup}},
+		"info": map[string]any{
+			"name":         name,
+			"length":       4 * 16384,
+			"piece length": 16384,
+			"pieces":       strings.Repeat("\xaa", 4*pieceHashLen),
+		},
+	})
+	mi, err := metainfo.LoadFile(path)
+	require.NoError(t, err)
+
+	hash := mi.InfoHash()
+	require.NoError(t, os.Rename(path, filepath.Join(dir, hash+TorrentExt)))
+	return hash
+}
+
+func TestReadEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hash := writeTorrent(t, dir, "ubuntu.iso")
+	base := filepath.Join(dir, hash+TorrentExt)
+
+	writeBencode(t, base+RTorrentExt, map[string]any{
+		"state":          1,
+		"state_changed":  1700000000,
+		"complete":       0,
+		"directory":      "/mnt/old/ubuntu",
+		"custom1":        "linux",
+		"custom":         map[string]any{"addtime": "1699999999"},
+		"total_uploaded": 2048,
+		"chunks_done":    1,
+	})
+	writeBencode(t, base+ResumeExt, map[string]any{
+		// Chunks 0 and 2 of 4 are done, which the bitfield gets the final say on over chunks_done
+		"bitfield": "\xa0",
+		"trackers": map[string]any{
+			testBackup:      map[string]any{"enabled": 0},
+			testExtra:       map[string]any{"enabled": 1, "extra_tracker": 1},
+			"dht://" + hash: map[string]any{"enabled": 1},
+		},
+	})
+
+	e, err := ReadEntry(dir, strings.ToLower(hash))
+	require.NoError(t, err)
+
+	d := e.Download
+	assert.Equal(t, hash, must(t, d.Hash))
+	assert.Equal(t, "ubuntu.iso", must(t, d.Name))
+	assert.True(t, must(t, d.IsStarted))
+	assert.False(t, must(t, d.IsComplete))
+	assert.Equal(t, "/mnt/old/ubuntu", must(t, d.Directory))
+	assert.Equal(t, "linux", must(t, func() (string, error) { return d.Custom(1) }))
+	assert.Equal(t, 2048, must(t, d.UpTotal))
+	assert.Equal(t, 4*16384, must(t, d.SizeBytes))
+	assert.InDelta(t, 0.5, must(t, d.Progress), 0)
+	assert.Equal(t, map[string]string{"addtime": "1699999999"}, e.Custom)
+
+	_, err = d.Message()
+	require.ErrorIs(t, err, rtorrent.ErrNoField, "rTorrent doesn't save messages")
+
+	require.Len(t, e.Trackers, 3, "the DHT pseudo-tracker isn't a real tracker")
+	type tracker struct {
+		url     string
+		typ     rtorrent.TrackerType
+		enabled bool
+		extra   bool
+	}
+	var got []tracker
+	for i, tr := range e.Trackers {
+		assert.Equal(t, rtorrent.NewTrackerWithIndex(hash, i), tr.TrackerIndex())
+		got = append(got, tracker{must(t, tr.URL), must(t, tr.Type), must(t, tr.IsEnabled), must(t, tr.IsExtraTracker)})
+	}
+	assert.Equal(t, []tracker{
+		{testAnnounce, rtorrent.TypeHTTP, true, false},
+		{testBackup, rtorrent.TypeUDP, false, false},
+		{testExtra, rtorrent.TypeHTTP, true, true},
+	}, got)
+}
+
+func TestReadEntryFullBitfield(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hash := writeTorrent(t, dir, "a")
+	writeBencode(t, filepath.Join(dir, hash+TorrentExt+ResumeExt), map[string]any{"bitfield": 4})
+
+	e, err := ReadEntry(dir, hash)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, must(t, e.Download.Progress), 0)
+}
+
+func TestReadEntryTorrentOnly(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hash := writeTorrent(t, dir, "a")
+
+	e, err := ReadEntry(dir, hash)
+	require.NoError(t, err)
+	assert.Equal(t, "a", must(t, e.Download.Name))
+	_, err = e.Download.Directory()
+	require.ErrorIs(t, err, rtorrent.ErrNoField)
+	assert.Nil(t, e.Custom)
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hashA := writeTorrent(t, dir, "a")
+	hashB := writeTorrent(t, dir, "b")
+	hashBad := writeTorrent(t, dir, "bad")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hashBad+TorrentExt+RTorrentExt), []byte("garbage"), 0o600))
+
+	entries, err := Read(dir)
+	require.ErrorIs(t, err, metainfo.ErrSyntax)
+	assert.Contains(t, err.Error(), hashBad)
+
+	var hashes []string
+	for _, e := range entries {
+		hashes = append(hashes, must(t, e.Download.Hash))
+	}
+	assert.ElementsMatch(t, []string{hashA, hashB}, hashes, "the unreadable download doesn't stop the others")
+}
+
+func TestReadEntryMissing(t *testing.T) {
+	t.Parallel()
+
+	_, err := ReadEntry(t.TempDir(), strings.Repeat("A", 40))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// must unwraps a getter's result, failing the test on error
+func must[T any](t *testing.T, get func() (T, error)) T {
+	t.Helper()
+
+	v, err := get()
+	require.NoError(t, err)
+	return v
+}
@@ -0,0 +1,323 @@
+package rtorrent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// defaultSnapshotFields are the download fields a Snapshot takes unless told otherwise
+var defaultSnapshotFields = []DownloadField{
+	DownloadFieldHash, DownloadFieldName, DownloadFieldState, DownloadFieldComplete, DownloadFieldSizeBytes,
+	DownloadFieldCompletedChunks, DownloadFieldUpTotal, DownloadFieldDownTotal, DownloadFieldRatio, DownloadFieldMessage,
+	DownloadFieldCustom1,
+}
+
+// defaultSnapshotTrackerFields are the tracker fields a Snapshot takes unless told otherwise
+var defaultSnapshotTrackerFields = []TrackerField{FieldURL, FieldIsEnabled, FieldSuccessCounter, FieldFailedCounter}
+
+// SnapshotService takes snapshots of rTorrent's state.
+type SnapshotService struct {
+	C Client
+}
+
+// Globals are rTorrent's totals and rates across every download, in bytes and bytes per second.
+type Globals struct {
+	DownloadTotal int
+	UploadTotal   int
+	DownloadRate  int
+	UploadRate    int
+}
+
+// Snapshot is rTorrent's state at a moment: its globals, and every download and its trackers.
+type Snapshot struct {
+	// Time is halfway through the request for the downloads, whose byte totals Diff works rates out from
+	Time      time.Time
+	Globals   Globals
+	Downloads map[string]*Download
+	// Trackers are the trackers of each download, by info-hash, or nil if the snapshot was taken without them
+	Trackers map[string][]*Tracker
+}
+
+// SnapshotOption configures Snapshot.
+type SnapshotOption func(*snapshotConfig)
+
+type snapshotConfig struct {
+	view          string
+	fields        []DownloadField
+	trackerFields []TrackerField
+}
+
+// WithSnapshotView limits a snapshot to the downloads in view.
+func WithSnapshotView(view string) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.view = view
+	}
+}
+
+// WithSnapshotFields sets the download fields a snapshot takes, in place of its defaults. The hash is always taken.
+func WithSnapshotFields(fields ...DownloadField) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.fields = fields
+	}
+}
+
+// WithSnapshotTrackerFields sets the tracker fields a snapshot takes, in place of its defaults. Giving none leaves
+// trackers out of the snapshot, saving a request.
+func WithSnapshotTrackerFields(fields ...TrackerField) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.trackerFields = fields
+	}
+}
+
+// Snapshot captures rTorrent's state in as few requests as it can, one for the downloads, one for all their trackers
+// and one for each global. rTorrent has no way to read them all at the same instant, so they are read back to back,
+// the downloads first as they matter most to Diff.
+func (s *SnapshotService) Snapshot(ctx context.Context, opts ...SnapshotOption) (*Snapshot, error) {
+	cfg := snapshotConfig{fields: defaultSnapshotFields, trackerFields: defaultSnapshotTrackerFields}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	start := time.Now()
+	downloads, err := (&DownloadService{C: s.C}).Downloads(ctx, cfg.view, cfg.fields)
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{
+		Time:      start.Add(time.Since(start) / 2),
+		Downloads: make(map[string]*Download, len(downloads)),
+	}
+	for _, d := range downloads {
+		hash, err := d.Hash()
+		if err != nil {
+			return nil, err
+		}
+		snap.Downloads[hash] = d
+	}
+
+	if snap.Globals, err = s.Globals(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.trackerFields) > 0 {
+		if snap.Trackers, err = (&TrackerService{C: s.C}).TrackersByDownload(ctx, cfg.view, cfg.trackerFields); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// Globals reads rTorrent's totals and rates, one request each. Unlike the Client methods that read them one at a time,
+// it takes a context, so a limiter's wait or a hung rTorrent can be given up on.
+func (s *SnapshotService) Globals(ctx context.Context) (Globals, error) {
+	var g Globals
+	for _, global := range []struct {
+		method string
+		value  *int
+	}{
+		{"down.total", &g.DownloadTotal},
+		{"up.total", &g.UploadTotal},
+		{"down.rate", &g.DownloadRate},
+		{"up.rate", &g.UploadRate},
+	} {
+		var err error
+		if *global.value, err = s.C.getInt(ctx, global.method, ""); err != nil {
+			return Globals{}, err
+		}
+	}
+	return g, nil
+}
+
+// Rate is how fast a download, or rTorrent as a whole, moved data between two snapshots, in bytes per second.
+type Rate struct {
+	Up   float64
+	Down float64
+}
+
+// FieldChange is a download field whose value changed between two snapshots. Values are as the getters give them, or
+// as rTorrent sent them for values that can't be read.
+type FieldChange struct {
+	Field DownloadField
+	Old   any
+	New   any
+}
+
+// TrackerChange is a tracker field whose value changed between two snapshots, for the tracker with the given URL.
+type TrackerChange struct {
+	URL   string
+	Field TrackerField
+	Old   any
+	New   any
+}
+
+// DownloadChange is how a download in both of two snapshots changed.
+type DownloadChange struct {
+	InfoHash        string
+	Fields          []FieldChange
+	TrackersAdded   []string
+	TrackersRemoved []string
+	Trackers        []TrackerChange
+}
+
+// SnapshotDiff is what changed between two snapshots.
+type SnapshotDiff struct {
+	From    time.Time
+	To      time.Time
+	Added   []string
+	Removed []string
+	Changed []DownloadChange
+	// Rates are the rates of the downloads in both snapshots, by info-hash, for those the snapshots have the byte
+	// totals of and whose totals didn't go down
+	Rates map[string]Rate
+	// Global is rTorrent's overall rate, or nil if its totals went down, as they do when it restarts
+	Global *Rate
+}
+
+// Diff reports the downloads added and removed between snapshots a and b, taken in that order, and what changed in the
+// fields both took of the rest, all sorted by info-hash. It works out rates from the change in the byte totals, which
+// are steadier than the instantaneous rates rTorrent reports.
+func Diff(a, b *Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{From: a.Time, To: b.Time, Rates: make(map[string]Rate)}
+	elapsed := b.Time.Sub(a.Time).Seconds()
+
+	for _, hash := range slices.Sorted(maps.Keys(b.Downloads)) {
+		if _, ok := a.Downloads[hash]; !ok {
+			diff.Added = append(diff.Added, hash)
+		}
+	}
+	for _, hash := range slices.Sorted(maps.Keys(a.Downloads)) {
+		oldD, newD := a.Downloads[hash], b.Downloads[hash]
+		if newD == nil {
+			diff.Removed = append(diff.Removed, hash)
+			continue
+		}
+		change := DownloadChange{InfoHash: hash, Fields: diffDownload(oldD, newD)}
+		if a.Trackers != nil && b.Trackers != nil {
+			change.TrackersAdded, change.TrackersRemoved, change.Trackers = diffTrackers(a.Trackers[hash], b.Trackers[hash])
+		}
+		if len(change.Fields) > 0 || len(change.TrackersAdded) > 0 || len(change.TrackersRemoved) > 0 || len(change.Trackers) > 0 {
+			diff.Changed = append(diff.Changed, change)
+		}
+
+		oldUp, oldDown, oldOK := downloadTotals(oldD)
+		newUp, newDown, newOK := downloadTotals(newD)
+		if rate, ok := rateBetween(oldUp, newUp, oldDown, newDown, elapsed); ok && oldOK && newOK {
+			diff.Rates[hash] = rate
+		}
+	}
+
+	if rate, ok := rateBetween(a.Globals.UploadTotal, b.Globals.UploadTotal, a.Globals.DownloadTotal, b.Globals.DownloadTotal,
+		elapsed); ok {
+		diff.Global = &rate
+	}
+	return diff
+}
+
+// diffDownload compares the fields both downloads have
+func diffDownload(a, b *Download) []FieldChange {
+	var changes []FieldChange
+	for _, name := range slices.Sorted(maps.Keys(a.dData)) {
+		if _, ok := b.dData[name]; !ok {
+			continue
+		}
+		oldV, newV := snapshotValue(downloadFields, a.dData, name), snapshotValue(downloadFields, b.dData, name)
+		if !sameValue(oldV, newV) {
+			changes = append(changes, FieldChange{Field: name, Old: oldV, New: newV})
+		}
+	}
+	return changes
+}
+
+// diffTrackers compares a download's trackers by URL, as their indexes move when trackers are added
+func diffTrackers(a, b []*Tracker) ([]string, []string, []TrackerChange) {
+	byURL := func(trackers []*Tracker) map[string]*Tracker {
+		m := make(map[string]*Tracker, len(trackers))
+		for _, t := range trackers {
+			if u, err := t.URL(); err == nil {
+				m[u] = t
+			}
+		}
+		return m
+	}
+	oldByURL, newByURL := byURL(a), byURL(b)
+
+	var (
+		added, removed []string
+		changes        []TrackerChange
+	)
+	for _, u := range slices.Sorted(maps.Keys(newByURL)) {
+		if _, ok := oldByURL[u]; !ok {
+			added = append(added, u)
+		}
+	}
+	for _, u := range slices.Sorted(maps.Keys(oldByURL)) {
+		newT, ok := newByURL[u]
+		if !ok {
+			removed = append(removed, u)
+			continue
+		}
+		oldT := oldByURL[u]
+		for _, name := range slices.Sorted(maps.Keys(oldT.tData)) {
+			if _, ok := newT.tData[name]; !ok || name == FieldURL {
+				continue
+			}
+			oldV, newV := snapshotValue(trackerFields, oldT.tData, name), snapshotValue(trackerFields, newT.tData, name)
+			if !sameValue(oldV, newV) {
+				changes = append(changes, TrackerChange{URL: u, Field: name, Old: oldV, New: newV})
+			}
+		}
+	}
+	return added, removed, changes
+}
+
+// snapshotValue is a field's value for comparing, converted if it can be and as rTorrent sent it otherwise
+func snapshotValue[K ~string](fs *FieldSet[K], r Record[K], name K) any {
+	f, ok := fs.Lookup(name)
+	if !ok {
+		return r[name]
+	}
+	v, err := f.Value(r)
+	if err != nil {
+		return r[name]
+	}
+	return v
+}
+
+// sameValue compares two values snapshotValue gave, which may be raw values that == can't compare
+func sameValue(a, b any) bool {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// downloadTotals gives a download's byte totals, if it has both
+func downloadTotals(d *Download) (int, int, bool) {
+	up, err := d.UpTotal()
+	if err != nil {
+		return 0, 0, false
+	}
+	down, err := d.DownTotal()
+	if err != nil {
+		return 0, 0, false
+	}
+	return up, down, true
+}
+
+// rateBetween works out a rate from byte totals taken elapsed seconds apart, unless a total went down
+func rateBetween(oldUp, newUp, oldDown, newDown int, elapsed float64) (Rate, bool) {
+	if elapsed <= 0 || newUp < oldUp || newDown < oldDown {
+		return Rate{}, false
+	}
+	return Rate{Up: float64(newUp-oldUp) / elapsed, Down: float64(newDown-oldDown) / elapsed}, true
+}
+
+// String summarises the diff for logs.
+func (d *SnapshotDiff) String() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed over %s", len(d.Added), len(d.Removed), len(d.Changed), d.To.Sub(d.From))
+}
@@ -0,0 +1,124 @@
+package rtorrent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSnapshotService_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "seeding", "d.hash=", "d.up.total=").
+		Return([][]any{{testDownloads[0], int64(10)}, {testDownloads[1], int64(20)}}, nil)
+	m.EXPECT().getInt(gomock.Any(), "down.total", "").Return(100, nil)
+	m.EXPECT().getInt(gomock.Any(), "up.total", "").Return(200, nil)
+	m.EXPECT().getInt(gomock.Any(), "down.rate", "").Return(3, nil)
+	m.EXPECT().getInt(gomock.Any(), "up.rate", "").Return(4, nil)
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "seeding", "d.hash=", "t.multicall=,t.url=").
+		Return([][]any{{testDownloads[0], []any{[]any{"udp://a"}}}}, nil)
+
+	before := time.Now()
+	snap, err := (&SnapshotService{C: m}).Snapshot(t.Context(), WithSnapshotView("seeding"),
+		WithSnapshotFields(DownloadFieldUpTotal), WithSnapshotTrackerFields(FieldURL))
+	require.NoError(t, err)
+	assert.False(t, snap.Time.Before(before))
+	assert.Equal(t, Globals{DownloadTotal: 100, UploadTotal: 200, DownloadRate: 3, UploadRate: 4}, snap.Globals)
+	require.Len(t, snap.Downloads, 2)
+	up, err := snap.Downloads[testDownloads[1]].UpTotal()
+	require.NoError(t, err)
+	assert.Equal(t, 20, up)
+	require.Len(t, snap.Trackers[testDownloads[0]], 1)
+
+	// Without tracker fields there is no tracker request
+	m = NewMockClient(gomock.NewController(t))
+	m.EXPECT().getSliceSlice(gomock.Any(), downloadListMultiCall, "default", "d.hash=").Return(nil, nil)
+	m.EXPECT().getInt(gomock.Any(), gomock.Any(), "").Return(0, nil).Times(4)
+	snap, err = (&SnapshotService{C: m}).Snapshot(t.Context(), WithSnapshotFields(), WithSnapshotTrackerFields())
+	require.NoError(t, err)
+	assert.Nil(t, snap.Trackers)
+}
+
+func TestSnapshotService_Globals(t *testing.T) {
+	t.Parallel()
+
+	m := NewMockClient(gomock.NewController(t))
+	m.EXPECT().getInt(gomock.Any(), "down.total", "").Return(100, nil)
+	m.EXPECT().getInt(gomock.Any(), "up.total", "").Return(200, nil)
+	m.EXPECT().getInt(gomock.Any(), "down.rate", "").Return(3, nil)
+	m.EXPECT().getInt(gomock.Any(), "up.rate", "").Return(0, errInstanceDown)
+
+	_, err := (&SnapshotService{C: m}).Globals(t.Context())
+	require.ErrorIs(t, err, errInstanceDown)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	var (
+		hashGone  = strings.Repeat("A", 40)
+		hashKept  = strings.Repeat("B", 40)
+		hashNew   = strings.Repeat("C", 40)
+		hashReset = strings.Repeat("D", 40)
+		start     = time.Unix(1700000000, 0)
+	)
+	download := func(hash string, up, down int64, name string) *Download {
+		return NewDownload(map[DownloadField]any{
+			DownloadFieldHash: hash, DownloadFieldUpTotal: up, DownloadFieldDownTotal: down, DownloadFieldName: name,
+		})
+	}
+	tracker := func(u string, enabled int64) *Tracker {
+		return NewTracker(NewTrackerWithIndex(hashKept, 0), map[TrackerField]any{FieldURL: u, FieldIsEnabled: enabled})
+	}
+
+	a := &Snapshot{
+		Time:    start,
+		Globals: Globals{UploadTotal: 1000, DownloadTotal: 500},
+		Downloads: map[string]*Download{
+			hashGone:  download(hashGone, 0, 0, "gone"),
+			hashKept:  download(hashKept, 100, 50, "kept"),
+			hashReset: download(hashReset, 100, 100, "reset"),
+		},
+		Trackers: map[string][]*Tracker{hashKept: {tracker("udp://a", 1), tracker("udp://old", 1)}},
+	}
+	b := &Snapshot{
+		Time:    start.Add(10 * time.Second),
+		Globals: Globals{UploadTotal: 2000, DownloadTotal: 600},
+		Downloads: map[string]*Download{
+			hashKept:  download(hashKept, 600, 50, "renamed"),
+			hashNew:   download(hashNew, 0, 0, "new"),
+			hashReset: download(hashReset, 0, 0, "reset"),
+		},
+		Trackers: map[string][]*Tracker{hashKept: {tracker("udp://a", 0), tracker("udp://new", 1)}},
+	}
+
+	diff := Diff(a, b)
+	assert.Equal(t, []string{hashNew}, diff.Added)
+	assert.Equal(t, []string{hashGone}, diff.Removed)
+
+	require.Len(t, diff.Changed, 2)
+	kept := diff.Changed[0]
+	assert.Equal(t, hashKept, kept.InfoHash)
+	assert.Equal(t, []FieldChange{
+		{Field: DownloadFieldName, Old: "kept", New: "renamed"},
+		{Field: DownloadFieldUpTotal, Old: 100, New: 600},
+	}, kept.Fields)
+	assert.Equal(t, []string{"udp://new"}, kept.TrackersAdded)
+	assert.Equal(t, []string{"udp://old"}, kept.TrackersRemoved)
+	assert.Equal(t, []TrackerChange{{URL: "udp://a", Field: FieldIsEnabled, Old: true, New: false}}, kept.Trackers)
+	assert.Equal(t, hashReset, diff.Changed[1].InfoHash)
+
+	assert.Equal(t, map[string]Rate{hashKept: {Up: 50, Down: 0}}, diff.Rates, "totals that went down give no rate")
+	require.NotNil(t, diff.Global)
+	assert.Equal(t, Rate{Up: 100, Down: 10}, *diff.Global)
+	assert.Equal(t, "1 added, 1 removed, 2 changed over 10s", diff.String())
+
+	// rTorrent restarting resets its totals
+	b.Globals.UploadTotal = 0
+	assert.Nil(t, Diff(a, b).Global)
+}
@@ -32,9 +32,11 @@ var (
 	ErrBadData           = errors.New("bad data")
 	ErrNoDataFromTracker = errors.New("no data from tracker")
 	ErrMultipleTrackers  = errors.New("multiple trackers returned")
+	ErrDownloadNotFound  = errors.New("download not found")
 )
 
-// XMLRPC Tracker Fields
+// XMLRPC Tracker Fields. rTorrent keeps no failure message per tracker, the latest one for the whole download is in
+// d.message, which DownloadFieldMessage retrieves.
 const (
 	FieldCanScrape      = TrackerField("can_scrape")
 	FieldIsUsable       = TrackerField("is_usable")
@@ -56,6 +58,13 @@ const (
 	FieldSuccessNext    = TrackerField("success_time_next")
 	FieldType           = TrackerField("type")
 	FieldURL            = TrackerField("url")
+
+	FieldGroup            = TrackerField("group")
+	FieldScrapeComplete   = TrackerField("scrape_complete")
+	FieldScrapeIncomplete = TrackerField("scrape_incomplete")
+	FieldScrapeDownloaded = TrackerField("scrape_downloaded")
+	FieldScrapeTimeLast   = TrackerField("scrape_time_last")
+	FieldScrapeCounter    = TrackerField("scrape_counter")
 )
 
 // Tracker Events
@@ -74,60 +83,50 @@ const (
 	TypeDHT
 )
 
-// fieldStringers maps every retrievable field to a renderer. Its key set is the authoritative list of valid fields
-// (see AllTrackerFields) so the two can't drift apart.
-var fieldStringers = map[TrackerField]func(*Tracker) (string, error){
-	FieldCanScrape:      stringerFor((*Tracker).CanScrape, strconv.FormatBool),
-	FieldIsUsable:       stringerFor((*Tracker).IsUsable, strconv.FormatBool),
-	FieldIsEnabled:      stringerFor((*Tracker).IsEnabled, strconv.FormatBool),
-	FieldFailedCounter:  stringerFor((*Tracker).FailedCounter, strconv.Itoa),
-	FieldActivityLast:   stringerFor((*Tracker).ActivityLastTime, time.Time.String),
-	FieldActivityNext:   stringerFor((*Tracker).ActivityTimeNext, time.Time.String),
-	FieldFailedLast:     stringerFor((*Tracker).FailedTimeLast, time.Time.String),
-	FieldFailedNext:     stringerFor((*Tracker).FailedTimeNext, time.Time.String),
-	FieldID:             stringerFor((*Tracker).ID, identity),
-	FieldIsBusy:         stringerFor((*Tracker).IsBusy, strconv.FormatBool),
-	FieldIsOpen:         stringerFor((*Tracker).IsOpen, strconv.FormatBool),
-	FiledIsExtraTracker: stringerFor((*Tracker).IsExtraTracker, strconv.FormatBool),
-	FieldLatestEvent:    stringerFor((*Tracker).LatestEvent, TrackerEvent.String),
-	FieldMinInterval:    stringerFor((*Tracker).MinInterval, strconv.Itoa),
-	FieldNormalInterval: stringerFor((*Tracker).NormalInterval, strconv.Itoa),
-	FieldSuccessCounter: stringerFor((*Tracker).SuccessCounter, strconv.Itoa),
-	FieldSuccessLast:    stringerFor((*Tracker).SuccessTimeLast, time.Time.String),
-	FieldSuccessNext:    stringerFor((*Tracker).SuccessTimeNext, time.Time.String),
-	FieldType:           stringerFor((*Tracker).Type, TrackerType.String),
-	FieldURL:            stringerFor((*Tracker).URL, identity),
-}
+// trackerFields is every retrievable tracker field, and the authoritative list of valid ones (see AllTrackerFields)
+var trackerFields = newFieldSet(ErrNoDataFromTracker,
+	NewField(PrefixTracker, FieldCanScrape, boolFromAny, strconv.FormatBool),
+	NewField(PrefixTracker, FieldIsUsable, boolFromAny, strconv.FormatBool),
+	NewField(PrefixTracker, FieldIsEnabled, boolFromAny, strconv.FormatBool).AsSettable(),
+	NewField(PrefixTracker, FieldFailedCounter, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldActivityLast, timeFromAny, time.Time.String),
+	NewField(PrefixTracker, FieldActivityNext, timeFromAny, time.Time.String),
+	NewField(PrefixTracker, FieldFailedLast, timeFromAny, time.Time.String),
+	NewField(PrefixTracker, FieldFailedNext, timeFromAny, time.Time.String),
+	NewField(PrefixTracker, FieldID, stringFromAny, identity),
+	NewField(PrefixTracker, FieldIsBusy, boolFromAny, strconv.FormatBool),
+	NewField(PrefixTracker, FieldIsOpen, boolFromAny, strconv.FormatBool),
+	NewField(PrefixTracker, FiledIsExtraTracker, boolFromAny, strconv.FormatBool),
+	NewField(PrefixTracker, FieldLatestEvent, enumFromAny[TrackerEvent], TrackerEvent.String),
+	NewField(PrefixTracker, FieldMinInterval, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldNormalInterval, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldSuccessCounter, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldSuccessLast, timeFromAny, time.Time.String),
+	NewField(PrefixTracker, FieldSuccessNext, timeFromAny, time.Time.String),
+	NewField(PrefixTracker, FieldType, enumFromAny[TrackerType], TrackerType.String),
+	NewField(PrefixTracker, FieldURL, stringFromAny, identity),
+
+	NewField(PrefixTracker, FieldGroup, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldScrapeComplete, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldScrapeIncomplete, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldScrapeDownloaded, intFromAny, strconv.Itoa),
+	NewField(PrefixTracker, FieldScrapeTimeLast, timeFromAny, time.Time.String),
+	NewField(PrefixTracker, FieldScrapeCounter, intFromAny, strconv.Itoa),
+)
 
 // AllTrackerFields returns every retrievable tracker field, sorted. We hand back a fresh slice each call so callers
 // can't mutate the package's own view of what a valid field is.
 func AllTrackerFields() []TrackerField {
-	return slices.Sorted(maps.Keys(fieldStringers))
+	return trackerFields.Names()
 }
 
-// identity satisfies the format argument of stringerFor for string-valued fields, since Go has no builtin
-func identity(s string) string { return s }
-
-// stringerFor adapts a Tracker getter into the signature fieldStringers wants, deferring to format for the rendering
-func stringerFor[T any](get func(*Tracker) (T, error), format func(T) string) func(*Tracker) (string, error) {
-	return func(t *Tracker) (string, error) {
-		v, err := get(t)
-		if err != nil {
-			return "", err
-		}
-		return format(v), nil
-	}
+// TrackerFields returns the tracker fields as a FieldSet, for code working with fields of any kind of entity.
+func TrackerFields() *FieldSet[TrackerField] {
+	return trackerFields
 }
 
-// trackerField looks f up in the tracker's data and converts it with conv, the shape every getter below follows
-func trackerField[T any](t *Tracker, f TrackerField, conv func(any) (T, error)) (T, error) {
-	data, ok := t.tData[f]
-	if !ok {
-		var zero T
-		return zero, ErrNoField
-	}
-	return conv(data)
-}
+// identity satisfies the format argument of NewField for string-valued fields, since Go has no builtin
+func identity(s string) string { return s }
 
 // enumFromAny converts a raw value into any int-backed tracker enum, which covers both TrackerEvent and TrackerType
 func enumFromAny[T ~int](data any) (T, error) {
@@ -162,7 +161,7 @@ func (ti *TrackerIndex) String() string {
 type TrackerField string
 
 func (tf TrackerField) AsXMLRPCArgument() string {
-	return "t." + string(tf) + "="
+	return PrefixTracker + string(tf) + "="
 }
 
 func (tf TrackerField) String() string {
@@ -210,7 +209,14 @@ func (tt TrackerType) String() string {
 // Tracker is used to represent information about a tracker in rTorrent
 type Tracker struct {
 	ti    *TrackerIndex
-	tData map[TrackerField]any
+	tData Record[TrackerField]
+}
+
+// NewTracker builds a Tracker from data already gathered, for sources of tracker information other than a live
+// TrackerService such as rTorrent's session files. Values are converted as the getters are called, just as they are
+// for trackers read from rTorrent, so they may be of any type the getters accept.
+func NewTracker(ti *TrackerIndex, data map[TrackerField]any) *Tracker {
+	return &Tracker{ti: ti, tData: data}
 }
 
 func (t *Tracker) CloneWithTrackerIndex(ti *TrackerIndex) *Tracker {
@@ -220,11 +226,11 @@ func (t *Tracker) CloneWithTrackerIndex(ti *TrackerIndex) *Tracker {
 // GetFieldValueAsString renders the value of f as a string, returning "<ne>" if the field isn't one we know about and
 // "<na>" if it is known but couldn't be read off this particular tracker
 func (t *Tracker) GetFieldValueAsString(f TrackerField) string {
-	stringer, ok := fieldStringers[f]
+	field, ok := trackerFields.Lookup(f)
 	if !ok {
 		return noFieldStr
 	}
-	str, err := stringer(t)
+	str, err := field.Format(t.tData)
 	if err != nil {
 		return noValueStr
 	}
@@ -254,106 +260,142 @@ func (t *Tracker) TrackerIndex() *TrackerIndex {
 // CanScrape Checks if the announce URL is scrapeable. rTorrent considers a HTTP tracker scrapeable if the announce URL contains the string
 // /announce somewhere after the rightmost / (inclusively).
 func (t *Tracker) CanScrape() (bool, error) {
-	return trackerField(t, FieldCanScrape, boolFromAny)
+	return RecordValue(t.tData, FieldCanScrape, boolFromAny)
 }
 
 // IsUsable Checks if the tracker is usable. A tracker is considered usable if it is enabled and not marked as failed.
 func (t *Tracker) IsUsable() (bool, error) {
-	return trackerField(t, FieldIsUsable, boolFromAny)
+	return RecordValue(t.tData, FieldIsUsable, boolFromAny)
 }
 
 // IsEnabled Checks if the tracker is enabled. A tracker is considered enabled if it is not marked as disabled.
 func (t *Tracker) IsEnabled() (bool, error) {
-	return trackerField(t, FieldIsEnabled, boolFromAny)
+	return RecordValue(t.tData, FieldIsEnabled, boolFromAny)
 }
 
 // FailedCounter Returns the number of failed requests to the tracker. Note that this value resets to 0 if a request succeeds.
 func (t *Tracker) FailedCounter() (int, error) {
-	return trackerField(t, FieldFailedCounter, intFromAny)
+	return RecordValue(t.tData, FieldFailedCounter, intFromAny)
 }
 
 // ActivityLastTime Returns the last time there was an attempt to announce to this tracker, regardless of whether or not the announce
 // succeeded.
 func (t *Tracker) ActivityLastTime() (time.Time, error) {
-	return trackerField(t, FieldActivityLast, timeFromAny)
+	return RecordValue(t.tData, FieldActivityLast, timeFromAny)
 }
 
 // ActivityTimeNext Returns when rtorrent will attempt to announce to the tracker next. In most cases, t.activity_time_next -
 // t.activity_time_last will equal t.normal_interval.
 func (t *Tracker) ActivityTimeNext() (time.Time, error) {
-	return trackerField(t, FieldActivityNext, timeFromAny)
+	return RecordValue(t.tData, FieldActivityNext, timeFromAny)
 }
 
 // FailedTimeLast Returns the last time there was a failed attempt to announce to this tracker.
 func (t *Tracker) FailedTimeLast() (time.Time, error) {
-	return trackerField(t, FieldFailedLast, timeFromAny)
+	return RecordValue(t.tData, FieldFailedLast, timeFromAny)
 }
 
 // FailedTimeNext Returns the time at when the next request is planned to happen after a failed request. rTorrent backs off failed requests
 // exponentially, i.e. each time a request fails, it doubles the interval until it tries again.
 func (t *Tracker) FailedTimeNext() (time.Time, error) {
-	return trackerField(t, FieldFailedNext, timeFromAny)
+	return RecordValue(t.tData, FieldFailedNext, timeFromAny)
 }
 
 // ID If a previous HTTP tracker response contains the tracker id key, t.id will contain that value, and it will be added as a parameter to
 // any subsequent requests to that same tracker.
 func (t *Tracker) ID() (string, error) {
-	return trackerField(t, FieldID, stringFromAny)
+	return RecordValue(t.tData, FieldID, stringFromAny)
 }
 
 // IsBusy Returns true if the request is in the middle of processing, and false otherwise (this is identical to IsOpen())
 func (t *Tracker) IsBusy() (bool, error) {
-	return trackerField(t, FieldIsBusy, boolFromAny)
+	return RecordValue(t.tData, FieldIsBusy, boolFromAny)
 }
 
 // IsOpen Returns true if the request is in the middle of processing, and false otherwise (this is identical to IsBusy())
 func (t *Tracker) IsOpen() (bool, error) {
-	return trackerField(t, FieldIsOpen, boolFromAny)
+	return RecordValue(t.tData, FieldIsOpen, boolFromAny)
 }
 
 // IsExtraTracker Returns true if the tracker was added via d.tracker.insert, rather than existing in the original metafile.
 func (t *Tracker) IsExtraTracker() (bool, error) {
-	return trackerField(t, FiledIsExtraTracker, boolFromAny)
+	return RecordValue(t.tData, FiledIsExtraTracker, boolFromAny)
 }
 
 // LatestEvent Returns the latest event that occurred with the tracker, one of the Event constants. EventScrape is not
 // an event key the BitTorrent spec defines, it means the tracker is currently processing a scrape request.
 func (t *Tracker) LatestEvent() (TrackerEvent, error) {
-	return trackerField(t, FieldLatestEvent, enumFromAny[TrackerEvent])
+	return RecordValue(t.tData, FieldLatestEvent, enumFromAny[TrackerEvent])
 }
 
 // MinInterval Returns the values for the minimum announce intervals as returned from the tracker request.
 func (t *Tracker) MinInterval() (int, error) {
-	return trackerField(t, FieldMinInterval, intFromAny)
+	return RecordValue(t.tData, FieldMinInterval, intFromAny)
 }
 
 // NormalInterval Returns the values for the normal announce intervals as returned from the tracker request.
 func (t *Tracker) NormalInterval() (int, error) {
-	return trackerField(t, FieldNormalInterval, intFromAny)
+	return RecordValue(t.tData, FieldNormalInterval, intFromAny)
 }
 
 // SuccessCounter Returns the number of successful requests to the tracker.
 func (t *Tracker) SuccessCounter() (int, error) {
-	return trackerField(t, FieldSuccessCounter, intFromAny)
+	return RecordValue(t.tData, FieldSuccessCounter, intFromAny)
 }
 
 // SuccessTimeLast Returns the last time there was a successful attempt to announce to this tracker.
 func (t *Tracker) SuccessTimeLast() (time.Time, error) {
-	return trackerField(t, FieldSuccessLast, timeFromAny)
+	return RecordValue(t.tData, FieldSuccessLast, timeFromAny)
 }
 
 // SuccessTimeNext Returns the time at when the next request is planned to happen after a successful request.
 func (t *Tracker) SuccessTimeNext() (time.Time, error) {
-	return trackerField(t, FieldSuccessNext, timeFromAny)
+	return RecordValue(t.tData, FieldSuccessNext, timeFromAny)
 }
 
 // Type Returns the type of the tracker, one of the Type constants
 func (t *Tracker) Type() (TrackerType, error) {
-	return trackerField(t, FieldType, enumFromAny[TrackerType])
+	return RecordValue(t.tData, FieldType, enumFromAny[TrackerType])
 }
 
 func (t *Tracker) URL() (string, error) {
-	return trackerField(t, FieldURL, stringFromAny)
+	return RecordValue(t.tData, FieldURL, stringFromAny)
+}
+
+// Group Returns the tier of the torrent's announce list the tracker belongs to. rTorrent tries the trackers of each
+// group in turn, moving on to the next group only when every tracker in the current one has failed.
+func (t *Tracker) Group() (int, error) {
+	return RecordValue(t.tData, FieldGroup, intFromAny)
+}
+
+// ScrapeComplete Returns the number of seeders the tracker reported in its last scrape.
+func (t *Tracker) ScrapeComplete() (int, error) {
+	return RecordValue(t.tData, FieldScrapeComplete, intFromAny)
+}
+
+// ScrapeIncomplete Returns the number of leechers the tracker reported in its last scrape.
+func (t *Tracker) ScrapeIncomplete() (int, error) {
+	return RecordValue(t.tData, FieldScrapeIncomplete, intFromAny)
+}
+
+// ScrapeDownloaded Returns the number of completed downloads the tracker reported in its last scrape.
+func (t *Tracker) ScrapeDownloaded() (int, error) {
+	return RecordValue(t.tData, FieldScrapeDownloaded, intFromAny)
+}
+
+// ScrapeTimeLast Returns the last time the tracker was scraped, or the Unix epoch if it never has been.
+func (t *Tracker) ScrapeTimeLast() (time.Time, error) {
+	return RecordValue(t.tData, FieldScrapeTimeLast, timeFromAny)
+}
+
+// ScrapeCounter Returns the number of successful scrapes of the tracker.
+func (t *Tracker) ScrapeCounter() (int, error) {
+	return RecordValue(t.tData, FieldScrapeCounter, intFromAny)
+}
+
+// target is how rTorrent addresses a single tracker as the target of a t.* command, such as t.is_enabled.set
+func (ti *TrackerIndex) target() string {
+	return ti.InfoHash + ":t" + strconv.Itoa(ti.Index)
 }
 
 // NewTrackerNoIndex creates a new trackerIndex with no index specification, meaning that all trackers for the given infoHash will be
@@ -369,42 +411,117 @@ func NewTrackerWithIndex(infoHash string, index int) *TrackerIndex {
 }
 
 // TrackerWithDetails retrieves a download's trackers along with the requested detail fields. A nil ti gives back
-// ErrNilTrackerIndex and no trackers, while any other error still returns the trackers populated as far as they got.
+// ErrNilTrackerIndex and no trackers. A row that doesn't decode still gives back one tracker per row, populated up to
+// that row, which has only its index, and nil after it.
 func (ts *TrackerService) TrackerWithDetails(ctx context.Context, ti *TrackerIndex, fields []TrackerField) ([]*Tracker, error) {
 	if ti == nil {
 		return nil, ErrNilTrackerIndex
 	}
-	t := Tracker{ti: ti, tData: make(map[TrackerField]any)}
-	tSlice := []*Tracker{&t}
-	newCmds := []string{ti.String()}
-	for _, field := range fields {
-		if _, ok := fieldStringers[field]; !ok {
-			return tSlice, fmt.Errorf("%w: %s", ErrUnknownField, field)
-		}
-		newCmds = append(newCmds, field.AsXMLRPCArgument())
+	args, err := trackerFields.Arguments(fields)
+	if err != nil {
+		return []*Tracker{{ti: ti, tData: make(Record[TrackerField])}}, err
 	}
-	sliceOfSlices, err := ts.contextWrapGetSliceSliceByHash(ctx, trackerListMultiCall, newCmds...)
+	sliceOfSlices, err := ts.contextWrapGetSliceSliceByHash(ctx, trackerListMultiCall, slices.Concat([]string{ti.String()}, args)...)
 	if err != nil {
-		return tSlice, err
+		return []*Tracker{{ti: ti, tData: make(Record[TrackerField])}}, err
 	}
 
-	tSlice = make([]*Tracker, len(sliceOfSlices))
-	for i, slice := range sliceOfSlices {
-		// A multicall on a specific index returns one tracker, so we synthesize indexes only for the whole list
-		idx := ti
+	// A multicall on a specific index returns one tracker, so we synthesize indexes only for the whole list
+	indexOf := func(i int) *TrackerIndex {
 		if len(sliceOfSlices) > 1 {
-			idx = NewTrackerWithIndex(ti.InfoHash, i)
+			return NewTrackerWithIndex(ti.InfoHash, i)
 		}
-		tSlice[i] = &Tracker{ti: idx}
+		return ti
+	}
+	decoded, err := DecodeMulticall(trackerFields, fields, sliceOfSlices, func(i int, r Record[TrackerField]) *Tracker {
+		return &Tracker{ti: indexOf(i), tData: r}
+	})
+	if err != nil {
+		// As ever, a row that doesn't decode still gives back one tracker per row: those before it populated, it with
+		// only its index, and those after it nil
+		trackers := make([]*Tracker, len(sliceOfSlices))
+		copy(trackers, decoded)
+		trackers[len(decoded)] = &Tracker{ti: indexOf(len(decoded))}
+		return trackers, err
+	}
+	return decoded, nil
+}
 
-		tData, err := TrackerDataFromSlice(fields, slice)
+// TrackersByDownload retrieves the trackers of every download in view, or every download if view is "", along with
+// the requested detail fields, in a single request. The trackers come back by info-hash, each with its index among
+// its download's trackers, so they can be passed on to anything taking a single tracker such as SetEnabled.
+func (ts *TrackerService) TrackersByDownload(ctx context.Context, view string, fields []TrackerField) (map[string][]*Tracker, error) {
+	if view == "" {
+		view = "default"
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: no tracker fields requested", ErrNoField)
+	}
+	// The nested multicall takes the tracker target, which is empty for every tracker, and then its commands all as one
+	// comma separated argument
+	args, err := trackerFields.Arguments(fields)
+	if err != nil {
+		return nil, err
+	}
+	nested := slices.Concat([]string{trackerListMultiCall + "="}, args)
+
+	rows, err := ts.C.getSliceSlice(ctx, downloadListMultiCall, view, DownloadFieldHash.AsXMLRPCArgument(), strings.Join(nested, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	byDownload := make(map[string][]*Tracker, len(rows))
+	for _, row := range rows {
+		if len(row) < 2 {
+			return byDownload, fmt.Errorf("%w: got %d values for a download's hash and trackers", ErrBadData, len(row))
+		}
+		hash, err := stringFromAny(row[0])
 		if err != nil {
-			return tSlice, err
+			return byDownload, err
+		}
+		list, ok := row[1].([]any)
+		if !ok {
+			return byDownload, fmt.Errorf("%w: %s: cannot convert %T to a tracker list", ErrBadData, hash, row[1])
 		}
-		tSlice[i].tData = tData
+
+		values := make([][]any, 0, len(list))
+		for _, item := range list {
+			v, ok := item.([]any)
+			if !ok {
+				return byDownload, fmt.Errorf("%w: %s: cannot convert %T to a tracker", ErrBadData, hash, item)
+			}
+			values = append(values, v)
+		}
+		trackers, err := DecodeMulticall(trackerFields, fields, values, func(i int, r Record[TrackerField]) *Tracker {
+			return &Tracker{ti: NewTrackerWithIndex(hash, i), tData: r}
+		})
+		if err != nil {
+			return byDownload, fmt.Errorf("%s: %w", hash, err)
+		}
+		byDownload[hash] = trackers
 	}
+	return byDownload, nil
+}
 
-	return tSlice, nil
+// SetEnabled enables or disables a single tracker of a download, which ti must give the index of. rTorrent doesn't
+// announce to disabled trackers, and forgets the change when the download is removed unless the session is saved.
+func (ts *TrackerService) SetEnabled(ctx context.Context, ti *TrackerIndex, enabled bool) error {
+	if ti == nil {
+		return ErrNilTrackerIndex
+	}
+	if ti.Index < 0 {
+		return fmt.Errorf("%w: %s has no tracker index", ErrBadData, ti)
+	}
+	field, _ := trackerFields.Lookup(FieldIsEnabled)
+	cmd, err := field.SetCommand()
+	if err != nil {
+		return err
+	}
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	return ts.C.execute(ctx, cmd, ti.target(), value)
 }
 
 func (ts *TrackerService) contextWrapGetSliceSliceByHash(ctx context.Context, method string, args ...string) ([][]any, error) {
@@ -417,7 +534,7 @@ func (ts *TrackerService) contextWrapGetSliceSliceByHash(ctx context.Context, me
 	resultChan := make(chan result, 1)
 
 	go func() {
-		sliceOfSlices, err := ts.C.getSliceSliceByHash(method, args...)
+		sliceOfSlices, err := ts.C.getSliceSliceByHash(ctx, method, args...)
 		resultChan <- result{sliceOfSlices, err}
 	}()
 
@@ -431,16 +548,5 @@ func (ts *TrackerService) contextWrapGetSliceSliceByHash(ctx context.Context, me
 
 // TrackerDataFromSlice builds a tracker's data map by pairing the requested fields with the values rTorrent returned
 func TrackerDataFromSlice(fields []TrackerField, data []any) (map[TrackerField]any, error) {
-	if len(data) == 0 {
-		return nil, ErrNoDataFromTracker
-	}
-	if len(data) < len(fields) {
-		return nil, fmt.Errorf("%w: got %d values for %d requested fields", ErrBadData, len(data), len(fields))
-	}
-	tData := make(map[TrackerField]any, len(fields))
-	// We range the values rather than the fields so that the index is provably in bounds for both slices
-	for i, v := range data[:len(fields)] {
-		tData[fields[i]] = v
-	}
-	return tData, nil
+	return trackerFields.Record(fields, data)
 }
@@ -82,7 +82,7 @@ func TestAllTrackerFields(t *testing.T) {
 	t.Parallel()
 
 	fields := AllTrackerFields()
-	assert.Len(t, fields, len(fieldStringers))
+	assert.Len(t, fields, len(trackerFields.fields))
 	assert.IsIncreasing(t, fields, "AllTrackerFields should come back sorted")
 	assert.Contains(t, fields, FieldURL)
 
@@ -164,7 +164,7 @@ func TestTrackerService_TrackerWithDetails(t *testing.T) {
 		fields := []TrackerField{FieldID, FieldURL}
 		xmlRPCFields := []string{ti.String(), FieldID.AsXMLRPCArgument(), FieldURL.AsXMLRPCArgument()}
 
-		mockClient.EXPECT().getSliceSliceByHash("t.multicall", xmlRPCFields).Return([][]any{{testID, testURL}}, nil)
+		mockClient.EXPECT().getSliceSliceByHash(gomock.Any(), "t.multicall", xmlRPCFields).Return([][]any{{testID, testURL}}, nil)
 
 		tracker, err := ts.TrackerWithDetails(t.Context(), ti, fields)
 		require.NoError(t, err)
@@ -182,7 +182,7 @@ func TestTrackerService_TrackerWithDetails(t *testing.T) {
 		fields := []TrackerField{FieldID, FieldURL}
 		xmlRPCFields := []string{ti.InfoHash, FieldID.AsXMLRPCArgument(), FieldURL.AsXMLRPCArgument()}
 
-		mockClient.EXPECT().getSliceSliceByHash("t.multicall", xmlRPCFields).
+		mockClient.EXPECT().getSliceSliceByHash(gomock.Any(), "t.multicall", xmlRPCFields).
 			Return([][]any{{testID, testURL}, {"test_id2", "test_url2"}}, nil)
 
 		tracker, err := ts.TrackerWithDetails(t.Context(), ti, fields)
@@ -216,6 +216,25 @@ func TestTrackerService_TrackerWithDetails(t *testing.T) {
 		require.Len(t, tracker, 1)
 		assert.Equal(t, ti, tracker[0].TrackerIndex())
 	})
+
+	t.Run("a row that doesn't decode still gives one tracker per row", func(t *testing.T) {
+		t.Parallel()
+
+		mockClient := NewMockClient(gomock.NewController(t))
+		ts := &TrackerService{C: mockClient}
+
+		ti := NewTrackerNoIndex("12345")
+		mockClient.EXPECT().getSliceSliceByHash(gomock.Any(), "t.multicall", ti.InfoHash, FieldID.AsXMLRPCArgument(),
+			FieldURL.AsXMLRPCArgument()).Return([][]any{{testID, testURL}, {testID}, {testID, testURL}}, nil)
+
+		tracker, err := ts.TrackerWithDetails(t.Context(), ti, []TrackerField{FieldID, FieldURL})
+		require.Error(t, err)
+		require.Len(t, tracker, 3)
+		assert.Equal(t, testURL, tracker[0].tData[FieldURL])
+		assert.Equal(t, 1, tracker[1].ti.Index)
+		assert.Nil(t, tracker[1].tData)
+		assert.Nil(t, tracker[2])
+	})
 }
 
 func TestTrackerService_contextWrapGetSliceSliceByHash(t *testing.T) {
@@ -230,7 +249,7 @@ func TestTrackerService_contextWrapGetSliceSliceByHash(t *testing.T) {
 		method := "test_method"
 		args := []string{"arg1", "arg2"}
 
-		mockClient.EXPECT().getSliceSliceByHash(method, args).Return([][]any{{"result"}}, nil)
+		mockClient.EXPECT().getSliceSliceByHash(gomock.Any(), method, args).Return([][]any{{"result"}}, nil)
 
 		result, err := ts.contextWrapGetSliceSliceByHash(t.Context(), method, args...)
 		require.NoError(t, err)
@@ -246,8 +265,8 @@ func TestTrackerService_contextWrapGetSliceSliceByHash(t *testing.T) {
 		// Cancelling frees the caller but not the in-flight request, so we expect the call and wait for it rather
 		// than letting it land on the mock after the controller has finished
 		called := make(chan struct{})
-		mockClient.EXPECT().getSliceSliceByHash(gomock.Any(), gomock.Any(), gomock.Any()).
-			DoAndReturn(func(string, ...string) ([][]any, error) {
+		mockClient.EXPECT().getSliceSliceByHash(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, string, ...string) ([][]any, error) {
 				close(called)
 				return nil, nil
 			})
@@ -293,3 +312,104 @@ func TestTrackerDataFromSlice(t *testing.T) {
 		assert.Nil(t, result)
 	})
 }
+
+func TestTracker_ScrapeGetters(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewTracker(NewTrackerWithIndex("12345", 0), map[TrackerField]any{
+		FieldGroup:            int64(1),
+		FieldScrapeComplete:   int64(40),
+		FieldScrapeIncomplete: int64(3),
+		FieldScrapeDownloaded: int64(912),
+		FieldScrapeTimeLast:   int64(1700000000),
+		FieldScrapeCounter:    int64(7),
+	})
+
+	tests := []struct {
+		name     string
+		get      func() (int, error)
+		expected int
+	}{
+		{"group", tracker.Group, 1},
+		{"seeders", tracker.ScrapeComplete, 40},
+		{"leechers", tracker.ScrapeIncomplete, 3},
+		{"downloaded", tracker.ScrapeDownloaded, 912},
+		{"scrapes", tracker.ScrapeCounter, 7},
+	}
+	for _, tt := range tests {
+		v, err := tt.get()
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, v, tt.name)
+	}
+
+	last, err := tracker.ScrapeTimeLast()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000), last.Unix())
+	assert.Equal(t, "40", tracker.GetFieldValueAsString(FieldScrapeComplete))
+}
+
+func TestTrackerService_SetEnabled(t *testing.T) {
+	t.Parallel()
+
+	mockClient := NewMockClient(gomock.NewController(t))
+	ts := &TrackerService{C: mockClient}
+
+	gomock.InOrder(
+		mockClient.EXPECT().execute(gomock.Any(), "t.is_enabled.set", "12345:t2", "0").Return(nil),
+		mockClient.EXPECT().execute(gomock.Any(), "t.is_enabled.set", "12345:t0", "1").Return(nil),
+	)
+	require.NoError(t, ts.SetEnabled(t.Context(), NewTrackerWithIndex("12345", 2), false))
+	require.NoError(t, ts.SetEnabled(t.Context(), NewTrackerWithIndex("12345", 0), true))
+
+	// Every tracker of a download isn't a target rTorrent can set
+	require.ErrorIs(t, ts.SetEnabled(t.Context(), NewTrackerNoIndex("12345"), true), ErrBadData)
+	require.ErrorIs(t, ts.SetEnabled(t.Context(), nil, true), ErrNilTrackerIndex)
+}
+
+func TestTrackerService_TrackersByDownload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("every download's trackers in one call", func(t *testing.T) {
+		t.Parallel()
+
+		mockClient := NewMockClient(gomock.NewController(t))
+		mockClient.EXPECT().getSliceSlice(gomock.Any(), "d.multicall2", "seeding", "d.hash=", "t.multicall=,t.url=,t.is_enabled=").
+			Return([][]any{
+				{"AAAA", []any{[]any{testURL, int64(1)}, []any{"test_url2", int64(0)}}},
+				{"BBBB", []any{}},
+			}, nil)
+
+		trackers, err := (&TrackerService{C: mockClient}).TrackersByDownload(t.Context(), "seeding", []TrackerField{FieldURL, FieldIsEnabled})
+		require.NoError(t, err)
+		require.Len(t, trackers, 2)
+		assert.Empty(t, trackers["BBBB"])
+
+		require.Len(t, trackers["AAAA"], 2)
+		second := trackers["AAAA"][1]
+		assert.Equal(t, NewTrackerWithIndex("AAAA", 1), second.TrackerIndex())
+		assert.Equal(t, "test_url2", second.GetFieldValueAsString(FieldURL))
+		assert.Equal(t, "false", second.GetFieldValueAsString(FieldIsEnabled))
+	})
+
+	t.Run("bad fields are rejected without a request", func(t *testing.T) {
+		t.Parallel()
+
+		ts := &TrackerService{C: NewMockClient(gomock.NewController(t))}
+
+		_, err := ts.TrackersByDownload(t.Context(), "", []TrackerField{TrackerField("bogus")})
+		require.ErrorIs(t, err, ErrUnknownField)
+		_, err = ts.TrackersByDownload(t.Context(), "", nil)
+		require.ErrorIs(t, err, ErrNoField)
+	})
+
+	t.Run("malformed rows", func(t *testing.T) {
+		t.Parallel()
+
+		mockClient := NewMockClient(gomock.NewController(t))
+		mockClient.EXPECT().getSliceSlice(gomock.Any(), "d.multicall2", "default", "d.hash=", "t.multicall=,t.url=").
+			Return([][]any{{"AAAA", "not a list"}}, nil)
+
+		_, err := (&TrackerService{C: mockClient}).TrackersByDownload(t.Context(), "", []TrackerField{FieldURL})
+		require.ErrorIs(t, err, ErrBadData)
+	})
+}